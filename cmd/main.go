@@ -8,11 +8,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"pullreq/internal/affinity"
+	"pullreq/internal/comment"
+	"pullreq/internal/ownership"
 	"pullreq/internal/pr"
+	"pullreq/internal/rotation"
 	"pullreq/internal/team"
 	"pullreq/internal/user"
 	"syscall"
 	"time"
+	_ "time/tzdata" // working hours are resolved in the reviewers' timezones
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -35,6 +40,7 @@ func main() {
 	dbPassword := os.Getenv("DB_PASSWORD")
 	dbName := os.Getenv("DB_NAME")
 	serverPort := os.Getenv("SERVER_PORT")
+	rebalanceInterval := os.Getenv("REBALANCE_INTERVAL") // e.g. 15m, empty turns the rebalancer off
 
 	db, err := initDB(sugar, dbHost, dbPort, dbUser, dbPassword, dbName)
 	if err != nil {
@@ -45,11 +51,22 @@ func main() {
 
 	userRepo := &user.UserRepo{DB: db}
 	teamRepo := &team.TeamRepo{DB: db, UR: userRepo}
-	prRepo := &pr.PullRequestRepo{DB: db, UR: userRepo, TR: teamRepo}
+	ownershipRepo := &ownership.OwnershipRepo{DB: db}
+	affinityRepo := &affinity.AffinityRepo{DB: db}
+	rotationRepo := &rotation.RotationRepo{DB: db}
+	commentRepo := &comment.CommentRepo{DB: db}
+	prRepo := &pr.PullRequestRepo{DB: db, UR: userRepo, TR: teamRepo, OR: ownershipRepo, AR: affinityRepo, RR: rotationRepo, Selector: pr.NewStrategySelector()}
+	// deactivating users moves their open reviews through the PR assignment policy
+	userRepo.Reassigner = prRepo
+	teamRepo.Reassigner = prRepo
 
 	teamRouter := &team.TeamRouter{TR: teamRepo}
 	userRouter := &user.UserRouter{UR: userRepo}
 	prRouter := &pr.PrRouter{PR: prRepo}
+	ownershipRouter := &ownership.OwnershipRouter{OR: ownershipRepo}
+	affinityRouter := &affinity.AffinityRouter{AR: affinityRepo}
+	rotationRouter := &rotation.RotationRouter{RR: rotationRepo}
+	commentRouter := &comment.CommentRouter{CR: commentRepo}
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
@@ -62,18 +79,67 @@ func main() {
 		r.Post("/add", teamRouter.HandleAddTeam)
 		r.Get("/get", teamRouter.GetTeamWithMembersHandler)
 		r.Post("/deactivation", teamRouter.DeactivateTeam)
+		r.Post("/settings", teamRouter.UpdateTeamSettings)
 	})
 
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/setIsActive", userRouter.RouterSetActiviry)
 		r.Get("/getReview", userRouter.GetUserReviewsHandler)
 		r.Get("/getStat", userRouter.GetStat)
+		r.Post("/setMaxOpenReviews", userRouter.RouterSetMaxOpenReviews)
+		r.Post("/setSeniority", userRouter.RouterSetSeniority)
+		r.Post("/setAdmin", userRouter.RouterSetAdmin)
+		r.Post("/setWorkingHours", userRouter.RouterSetWorkingHours)
+		r.Post("/addOutOfOffice", userRouter.RouterAddOutOfOffice)
+		r.Post("/deleteOutOfOffice", userRouter.RouterDeleteOutOfOffice)
+		r.Get("/getOutOfOffice", userRouter.GetOutOfOfficeHandler)
+		r.Get("/availability", userRouter.AvailabilityHandler)
+		r.Post("/setTags", userRouter.RouterSetTags)
+		r.Get("/getTags", userRouter.GetTagsHandler)
 	})
 
 	r.Route("/pullRequest", func(r chi.Router) {
 		r.Post("/create", prRouter.CreatePullRequest)
+		r.Post("/preview", prRouter.PreviewPullRequest)
 		r.Post("/merge", prRouter.Merge)
 		r.Post("/reassign", prRouter.AssignedReviewer)
+		r.Post("/setLabels", prRouter.SetLabels)
+		r.Post("/update", prRouter.Update)
+		r.Get("/history", prRouter.History)
+		r.Get("/explain", prRouter.Explain)
+		r.Get("/get", prRouter.Get)
+		r.Get("/list", prRouter.List)
+		r.Post("/startReview", prRouter.StartReview)
+		r.Post("/review", prRouter.SubmitReview)
+		r.Post("/close", prRouter.Close)
+		r.Post("/reopen", prRouter.Reopen)
+		r.Post("/readyForReview", prRouter.ReadyForReview)
+		r.Post("/rebalance", prRouter.Rebalance)
+	})
+
+	r.Route("/ownership", func(r chi.Router) {
+		r.Post("/import", ownershipRouter.ImportCodeowners)
+		r.Get("/rules", ownershipRouter.GetRulesHandler)
+	})
+
+	r.Route("/affinity", func(r chi.Router) {
+		r.Post("/set", affinityRouter.SetRuleHandler)
+		r.Post("/delete", affinityRouter.DeleteRuleHandler)
+		r.Get("/rules", affinityRouter.GetRulesHandler)
+	})
+
+	r.Route("/rotation", func(r chi.Router) {
+		r.Post("/set", rotationRouter.SetRotationHandler)
+		r.Post("/delete", rotationRouter.DeleteRotationHandler)
+		r.Get("/schedule", rotationRouter.GetScheduleHandler)
+	})
+
+	r.Route("/comment", func(r chi.Router) {
+		r.Post("/add", commentRouter.AddHandler)
+		r.Get("/list", commentRouter.ListHandler)
+		r.Post("/edit", commentRouter.EditHandler)
+		r.Post("/resolve", commentRouter.ResolveHandler)
+		r.Post("/unresolve", commentRouter.UnresolveHandler)
 	})
 
 	srv := &http.Server{
@@ -88,9 +154,20 @@ func main() {
 		}
 	}()
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if rebalanceInterval != "" {
+		interval, err := time.ParseDuration(rebalanceInterval)
+		if err != nil || interval <= 0 {
+			sugar.Fatalw("Invalid REBALANCE_INTERVAL", "value", rebalanceInterval)
+		}
+		go runRebalancer(jobCtx, sugar, prRepo, interval)
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
+	stopJobs()
 
 	sugar.Infow("Shutting down server gracefully")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -119,6 +196,32 @@ func initDB(logger *zap.SugaredLogger, host, port, user, password, dbname string
 	return db, nil
 }
 
+// runRebalancer evens out the review load of every team each interval until ctx is done.
+func runRebalancer(ctx context.Context, logger *zap.SugaredLogger, repo *pr.PullRequestRepo, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			moves, err := repo.Rebalance(ctx, "", false)
+			if err != nil {
+				logger.Errorw("Rebalancing failed", "error", err)
+				continue
+			}
+			for _, m := range moves {
+				logger.Infow("Review rebalanced",
+					"team", m.TeamName,
+					"pull_request_id", m.PullRequestID,
+					"from", m.OldUserID,
+					"to", m.NewUserID,
+				)
+			}
+		}
+	}
+}
+
 func zapLoggerMiddleware(logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
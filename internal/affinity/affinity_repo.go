@@ -0,0 +1,144 @@
+package affinity
+
+import (
+	"context"
+	"database/sql"
+	"pullreq/internal/errs"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// Kinds of pairwise rules between an author and a reviewer.
+const (
+	KindNever  = "never"  // the reviewer is never assigned to the author's PRs
+	KindPrefer = "prefer" // the reviewer is preferred over other candidates
+	KindAlways = "always" // the reviewer is assigned to every PR of the author
+)
+
+func ValidKind(kind string) bool {
+	switch kind {
+	case KindNever, KindPrefer, KindAlways:
+		return true
+	}
+	return false
+}
+
+type Rule struct {
+	Kind       string `json:"kind"`
+	AuthorID   string `json:"author_id"`
+	ReviewerID string `json:"reviewer_id"`
+}
+
+// RuleSet holds the reviewer IDs of the rules of one author grouped by kind.
+type RuleSet struct {
+	Never  []string
+	Prefer []string
+	Always []string
+}
+
+type AffinityRepoInterface interface {
+	SetRule(ctx context.Context, rule Rule) error
+	DeleteRule(ctx context.Context, authorID, reviewerID string) error
+	GetRules(ctx context.Context, authorID string) ([]Rule, error)
+	RulesFor(ctx context.Context, authorID string) (*RuleSet, error)
+}
+
+type AffinityRepo struct {
+	DB *sql.DB
+}
+
+// SetRule stores the rule, replacing an existing rule for the same pair.
+func (AR *AffinityRepo) SetRule(ctx context.Context, rule Rule) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Insert("reviewer_rules").
+		Columns("kind", "author_id", "reviewer_id").
+		Values(rule.Kind, rule.AuthorID, rule.ReviewerID).
+		Suffix("ON CONFLICT (author_id, reviewer_id) DO UPDATE SET kind = EXCLUDED.kind").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := AR.DB.ExecContext(ctx, q, args...); err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" {
+			return errs.NotFountError
+		}
+		return err
+	}
+	return nil
+}
+
+func (AR *AffinityRepo) DeleteRule(ctx context.Context, authorID, reviewerID string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Delete("reviewer_rules").
+		Where(sq.Eq{"author_id": authorID, "reviewer_id": reviewerID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := AR.DB.ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errs.NotFountError
+	}
+	return nil
+}
+
+// GetRules returns the rules of authorID, or all rules when authorID is empty.
+func (AR *AffinityRepo) GetRules(ctx context.Context, authorID string) ([]Rule, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	builder := psql.Select("kind", "author_id", "reviewer_id").
+		From("reviewer_rules").
+		OrderBy("author_id", "reviewer_id")
+	if authorID != "" {
+		builder = builder.Where(sq.Eq{"author_id": authorID})
+	}
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := AR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]Rule, 0)
+	for rows.Next() {
+		var r Rule
+		if err := rows.Scan(&r.Kind, &r.AuthorID, &r.ReviewerID); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+func (AR *AffinityRepo) RulesFor(ctx context.Context, authorID string) (*RuleSet, error) {
+	rules, err := AR.GetRules(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &RuleSet{}
+	for _, r := range rules {
+		switch r.Kind {
+		case KindNever:
+			set.Never = append(set.Never, r.ReviewerID)
+		case KindPrefer:
+			set.Prefer = append(set.Prefer, r.ReviewerID)
+		case KindAlways:
+			set.Always = append(set.Always, r.ReviewerID)
+		}
+	}
+	return set, nil
+}
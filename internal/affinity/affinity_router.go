@@ -0,0 +1,89 @@
+package affinity
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"pullreq/internal/errs"
+	jsonutils "pullreq/internal/json_utils"
+)
+
+type AffinityRouter struct {
+	AR AffinityRepoInterface
+}
+
+type DeleteRuleRequest struct {
+	AuthorID   string `json:"author_id"`
+	ReviewerID string `json:"reviewer_id"`
+}
+
+func (ar *AffinityRouter) SetRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req Rule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !ValidKind(req.Kind) {
+		http.Error(w, "kind must be one of never, prefer, always", http.StatusBadRequest)
+		return
+	}
+	if req.AuthorID == "" || req.ReviewerID == "" || req.AuthorID == req.ReviewerID {
+		http.Error(w, "author_id and reviewer_id must name two different users", http.StatusBadRequest)
+		return
+	}
+
+	if err := ar.AR.SetRule(r.Context(), req); err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"rule": req}, http.StatusOK)
+}
+
+func (ar *AffinityRouter) DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DeleteRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := ar.AR.DeleteRule(r.Context(), req.AuthorID, req.ReviewerID); err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "Rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"deleted": req}, http.StatusOK)
+}
+
+func (ar *AffinityRouter) GetRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rules, err := ar.AR.GetRules(r.Context(), r.URL.Query().Get("author_id"))
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"rules": rules}, http.StatusOK)
+}
@@ -0,0 +1,350 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"pullreq/internal/errs"
+	"pullreq/internal/pr"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Comment is a message on a PR. A comment without a parent opens a thread, replies
+// belong to the thread of the comment they answer.
+type Comment struct {
+	ID            int        `json:"comment_id"`
+	PullRequestID string     `json:"pull_request_id"`
+	ThreadID      int        `json:"thread_id"`
+	ParentID      *int       `json:"parent_id,omitempty"`
+	AuthorID      string     `json:"author_id"`
+	Body          string     `json:"body"`
+	CreatedAt     time.Time  `json:"created_at"`
+	EditedAt      *time.Time `json:"edited_at,omitempty"`
+}
+
+// Thread is a comment with its replies. Resolving applies to the whole thread.
+type Thread struct {
+	ID         int        `json:"thread_id"`
+	Resolved   bool       `json:"resolved"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Comments   []Comment  `json:"comments"` // the opening comment first, replies in order
+}
+
+type AddRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	AuthorID      string `json:"author_id"`
+	Body          string `json:"body"`
+	ParentID      *int   `json:"parent_id,omitempty"` // the comment answered, empty to open a thread
+}
+
+type EditRequest struct {
+	CommentID int    `json:"comment_id"`
+	AuthorID  string `json:"author_id"`
+	Body      string `json:"body"`
+}
+
+type ResolveRequest struct {
+	CommentID int    `json:"comment_id"` // any comment of the thread
+	UserID    string `json:"user_id"`
+}
+
+type CommentRepoInterface interface {
+	Add(ctx context.Context, req AddRequest) (*Comment, error)
+	List(ctx context.Context, prID string, unresolvedOnly bool) ([]Thread, error)
+	Edit(ctx context.Context, req EditRequest) (*Comment, error)
+	Resolve(ctx context.Context, req ResolveRequest, resolved bool) (*Thread, error)
+}
+
+// commentStates are the states of a PR that take comments, edits and resolves.
+var commentStates = []string{pr.StatusDraft, pr.StatusOpen}
+
+type CommentRepo struct {
+	DB    *sql.DB
+	Clock func() time.Time // nil means time.Now, replaced in tests
+}
+
+func (CR *CommentRepo) now() time.Time {
+	if CR.Clock == nil {
+		return time.Now()
+	}
+	return CR.Clock()
+}
+
+// Add stores a comment of the PR author or one of its reviewers. Comments of other
+// users are refused with errs.NotParticipantError.
+func (CR *CommentRepo) Add(ctx context.Context, req AddRequest) (*Comment, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := CR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkParticipant(ctx, tx, req.PullRequestID, req.AuthorID); err != nil {
+		return nil, err
+	}
+
+	c := Comment{PullRequestID: req.PullRequestID, ParentID: req.ParentID, AuthorID: req.AuthorID, Body: req.Body}
+	var threadID interface{}
+	if req.ParentID != nil {
+		parentQuery, args, _ := psql.Select("COALESCE(thread_id, id)").
+			From("pr_comments").
+			Where(sq.Eq{"id": *req.ParentID, "request_id": req.PullRequestID}).
+			ToSql()
+		if err := tx.QueryRowContext(ctx, parentQuery, args...).Scan(&c.ThreadID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errs.NotFountError
+			}
+			return nil, err
+		}
+		threadID = c.ThreadID
+	}
+
+	q, args, err := psql.Insert("pr_comments").
+		Columns("request_id", "thread_id", "parent_id", "author_id", "body", "created_at").
+		Values(req.PullRequestID, threadID, req.ParentID, req.AuthorID, req.Body, CR.now()).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(&c.ID, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if req.ParentID == nil {
+		c.ThreadID = c.ID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// List returns the threads of the PR in the order they were opened.
+func (CR *CommentRepo) List(ctx context.Context, prID string, unresolvedOnly bool) ([]Thread, error) {
+	where := sq.And{sq.Eq{"c.request_id": prID}}
+	if unresolvedOnly {
+		where = append(where, sq.Expr("t.resolved_at IS NULL"))
+	}
+	threads, err := CR.threads(ctx, where)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(threads) == 0 {
+		psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+		var exists bool
+		q, args, _ := psql.Select().Column(sq.Expr("EXISTS (SELECT 1 FROM pr WHERE id = ?)", prID)).ToSql()
+		if err := CR.DB.QueryRowContext(ctx, q, args...).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errs.NotFountError
+		}
+	}
+	return threads, nil
+}
+
+// Edit replaces the body of a comment. Only its author may edit it. The PR is locked
+// against merging and closing like in checkParticipant.
+func (CR *CommentRepo) Edit(ctx context.Context, req EditRequest) (*Comment, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := CR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var authorID, status string
+	q, args, _ := psql.Select("c.author_id", "pr.pr_status").
+		From("pr_comments c").
+		Join("pr ON pr.id = c.request_id").
+		Where(sq.Eq{"c.id": req.CommentID}).
+		Suffix("FOR SHARE OF pr").
+		ToSql()
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(&authorID, &status); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+	if authorID != req.AuthorID {
+		return nil, errs.NotCommentAuthorError
+	}
+	if err := pr.RequireStatus(status, "comment", commentStates); err != nil {
+		return nil, err
+	}
+
+	var c Comment
+	var threadID sql.NullInt64
+	var parentID sql.NullInt64
+	var editedAt sql.NullTime
+	q, args, err = psql.Update("pr_comments").
+		Set("body", req.Body).
+		Set("edited_at", CR.now()).
+		Where(sq.Eq{"id": req.CommentID}).
+		Suffix("RETURNING id, request_id, thread_id, parent_id, author_id, body, created_at, edited_at").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(&c.ID, &c.PullRequestID, &threadID, &parentID, &c.AuthorID, &c.Body, &c.CreatedAt, &editedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	c.ThreadID = c.ID
+	if threadID.Valid {
+		c.ThreadID = int(threadID.Int64)
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+	if editedAt.Valid {
+		c.EditedAt = &editedAt.Time
+	}
+	return &c, nil
+}
+
+// Resolve marks the thread of the comment resolved, or unresolved again. The PR author
+// and its reviewers may do so.
+func (CR *CommentRepo) Resolve(ctx context.Context, req ResolveRequest, resolved bool) (*Thread, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := CR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var prID string
+	var threadID int
+	q, args, _ := psql.Select("request_id", "COALESCE(thread_id, id)").
+		From("pr_comments").
+		Where(sq.Eq{"id": req.CommentID}).
+		ToSql()
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(&prID, &threadID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+	if err := checkParticipant(ctx, tx, prID, req.UserID); err != nil {
+		return nil, err
+	}
+
+	builder := psql.Update("pr_comments").Where(sq.Eq{"id": threadID})
+	if resolved {
+		builder = builder.Set("resolved_by", req.UserID).Set("resolved_at", CR.now())
+	} else {
+		builder = builder.Set("resolved_by", nil).Set("resolved_at", nil)
+	}
+	q, args, err = builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	threads, err := CR.threads(ctx, sq.Eq{"t.id": threadID})
+	if err != nil {
+		return nil, err
+	}
+	if len(threads) == 0 {
+		return nil, errs.NotFountError
+	}
+	return &threads[0], nil
+}
+
+// checkParticipant locks the PR against merging and checks that userID is its author or
+// one of its current reviewers. Merged and closed PRs take no more comments.
+func checkParticipant(ctx context.Context, tx *sql.Tx, prID, userID string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	var status string
+	var participant bool
+	q, args, err := psql.Select("pr.pr_status").
+		Column(sq.Expr("pr.author_id = ? OR EXISTS (SELECT 1 FROM userspr ur WHERE ur.request_id = pr.id AND ur.user_id = ?)", userID, userID)).
+		From("pr").
+		Where(sq.Eq{"pr.id": prID}).
+		Suffix("FOR SHARE").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(&status, &participant); err != nil {
+		if err == sql.ErrNoRows {
+			return errs.NotFountError
+		}
+		return err
+	}
+	if err := pr.RequireStatus(status, "comment", commentStates); err != nil {
+		return err
+	}
+	if !participant {
+		return errs.NotParticipantError
+	}
+	return nil
+}
+
+// threads loads the threads whose comments match where. The opening comment of a
+// thread is aliased t, the comment itself c.
+func (CR *CommentRepo) threads(ctx context.Context, where sq.Sqlizer) ([]Thread, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Select(
+		"c.id", "c.request_id", "t.id", "c.parent_id", "c.author_id", "c.body", "c.created_at", "c.edited_at",
+		"COALESCE(t.resolved_by, '')", "t.resolved_at",
+	).
+		From("pr_comments c").
+		Join("pr_comments t ON t.id = COALESCE(c.thread_id, c.id)").
+		Where(where).
+		OrderBy("t.id", "c.id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := CR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	threads := make([]Thread, 0)
+	for rows.Next() {
+		var c Comment
+		var parentID sql.NullInt64
+		var editedAt, resolvedAt sql.NullTime
+		var resolvedBy string
+		if err := rows.Scan(&c.ID, &c.PullRequestID, &c.ThreadID, &parentID, &c.AuthorID, &c.Body, &c.CreatedAt, &editedAt, &resolvedBy, &resolvedAt); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			c.ParentID = &id
+		}
+		if editedAt.Valid {
+			c.EditedAt = &editedAt.Time
+		}
+
+		if n := len(threads); n == 0 || threads[n-1].ID != c.ThreadID {
+			t := Thread{ID: c.ThreadID, Resolved: resolvedAt.Valid, ResolvedBy: resolvedBy, Comments: make([]Comment, 0, 1)}
+			if resolvedAt.Valid {
+				t.ResolvedAt = &resolvedAt.Time
+			}
+			threads = append(threads, t)
+		}
+		threads[len(threads)-1].Comments = append(threads[len(threads)-1].Comments, c)
+	}
+	return threads, rows.Err()
+}
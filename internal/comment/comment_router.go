@@ -0,0 +1,141 @@
+package comment
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"pullreq/internal/errs"
+	jsonutils "pullreq/internal/json_utils"
+	"strings"
+)
+
+type CommentRouter struct {
+	CR CommentRepoInterface
+}
+
+func (cr *CommentRouter) AddHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.PullRequestID == "" || req.AuthorID == "" {
+		http.Error(w, "pull_request_id and author_id are required", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		http.Error(w, "body must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	c, err := cr.CR.Add(r.Context(), req)
+	if err != nil {
+		errorResp(w, err)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"comment": c}, http.StatusCreated)
+}
+
+func (cr *CommentRouter) ListHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		http.Error(w, "Missing pull_request_id query parameter", http.StatusBadRequest)
+		return
+	}
+	unresolvedOnly := r.URL.Query().Get("unresolved") == "true"
+
+	threads, err := cr.CR.List(r.Context(), prID, unresolvedOnly)
+	if err != nil {
+		errorResp(w, err)
+		return
+	}
+	resp := map[string]interface{}{
+		"pull_request_id": prID,
+		"threads":         threads,
+	}
+	jsonutils.JsonResponse(w, resp, http.StatusOK)
+}
+
+func (cr *CommentRouter) EditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req EditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if strings.TrimSpace(req.Body) == "" {
+		http.Error(w, "body must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	c, err := cr.CR.Edit(r.Context(), req)
+	if err != nil {
+		errorResp(w, err)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"comment": c}, http.StatusOK)
+}
+
+func (cr *CommentRouter) ResolveHandler(w http.ResponseWriter, r *http.Request) {
+	cr.resolve(w, r, true)
+}
+
+func (cr *CommentRouter) UnresolveHandler(w http.ResponseWriter, r *http.Request) {
+	cr.resolve(w, r, false)
+}
+
+func (cr *CommentRouter) resolve(w http.ResponseWriter, r *http.Request, resolved bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	thread, err := cr.CR.Resolve(r.Context(), req, resolved)
+	if err != nil {
+		errorResp(w, err)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"thread": thread}, http.StatusOK)
+}
+
+func errorResp(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errs.NotFountError):
+		errs.JsonCodeResp(w, errs.CodeNotFound, "PR or comment not found", http.StatusNotFound)
+	case errors.Is(err, errs.PRMergedError):
+		errs.JsonCodeResp(w, errs.CodePRMerged, "cannot comment on merged PR", http.StatusConflict)
+	case errors.Is(err, errs.InvalidTransitionError):
+		errs.JsonCodeResp(w, errs.CodeInvalidTransition, "cannot comment on closed PR", http.StatusConflict)
+	case errors.Is(err, errs.NotParticipantError):
+		errs.JsonCodeResp(w, errs.CodeNotParticipant, "only the author and the reviewers of the PR can comment", http.StatusForbidden)
+	case errors.Is(err, errs.NotCommentAuthorError):
+		errs.JsonCodeResp(w, errs.CodeNotCommentAuthor, "only the author can edit a comment", http.StatusForbidden)
+	default:
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+	}
+}
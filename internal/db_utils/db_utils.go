@@ -0,0 +1,17 @@
+package dbutils
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Queryer runs a query on a *sql.DB or within a *sql.Tx, so helpers can be shared by
+// code with and without a transaction.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// RowQueryer is the single row counterpart of Queryer.
+type RowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
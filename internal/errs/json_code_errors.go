@@ -16,6 +16,18 @@ const (
 	CodeNotAssigned ErrorCode = "NOT_ASSIGNED"
 	CodeNoCandidate ErrorCode = "NO_CANDIDATE"
 	CodeNotFound    ErrorCode = "NOT_FOUND"
+	CodeNoOwner     ErrorCode = "NO_OWNER"
+
+	CodeInvalidCandidate  ErrorCode = "INVALID_CANDIDATE"
+	CodeAffinityViolation ErrorCode = "AFFINITY_VIOLATION"
+	CodeSeniorRequired    ErrorCode = "SENIOR_REQUIRED"
+	CodeMergeBlocked      ErrorCode = "MERGE_BLOCKED"
+	CodeInvalidTransition ErrorCode = "INVALID_TRANSITION"
+	CodePRNotOpen         ErrorCode = "PR_NOT_OPEN"
+	CodeVersionConflict   ErrorCode = "VERSION_CONFLICT"
+	CodeNotParticipant    ErrorCode = "NOT_PARTICIPANT"
+	CodeNotCommentAuthor  ErrorCode = "NOT_COMMENT_AUTHOR"
+	CodeNotAdmin          ErrorCode = "NOT_ADMIN"
 )
 
 var (
@@ -25,12 +37,27 @@ var (
 	NotAssignedError error = fmt.Errorf("User not assigned")
 	NO_CANDIDATE     error = fmt.Errorf("No condidate")
 	NoCandidateError error = fmt.Errorf(":)")
+	NoOwnerError     error = fmt.Errorf("No code owner can be assigned")
+
+	FallbackTeamError      error = fmt.Errorf("Invalid fallback team")
+	InvalidCandidateError  error = fmt.Errorf("Requested reviewer is not an eligible candidate")
+	AffinityViolationError error = fmt.Errorf("Reviewer rules of the author cannot be satisfied")
+	SeniorRequiredError    error = fmt.Errorf("The last senior reviewer can only be replaced by a senior")
+	RotationMemberError    error = fmt.Errorf("Rotation members have to belong to the team")
+	MergeBlockedError      error = fmt.Errorf("The merge quorum of the team is not met")
+	InvalidTransitionError error = fmt.Errorf("The PR cannot move to this status")
+	PRNotOpenError         error = fmt.Errorf("The PR is closed or merged")
+	VersionConflictError   error = fmt.Errorf("The PR was edited since this version")
+	NotParticipantError    error = fmt.Errorf("User is neither the author nor a reviewer of the PR")
+	NotCommentAuthorError  error = fmt.Errorf("Only the author can edit a comment")
+	NotAdminError          error = fmt.Errorf("Only an admin can force a merge")
 )
 
 type ErrorResponse struct {
 	Error struct {
-		Code    ErrorCode `json:"code"`
-		Message string    `json:"message"`
+		Code    ErrorCode   `json:"code"`
+		Message string      `json:"message"`
+		Details interface{} `json:"details,omitempty"`
 	} `json:"error"`
 }
 
@@ -45,3 +72,17 @@ func JsonCodeResp(w http.ResponseWriter, errorCode ErrorCode, msg string, httpSt
 		fmt.Println(response)
 	}
 }
+
+// JsonCodeDetailsResp is JsonCodeResp with details explaining the error.
+func JsonCodeDetailsResp(w http.ResponseWriter, errorCode ErrorCode, msg string, details interface{}, httpStatus int) {
+	response := ErrorResponse{}
+	response.Error.Code = errorCode
+	response.Error.Message = msg
+	response.Error.Details = details
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		fmt.Println(response)
+	}
+}
@@ -0,0 +1,95 @@
+package ownership
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// Parse reads a GitHub-style CODEOWNERS file. Rules keep the order of the file,
+// later rules take precedence over earlier ones.
+func Parse(codeowners string) ([]Rule, error) {
+	rules := make([]Rule, 0)
+
+	scanner := bufio.NewScanner(strings.NewReader(codeowners))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		if _, err := compilePattern(fields[0]); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, Rule{
+			Position: len(rules) + 1,
+			Pattern:  fields[0],
+			Owners:   fields[1:],
+		})
+	}
+
+	return rules, scanner.Err()
+}
+
+// Match reports whether path is covered by a CODEOWNERS pattern.
+func Match(pattern, path string) bool {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(strings.TrimPrefix(path, "/"))
+}
+
+// compilePattern translates a CODEOWNERS (gitignore-like) pattern into a regexp:
+// a leading or inner "/" anchors the pattern at the repository root, "*" and "?"
+// stay within a path segment, "**" spans segments and a pattern naming a directory
+// covers everything beneath it.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				if i+2 < len(p) && p[i+2] == '/' {
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if strings.HasSuffix(p, "/*") {
+		// "docs/*" owns the files of docs but not of its subdirectories
+		b.WriteString("$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,272 @@
+package ownership
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Rule maps a path pattern to its owners. Owners holds the raw CODEOWNERS tokens
+// ("@user", "@org/team"), Users and Teams the owners stored in the database.
+type Rule struct {
+	Position int      `json:"position"`
+	Pattern  string   `json:"pattern"`
+	Owners   []string `json:"-"`
+	Users    []string `json:"users"`
+	Teams    []string `json:"teams"`
+}
+
+type ImportResult struct {
+	Imported   int      `json:"imported"`
+	Unresolved []string `json:"unresolved_owners"`
+}
+
+type OwnershipRepoInterface interface {
+	ImportRules(ctx context.Context, rules []Rule) (*ImportResult, error)
+	GetRules(ctx context.Context) ([]Rule, error)
+	OwnersForPaths(ctx context.Context, paths []string) ([]string, error)
+}
+
+type OwnershipRepo struct {
+	DB *sql.DB
+}
+
+// ImportRules replaces all ownership rules. Owners that match no user or team are
+// skipped and reported back.
+func (OR *OwnershipRepo) ImportRules(ctx context.Context, rules []Rule) (*ImportResult, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := OR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM ownership_rules"); err != nil {
+		return nil, err
+	}
+
+	res := &ImportResult{Unresolved: make([]string, 0)}
+	unresolved := make(map[string]bool)
+	for _, rule := range rules {
+		ruleQuery, args, err := psql.Insert("ownership_rules").
+			Columns("position", "pattern").
+			Values(rule.Position, rule.Pattern).
+			Suffix("RETURNING id").
+			ToSql()
+		if err != nil {
+			return nil, err
+		}
+
+		var ruleID int
+		if err := tx.QueryRowContext(ctx, ruleQuery, args...).Scan(&ruleID); err != nil {
+			return nil, err
+		}
+
+		seen := make(map[string]bool, len(rule.Owners))
+		for _, owner := range rule.Owners {
+			if seen[owner] {
+				continue
+			}
+			seen[owner] = true
+
+			userID, teamID, err := resolveOwner(ctx, tx, owner)
+			if err != nil {
+				return nil, err
+			}
+			if userID == nil && teamID == nil {
+				if !unresolved[owner] {
+					unresolved[owner] = true
+					res.Unresolved = append(res.Unresolved, owner)
+				}
+				continue
+			}
+
+			ownerQuery, args, err := psql.Insert("ownership_rule_owners").
+				Columns("rule_id", "user_id", "team_id").
+				Values(ruleID, userID, teamID).
+				ToSql()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := tx.ExecContext(ctx, ownerQuery, args...); err != nil {
+				return nil, err
+			}
+		}
+		res.Imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// resolveOwner maps "@org/team" to a team by name and "@user" to a user by id or username.
+func resolveOwner(ctx context.Context, tx *sql.Tx, owner string) (*string, *int, error) {
+	if !strings.HasPrefix(owner, "@") {
+		return nil, nil, nil
+	}
+	name := strings.TrimPrefix(owner, "@")
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		q, args, err := psql.Select("id").From("teams").Where(sq.Eq{"team_name": name[i+1:]}).ToSql()
+		if err != nil {
+			return nil, nil, err
+		}
+		var teamID int
+		if err := tx.QueryRowContext(ctx, q, args...).Scan(&teamID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, nil
+			}
+			return nil, nil, err
+		}
+		return nil, &teamID, nil
+	}
+
+	q, args, err := psql.Select("id").
+		From("users").
+		Where(sq.Or{sq.Eq{"id": name}, sq.Eq{"username": name}}).
+		ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// an exact id match wins over a username match
+	var userID *string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		if userID == nil || id == name {
+			userID = &id
+		}
+	}
+	return userID, nil, rows.Err()
+}
+
+func (OR *OwnershipRepo) GetRules(ctx context.Context) ([]Rule, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Select("r.id", "r.position", "r.pattern", "o.user_id", "t.team_name").
+		From("ownership_rules r").
+		LeftJoin("ownership_rule_owners o ON o.rule_id = r.id").
+		LeftJoin("teams t ON t.id = o.team_id").
+		OrderBy("r.position", "o.user_id", "t.team_name").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := OR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]Rule, 0)
+	lastID := -1
+	for rows.Next() {
+		var id, position int
+		var pattern string
+		var userID, teamName sql.NullString
+		if err := rows.Scan(&id, &position, &pattern, &userID, &teamName); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			rules = append(rules, Rule{Position: position, Pattern: pattern, Users: []string{}, Teams: []string{}})
+			lastID = id
+		}
+		rule := &rules[len(rules)-1]
+		if userID.Valid {
+			rule.Users = append(rule.Users, userID.String)
+		}
+		if teamName.Valid {
+			rule.Teams = append(rule.Teams, teamName.String)
+		}
+	}
+
+	return rules, rows.Err()
+}
+
+// OwnersForPaths returns the IDs of users owning at least one of the paths. For every
+// path only the last matching rule counts, team owners are expanded to their members.
+func (OR *OwnershipRepo) OwnersForPaths(ctx context.Context, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return []string{}, nil
+	}
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Select("r.id", "r.pattern", "COALESCE(o.user_id, u.id)").
+		From("ownership_rules r").
+		LeftJoin("ownership_rule_owners o ON o.rule_id = r.id").
+		LeftJoin("users u ON u.team_id = o.team_id").
+		OrderBy("r.position", "r.id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := OR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type ruleOwners struct {
+		pattern string
+		owners  []string
+	}
+	rules := make([]*ruleOwners, 0)
+	lastID := -1
+	for rows.Next() {
+		var id int
+		var pattern string
+		var owner sql.NullString
+		if err := rows.Scan(&id, &pattern, &owner); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			rules = append(rules, &ruleOwners{pattern: pattern})
+			lastID = id
+		}
+		if owner.Valid {
+			rules[len(rules)-1].owners = append(rules[len(rules)-1].owners, owner.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	owners := make([]string, 0)
+	for _, path := range paths {
+		for i := len(rules) - 1; i >= 0; i-- {
+			if !Match(rules[i].pattern, path) {
+				continue
+			}
+			for _, o := range rules[i].owners {
+				if !seen[o] {
+					seen[o] = true
+					owners = append(owners, o)
+				}
+			}
+			break
+		}
+	}
+	sort.Strings(owners)
+
+	return owners, nil
+}
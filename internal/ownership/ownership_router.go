@@ -0,0 +1,61 @@
+package ownership
+
+import (
+	"encoding/json"
+	"net/http"
+	jsonutils "pullreq/internal/json_utils"
+)
+
+type OwnershipRouter struct {
+	OR OwnershipRepoInterface
+}
+
+type ImportRequest struct {
+	Codeowners string `json:"codeowners"` // content of a CODEOWNERS file
+}
+
+func (or *OwnershipRouter) ImportCodeowners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	rules, err := Parse(req.Codeowners)
+	if err != nil {
+		http.Error(w, "Invalid CODEOWNERS pattern", http.StatusBadRequest)
+		return
+	}
+
+	res, err := or.OR.ImportRules(r.Context(), rules)
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"imported":          res.Imported,
+		"unresolved_owners": res.Unresolved,
+	}
+	jsonutils.JsonResponse(w, response, http.StatusOK)
+}
+
+func (or *OwnershipRouter) GetRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rules, err := or.OR.GetRules(r.Context())
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"rules": rules}, http.StatusOK)
+}
@@ -0,0 +1,749 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"pullreq/internal/affinity"
+	dbutils "pullreq/internal/db_utils"
+	"pullreq/internal/errs"
+	"pullreq/internal/team"
+	"pullreq/internal/user"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// assignment describes one reviewer selection for a pull request.
+type assignment struct {
+	teamID   int
+	settings *team.TeamSettings
+	authorID string
+	labels   []string
+	owners   []string        // owners of the changed files, one of them has to be picked
+	exclude  map[string]bool // users that must not be picked
+	count    int
+
+	// affinity rules of the author
+	avoid  []string // never picked
+	prefer []string // preferred over everybody else
+	always []string // picked before anybody else, failing when one of them cannot be
+
+	duty []string // rotation order from the duty reviewer on, the first available one is picked
+
+	requireSenior bool // one reviewer should be senior or above
+	seniorOnly    bool // only seniors may be picked, used when the last senior is replaced
+
+	requested string // explicitly requested reviewer, only checked against the pools
+
+	now time.Time // reviewers inside their working hours at now are preferred
+
+	dryRun bool // the assignment is only previewed
+}
+
+// selection is the outcome of pickReviewers together with what is needed to explain it.
+type selection struct {
+	reviewers []string
+	fallback  []string // reviewers taken from a fallback team
+	senior    bool     // one of the reviewers is senior or above
+
+	seed     uint64
+	at       time.Time // moment the reviewers were picked at
+	pool     []PoolCandidate
+	excluded []Exclusion
+	seen     map[string]bool // users already listed in pool or excluded
+	picks    []Pick
+	draws    []Draw
+}
+
+// recorder hands selections on to the selector and records them in res as draws of
+// the current stage.
+type recorder struct {
+	selector ReviewerSelector
+	res      *selection
+	stage    string
+}
+
+func (r *recorder) Select(ctx context.Context, in SelectionInput) ([]string, error) {
+	in.Trace = &Trace{}
+	ids, err := r.selector.Select(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	tier := ""
+	if in.Tier >= 0 && in.Tier < len(tierNames) {
+		tier = tierNames[in.Tier]
+	}
+	candidates := make([]DrawCandidate, len(in.Candidates))
+	for i, c := range in.Candidates {
+		candidates[i] = DrawCandidate{UserID: c.User.Id, OpenReviews: c.OpenReviews}
+	}
+	r.res.draws = append(r.res.draws, Draw{
+		Stage:        r.stage,
+		Tier:         tier,
+		TeamID:       in.TeamID,
+		Count:        in.Count,
+		Candidates:   candidates,
+		Picked:       ids,
+		CursorBefore: in.Trace.CursorBefore,
+		CursorAfter:  in.Trace.CursorAfter,
+	})
+	for _, id := range ids {
+		r.res.picks = append(r.res.picks, Pick{UserID: id, Stage: r.stage, Tier: tier})
+	}
+	return ids, nil
+}
+
+// pickReviewers selects up to a.count reviewers from the team of a.teamID. Reviewers
+// the author's rules always ask for come first, followed by the duty reviewer of the
+// team rotation when the team asks for one. When the changed files have owners,
+// one reviewer is taken from the owners, who may belong to any team. When the team
+// requires a senior reviewer and nobody picked so far is senior, one senior is picked
+// from the team or its fallback teams if there is any. The remaining places are filled
+// from the team, and when it runs out of candidates from the fallback teams in order.
+// Within every pool reviewers preferred by the rules come first, then those with tags
+// matching the labels.
+func (PR *PullRequestRepo) pickReviewers(ctx context.Context, tx *sql.Tx, a assignment) (*selection, error) {
+	res := &selection{
+		reviewers: make([]string, 0, a.count),
+		fallback:  make([]string, 0),
+		seed:      rand.Uint64(),
+		pool:      make([]PoolCandidate, 0),
+		excluded:  make([]Exclusion, 0),
+		seen:      make(map[string]bool),
+		picks:     make([]Pick, 0, a.count),
+		draws:     make([]Draw, 0),
+	}
+	rec := &recorder{selector: PR.selector(), res: res}
+	in := SelectionInput{
+		TeamID:   a.teamID,
+		Strategy: a.settings.ReviewerStrategy,
+		Count:    a.count,
+		Tx:       tx,
+		DryRun:   a.dryRun,
+		Rand:     rand.New(rand.NewPCG(res.seed, res.seed)),
+	}
+	if a.exclude == nil {
+		a.exclude = make(map[string]bool)
+	}
+	if a.now.IsZero() {
+		a.now = PR.now()
+	}
+	res.at = a.now
+	pick := func(stage string, in SelectionInput, pool []Candidate) error {
+		if a.seniorOnly {
+			pool, _ = partition(pool, func(c Candidate) bool { return c.User.IsSenior() })
+		}
+
+		var ids []string
+		if a.requested != "" {
+			for _, c := range pool {
+				if c.User.Id == a.requested && !a.exclude[c.User.Id] {
+					ids = []string{a.requested}
+					res.picks = append(res.picks, Pick{UserID: a.requested, Stage: StageRequested})
+					a.requested = ""
+					break
+				}
+			}
+		} else {
+			remaining := make([]Candidate, 0, len(pool))
+			for _, c := range pool {
+				if !a.exclude[c.User.Id] {
+					remaining = append(remaining, c)
+				}
+			}
+			var err error
+			rec.stage = stage
+			ids, err = SelectPreferred(ctx, rec, in, a.tiers(remaining)...)
+			if err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			a.exclude[id] = true
+		}
+		res.reviewers = append(res.reviewers, ids...)
+		if in.TeamID != a.teamID {
+			res.fallback = append(res.fallback, ids...)
+		}
+		return nil
+	}
+
+	if len(a.always) > 0 {
+		alwaysUsers, err := PR.UR.GetUsers(ctx, a.always)
+		if err != nil {
+			return nil, err
+		}
+		alwaysPool, err := PR.poolOf(ctx, tx, alwaysUsers, a, res, SourceRule)
+		if err != nil {
+			return nil, err
+		}
+		// somebody out of office is skipped or stood in for by the delegate
+		for _, id := range a.always {
+			if !inPool(alwaysPool, id) && res.reason(id) != ExcludedOutOfOffice {
+				return nil, errs.AffinityViolationError
+			}
+		}
+		for _, c := range alwaysPool {
+			a.exclude[c.User.Id] = true
+			res.reviewers = append(res.reviewers, c.User.Id)
+			res.picks = append(res.picks, Pick{UserID: c.User.Id, Stage: StageRule})
+			res.senior = res.senior || c.User.IsSenior()
+		}
+	}
+
+	if len(a.duty) > 0 && a.count > 0 {
+		// the next member in the rotation stands in for one who cannot review
+		for _, id := range a.duty {
+			if slices.Contains(res.reviewers, id) {
+				break
+			}
+			dutyUsers, err := PR.UR.GetUsers(ctx, []string{id})
+			if err != nil {
+				return nil, err
+			}
+			dutyPool, _, err := candidatePool(ctx, tx, dutyUsers, a, res, SourceDuty)
+			if err != nil {
+				return nil, err
+			}
+			if len(dutyPool) > 0 {
+				a.exclude[id] = true
+				res.reviewers = append(res.reviewers, id)
+				res.picks = append(res.picks, Pick{UserID: id, Stage: StageDuty})
+				res.senior = res.senior || dutyPool[0].User.IsSenior()
+				break
+			}
+		}
+	}
+
+	ownerPicked := false
+	for _, id := range res.reviewers {
+		ownerPicked = ownerPicked || slices.Contains(a.owners, id)
+	}
+	if len(a.owners) > 0 && a.count > 0 && !ownerPicked {
+		ownerUsers, err := PR.UR.GetUsers(ctx, a.owners)
+		if err != nil {
+			return nil, err
+		}
+		ownerPool, err := PR.poolOf(ctx, tx, ownerUsers, a, res, SourceOwner)
+		if err != nil {
+			return nil, err
+		}
+		if len(ownerPool) == 0 {
+			return nil, errs.NoOwnerError
+		}
+
+		ownerIn := in
+		ownerIn.Count = 1
+		if a.requireSenior && !res.senior {
+			// an owner who is also senior satisfies both policies at once
+			seniors, _ := partition(ownerPool, func(c Candidate) bool { return c.User.IsSenior() })
+			if len(seniors) > 0 && a.requested == "" {
+				ownerPool = seniors
+			}
+		}
+		if err := pick(StageOwner, ownerIn, ownerPool); err != nil {
+			return nil, err
+		}
+		if a.requested != "" {
+			// the requested reviewer cannot replace the last code owner
+			return nil, errs.InvalidCandidateError
+		}
+		res.senior = res.senior || anySenior(ownerPool, res.reviewers)
+	}
+
+	// pools are built once per team so the senior search and the filling see the same candidates
+	teams := append([]int{a.teamID}, a.settings.FallbackTeamIDs...)
+	pools := make(map[int][]Candidate, len(teams))
+	poolFor := func(teamID int) ([]Candidate, error) {
+		if pool, ok := pools[teamID]; ok {
+			return pool, nil
+		}
+		members, err := PR.TR.GetTeamMember(ctx, teamID)
+		if err != nil {
+			return nil, err
+		}
+		source := SourceTeam
+		if teamID != a.teamID {
+			source = SourceFallback
+		}
+		pool, err := PR.poolOf(ctx, tx, members, a, res, source)
+		if err != nil {
+			return nil, err
+		}
+		pools[teamID] = pool
+		return pool, nil
+	}
+
+	if a.requireSenior && !res.senior && len(res.reviewers) < a.count && a.requested == "" {
+		for _, teamID := range teams {
+			pool, err := poolFor(teamID)
+			if err != nil {
+				return nil, err
+			}
+			seniors, _ := partition(pool, func(c Candidate) bool { return c.User.IsSenior() })
+			if len(seniors) == 0 {
+				continue
+			}
+
+			seniorIn := in
+			seniorIn.TeamID = teamID
+			seniorIn.Count = 1
+			before := len(res.reviewers)
+			if err := pick(StageSenior, seniorIn, seniors); err != nil {
+				return nil, err
+			}
+			if len(res.reviewers) > before {
+				res.senior = true
+				break
+			}
+		}
+	}
+
+	for _, teamID := range teams {
+		if len(res.reviewers) >= a.count {
+			break
+		}
+
+		pool, err := poolFor(teamID)
+		if err != nil {
+			return nil, err
+		}
+
+		teamIn := in
+		teamIn.TeamID = teamID
+		teamIn.Count = a.count - len(res.reviewers)
+		stage := StageTeam
+		if teamID != a.teamID {
+			stage = StageFallback
+		}
+		if err := pick(stage, teamIn, pool); err != nil {
+			return nil, err
+		}
+	}
+	if a.requested != "" {
+		return nil, errs.InvalidCandidateError
+	}
+	if a.seniorOnly && len(res.reviewers) > 0 {
+		res.senior = true
+	}
+
+	return res, nil
+}
+
+// anySenior reports whether one of ids is a senior candidate of the pool.
+func anySenior(pool []Candidate, ids []string) bool {
+	for _, c := range pool {
+		if c.User.IsSenior() && slices.Contains(ids, c.User.Id) {
+			return true
+		}
+	}
+	return false
+}
+
+// tiers splits the pool into reviewers preferred by the rules, reviewers whose tags
+// match the labels and the rest. Reviewers who are working at a.now, or start within
+// the hours the team looks ahead, come before all of those who are not. The tiers are
+// named by tierNames.
+func (a assignment) tiers(pool []Candidate) [][]Candidate {
+	ahead := time.Duration(a.settings.WorkingHoursAhead) * time.Hour
+	available, away := partition(pool, func(c Candidate) bool { return c.User.UntilWorking(a.now) <= ahead })
+
+	res := make([][]Candidate, 0, 6)
+	for _, part := range [][]Candidate{available, away} {
+		preferred, others := partition(part, func(c Candidate) bool { return slices.Contains(a.prefer, c.User.Id) })
+		matching, rest := partition(others, func(c Candidate) bool { return c.User.HasAnyTag(a.labels) })
+		res = append(res, preferred, matching, rest)
+	}
+	return res
+}
+
+// poolOf builds the candidate pool of users and puts the delegates of those out of
+// office in their place. Delegates of delegates are not followed.
+func (PR *PullRequestRepo) poolOf(ctx context.Context, tx *sql.Tx, users []*user.User, a assignment, res *selection, source string) ([]Candidate, error) {
+	pool, delegates, err := candidatePool(ctx, tx, users, a, res, source)
+	if err != nil || len(delegates) == 0 {
+		return pool, err
+	}
+
+	delegateUsers, err := PR.UR.GetUsers(ctx, delegates)
+	if err != nil {
+		return nil, err
+	}
+	standIns, _, err := candidatePool(ctx, tx, delegateUsers, a, res, SourceDelegate)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range standIns {
+		if !inPool(pool, c.User.Id) {
+			pool = append(pool, c)
+		}
+	}
+	sort.Slice(pool, func(i, j int) bool { return pool[i].User.Id < pool[j].User.Id })
+	return pool, nil
+}
+
+// candidatePool keeps active users that are neither the author nor excluded, are not
+// out of office and still have capacity for one more review. Every user seen for the
+// first time is recorded in res either as a candidate or with the reason it was left
+// out. The delegates of users out of office are returned along with the pool.
+func candidatePool(ctx context.Context, tx *sql.Tx, users []*user.User, a assignment, res *selection, source string) ([]Candidate, []string, error) {
+	sorted := make([]*user.User, len(users))
+	copy(sorted, users)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+
+	eligible := make([]*user.User, 0, len(sorted))
+	for _, u := range sorted {
+		reason := ""
+		switch {
+		case u.Id == a.authorID:
+			reason = ExcludedAuthor
+		case a.exclude[u.Id]:
+			reason = ExcludedAssigned
+		case slices.Contains(a.avoid, u.Id):
+			reason = ExcludedAvoided
+		case !u.IsActive:
+			reason = ExcludedInactive
+		}
+		if reason == "" {
+			eligible = append(eligible, u)
+		} else {
+			res.exclude(u.Id, reason)
+		}
+	}
+
+	ids := make([]string, len(eligible))
+	for i, u := range eligible {
+		ids[i] = u.Id
+	}
+	absent, err := user.Absent(ctx, tx, ids, a.now)
+	if err != nil {
+		return nil, nil, err
+	}
+	delegates := make([]string, 0, len(absent))
+	present := make([]*user.User, 0, len(eligible))
+	for _, u := range eligible {
+		delegate, away := absent[u.Id]
+		if !away {
+			present = append(present, u)
+			continue
+		}
+		res.exclude(u.Id, ExcludedOutOfOffice)
+		if delegate != "" && !slices.Contains(delegates, delegate) {
+			delegates = append(delegates, delegate)
+		}
+	}
+
+	loaded, err := loadCandidates(ctx, tx, present)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool := make([]Candidate, 0, len(loaded))
+	for _, c := range loaded {
+		if c.AtCapacity() {
+			res.exclude(c.User.Id, ExcludedAtCapacity)
+			continue
+		}
+		pool = append(pool, c)
+		if !res.seen[c.User.Id] {
+			res.seen[c.User.Id] = true
+			res.pool = append(res.pool, PoolCandidate{UserID: c.User.Id, Source: source, OpenReviews: c.OpenReviews})
+		}
+	}
+	return pool, delegates, nil
+}
+
+// reason returns why the user was left out, or an empty string.
+func (res *selection) reason(userID string) string {
+	for _, e := range res.excluded {
+		if e.UserID == userID {
+			return e.Reason
+		}
+	}
+	return ""
+}
+
+func inPool(pool []Candidate, userID string) bool {
+	for _, c := range pool {
+		if c.User.Id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (res *selection) exclude(userID, reason string) {
+	if res.seen[userID] {
+		return
+	}
+	res.seen[userID] = true
+	res.excluded = append(res.excluded, Exclusion{UserID: userID, Reason: reason})
+}
+
+// loadCandidates pairs users with the number of OPEN pull requests they are assigned to review.
+func loadCandidates(ctx context.Context, q dbutils.Queryer, users []*user.User) ([]Candidate, error) {
+	candidates := make([]Candidate, 0, len(users))
+	if len(users) == 0 {
+		return candidates, nil
+	}
+
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.Id
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	loadQuery, args, err := psql.
+		Select("ur.user_id", "COUNT(*)").
+		From("userspr ur").
+		Join("pr ON pr.id = ur.request_id").
+		Where(sq.Eq{"ur.user_id": ids, "pr.pr_status": StatusOpen}).
+		GroupBy("ur.user_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, loadQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	load := make(map[string]int, len(users))
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		load[userID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagsQuery, args, err := psql.
+		Select("user_id", "tag").
+		From("user_tags").
+		Where(sq.Eq{"user_id": ids}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	tagRows, err := q.QueryContext(ctx, tagsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	tags := make(map[string][]string, len(users))
+	for tagRows.Next() {
+		var userID, tag string
+		if err := tagRows.Scan(&userID, &tag); err != nil {
+			return nil, err
+		}
+		tags[userID] = append(tags[userID], tag)
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		u.Tags = tags[u.Id]
+		candidates = append(candidates, Candidate{User: u, OpenReviews: load[u.Id]})
+	}
+
+	return candidates, nil
+}
+
+func getLabels(ctx context.Context, q dbutils.Queryer, prID string) ([]string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	labelsQuery, args, err := psql.
+		Select("label").
+		From("pr_labels").
+		Where(sq.Eq{"request_id": prID}).
+		OrderBy("label").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, labelsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := make([]string, 0)
+	for rows.Next() {
+		var label string
+		if err := rows.Scan(&label); err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, rows.Err()
+}
+
+func insertLabels(ctx context.Context, tx *sql.Tx, prID string, labels []string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	insertBuilder := psql.Insert("pr_labels").Columns("request_id", "label")
+	for _, label := range labels {
+		insertBuilder = insertBuilder.Values(prID, label)
+	}
+
+	q, args, err := insertBuilder.ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, q, args...)
+	return err
+}
+
+// ownersForPaths returns the owners of the changed files other than the author.
+func (PR *PullRequestRepo) ownersForPaths(ctx context.Context, files []string, authorID string) ([]string, error) {
+	if PR.OR == nil || len(files) == 0 {
+		return nil, nil
+	}
+
+	owners, err := PR.OR.OwnersForPaths(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(owners))
+	for _, o := range owners {
+		if o != authorID {
+			res = append(res, o)
+		}
+	}
+	return res, nil
+}
+
+// dutyFor returns the rotation order of the team starting with the duty reviewer at
+// the moment at, empty when the team does not ask for duty reviewers.
+func (PR *PullRequestRepo) dutyFor(ctx context.Context, teamID int, settings *team.TeamSettings, at time.Time) ([]string, error) {
+	if PR.RR == nil || !settings.DutyReviewer {
+		return nil, nil
+	}
+	rot, err := PR.RR.RotationFor(ctx, teamID)
+	if err != nil || rot == nil {
+		return nil, err
+	}
+	return rot.Order(at), nil
+}
+
+// rulesFor returns the affinity rules of the author, an empty set when no rules are configured.
+func (PR *PullRequestRepo) rulesFor(ctx context.Context, authorID string) (*affinity.RuleSet, error) {
+	if PR.AR == nil {
+		return &affinity.RuleSet{}, nil
+	}
+	return PR.AR.RulesFor(ctx, authorID)
+}
+
+// normalizeFiles trims and deduplicates changed file paths.
+func normalizeFiles(files []string) []string {
+	seen := make(map[string]bool, len(files))
+	res := make([]string, 0, len(files))
+	for _, f := range files {
+		f = strings.TrimPrefix(strings.TrimSpace(f), "/")
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		res = append(res, f)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func getFiles(ctx context.Context, q dbutils.Queryer, prID string) ([]string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	filesQuery, args, err := psql.
+		Select("path").
+		From("pr_files").
+		Where(sq.Eq{"request_id": prID}).
+		OrderBy("path").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, filesQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		files = append(files, path)
+	}
+
+	return files, rows.Err()
+}
+
+func insertFiles(ctx context.Context, tx *sql.Tx, prID string, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	insertBuilder := psql.Insert("pr_files").Columns("request_id", "path")
+	for _, f := range files {
+		insertBuilder = insertBuilder.Values(prID, f)
+	}
+
+	q, args, err := insertBuilder.ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, q, args...)
+	return err
+}
+
+func getReviewers(ctx context.Context, q dbutils.Queryer, prID string) ([]string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	reviewersQuery, args, err := psql.
+		Select("user_id").
+		From("userspr").
+		Where(sq.Eq{"request_id": prID}).
+		OrderBy("user_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, reviewersQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviewers := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, id)
+	}
+
+	return reviewers, rows.Err()
+}
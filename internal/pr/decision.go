@@ -0,0 +1,341 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/rand/v2"
+	"pullreq/internal/errs"
+	"pullreq/internal/user"
+	"slices"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Kinds of assignment decisions.
+const (
+	DecisionCreate   = "create"
+	DecisionReassign = "reassign"
+	DecisionReady    = "ready_for_review" // the deferred assignment of a draft
+)
+
+// Reasons a user was left out of the candidate pool.
+const (
+	ExcludedInactive    = "inactive"
+	ExcludedAuthor      = "author"
+	ExcludedAtCapacity  = "at_capacity"
+	ExcludedAssigned    = "already_assigned"
+	ExcludedAvoided     = "avoided" // a never rule of the author
+	ExcludedOutOfOffice = "out_of_office"
+)
+
+// Sources a candidate was drawn from.
+const (
+	SourceOwner    = "owner"
+	SourceTeam     = "team"
+	SourceFallback = "fallback"
+	SourceRule     = "rule"     // an always rule of the author
+	SourceDelegate = "delegate" // stands in for somebody out of office
+	SourceDuty     = "duty"     // the duty reviewer of the team rotation
+)
+
+// Stages of a selection, in the order pickReviewers goes through them.
+const (
+	StageRule      = "rule"      // an always rule of the author
+	StageDuty      = "duty"      // the duty reviewer of the team rotation
+	StageRequested = "requested" // the reviewer asked for in a reassignment
+	StageOwner     = "owner"
+	StageSenior    = "senior"
+	StageTeam      = "team"
+	StageFallback  = "fallback"
+)
+
+// Tiers of the candidates within a stage, most preferred first.
+const (
+	TierPreferred     = "preferred" // preferred by a rule of the author
+	TierMatching      = "matching"  // tags match the labels
+	TierOther         = "other"
+	TierAwayPreferred = "away_preferred" // the same, but outside their working hours
+	TierAwayMatching  = "away_matching"
+	TierAwayOther     = "away_other"
+)
+
+// tierNames follows the order of the tiers returned by assignment.tiers.
+var tierNames = []string{TierPreferred, TierMatching, TierOther, TierAwayPreferred, TierAwayMatching, TierAwayOther}
+
+// Decision records how the reviewers of one assignment were chosen: who was considered,
+// who was left out and why, and the stage and tier every reviewer came from. Reviewers
+// of the rule, duty and requested stages are picked without the selector, all others in
+// the draws, the selector calls in the order they were made. Seed seeds the randomness
+// shared by the draws, so Replay repeats them.
+type Decision struct {
+	Kind      string          `json:"kind"`
+	Strategy  string          `json:"strategy"`
+	Seed      uint64          `json:"seed,string"`
+	Reviewers []string        `json:"reviewers"`
+	Picks     []Pick          `json:"picks"`
+	Pool      []PoolCandidate `json:"candidate_pool"`
+	Excluded  []Exclusion     `json:"excluded"`
+	Draws     []Draw          `json:"draws"`
+	DecidedAt time.Time       `json:"decided_at"`
+}
+
+// Pick is a reviewer of a decision with the stage and tier that supplied them. Tier is
+// empty for reviewers picked without the selector.
+type Pick struct {
+	UserID string `json:"user_id"`
+	Stage  string `json:"stage"`
+	Tier   string `json:"tier,omitempty"`
+}
+
+// Draw is one call of the selector: the candidates of a tier in the order they were
+// handed over, how many reviewers were asked for and who was picked.
+type Draw struct {
+	Stage        string          `json:"stage"`
+	Tier         string          `json:"tier"`
+	TeamID       int             `json:"team_id"`
+	Count        int             `json:"count"`
+	Candidates   []DrawCandidate `json:"candidates"`
+	Picked       []string        `json:"picked"`
+	CursorBefore string          `json:"cursor_before,omitempty"` // round robin only
+	CursorAfter  string          `json:"cursor_after,omitempty"`
+}
+
+type DrawCandidate struct {
+	UserID      string `json:"user_id"`
+	OpenReviews int    `json:"open_reviews"`
+}
+
+type PoolCandidate struct {
+	UserID      string `json:"user_id"`
+	Source      string `json:"source"`
+	OpenReviews int    `json:"open_reviews"`
+}
+
+type Exclusion struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+func insertDecision(ctx context.Context, tx *sql.Tx, prID, kind, strategy string, sel *selection) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	decisionQuery, args, err := psql.Insert("assignment_decisions").
+		Columns("request_id", "kind", "strategy", "seed", "decided_at").
+		Values(prID, kind, strategy, int64(sel.seed), sel.at).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	var decisionID int
+	if err := tx.QueryRowContext(ctx, decisionQuery, args...).Scan(&decisionID); err != nil {
+		return err
+	}
+
+	if len(sel.pool) > 0 || len(sel.excluded) > 0 {
+		insertBuilder := psql.Insert("assignment_decision_users").
+			Columns("decision_id", "user_id", "source", "open_reviews", "excluded_reason", "picked", "stage", "tier")
+		for _, c := range sel.pool {
+			var stage, tier interface{}
+			if i := slices.IndexFunc(sel.picks, func(p Pick) bool { return p.UserID == c.UserID }); i >= 0 {
+				stage, tier = sel.picks[i].Stage, sel.picks[i].Tier
+			}
+			insertBuilder = insertBuilder.Values(decisionID, c.UserID, c.Source, c.OpenReviews, nil, slices.Contains(sel.reviewers, c.UserID), stage, tier)
+		}
+		for _, e := range sel.excluded {
+			insertBuilder = insertBuilder.Values(decisionID, e.UserID, nil, nil, e.Reason, false, nil, nil)
+		}
+
+		usersQuery, args, err := insertBuilder.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, usersQuery, args...); err != nil {
+			return err
+		}
+	}
+
+	if len(sel.draws) == 0 {
+		return nil
+	}
+	drawsBuilder := psql.Insert("assignment_decision_draws").
+		Columns("decision_id", "position", "stage", "tier", "team_id", "wanted", "candidates", "picked", "cursor_before", "cursor_after")
+	for i, d := range sel.draws {
+		candidates, err := json.Marshal(d.Candidates)
+		if err != nil {
+			return err
+		}
+		picked, err := json.Marshal(d.Picked)
+		if err != nil {
+			return err
+		}
+		drawsBuilder = drawsBuilder.Values(decisionID, i, d.Stage, d.Tier, d.TeamID, d.Count, string(candidates), string(picked), d.CursorBefore, d.CursorAfter)
+	}
+	drawsQuery, args, err := drawsBuilder.ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, drawsQuery, args...)
+	return err
+}
+
+// Explain returns the assignment decisions of a PR, oldest first.
+func (PR *PullRequestRepo) Explain(ctx context.Context, ID string) ([]Decision, error) {
+	if err := PR.Check(ctx, ID); err == nil {
+		return nil, errs.NotFountError
+	} else if err != errs.ExistError {
+		return nil, err
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.
+		Select("d.id", "d.kind", "d.strategy", "d.seed", "d.decided_at",
+			"du.user_id", "du.source", "du.open_reviews", "du.excluded_reason", "COALESCE(du.picked, FALSE)",
+			"COALESCE(du.stage, '')", "COALESCE(du.tier, '')").
+		From("assignment_decisions d").
+		LeftJoin("assignment_decision_users du ON du.decision_id = d.id").
+		Where(sq.Eq{"d.request_id": ID}).
+		OrderBy("d.id", "du.user_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := PR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	decisions := make([]Decision, 0)
+	byID := make(map[int]int) // decision id to its index in decisions
+	lastID := -1
+	for rows.Next() {
+		var id int
+		var seed int64
+		var d Decision
+		var userID, source, reason sql.NullString
+		var openReviews sql.NullInt64
+		var picked bool
+		var stage, tier string
+		if err := rows.Scan(&id, &d.Kind, &d.Strategy, &seed, &d.DecidedAt, &userID, &source, &openReviews, &reason, &picked, &stage, &tier); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			d.Seed = uint64(seed)
+			d.Reviewers = make([]string, 0)
+			d.Picks = make([]Pick, 0)
+			d.Pool = make([]PoolCandidate, 0)
+			d.Excluded = make([]Exclusion, 0)
+			d.Draws = make([]Draw, 0)
+			byID[id] = len(decisions)
+			decisions = append(decisions, d)
+			lastID = id
+		}
+
+		cur := &decisions[len(decisions)-1]
+		if !userID.Valid {
+			continue
+		}
+		if reason.Valid {
+			cur.Excluded = append(cur.Excluded, Exclusion{UserID: userID.String, Reason: reason.String})
+			continue
+		}
+		cur.Pool = append(cur.Pool, PoolCandidate{UserID: userID.String, Source: source.String, OpenReviews: int(openReviews.Int64)})
+		if picked {
+			cur.Reviewers = append(cur.Reviewers, userID.String)
+			cur.Picks = append(cur.Picks, Pick{UserID: userID.String, Stage: stage, Tier: tier})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := PR.loadDraws(ctx, ID, decisions, byID); err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// loadDraws adds the draws of the decisions of the PR in the order they were made.
+func (PR *PullRequestRepo) loadDraws(ctx context.Context, prID string, decisions []Decision, byID map[int]int) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.
+		Select("dd.decision_id", "dd.stage", "dd.tier", "dd.team_id", "dd.wanted", "dd.candidates", "dd.picked",
+			"COALESCE(dd.cursor_before, '')", "COALESCE(dd.cursor_after, '')").
+		From("assignment_decision_draws dd").
+		Join("assignment_decisions d ON d.id = dd.decision_id").
+		Where(sq.Eq{"d.request_id": prID}).
+		OrderBy("dd.decision_id", "dd.position").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := PR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var d Draw
+		var candidates, picked []byte
+		if err := rows.Scan(&id, &d.Stage, &d.Tier, &d.TeamID, &d.Count, &candidates, &picked, &d.CursorBefore, &d.CursorAfter); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(candidates, &d.Candidates); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(picked, &d.Picked); err != nil {
+			return err
+		}
+		i, ok := byID[id]
+		if !ok {
+			continue
+		}
+		decisions[i].Draws = append(decisions[i].Draws, d)
+	}
+	return rows.Err()
+}
+
+// Replay repeats the draws of a decision with its seed and strategy and returns the
+// reviewers of the decision in the order of their stages: those picked without the
+// selector first, then the picks of every draw. The round robin cursor is taken from the
+// draws, so nothing is read or written. The selector sees the user IDs and open reviews
+// of the candidates only.
+func (PR *PullRequestRepo) Replay(ctx context.Context, d Decision) ([]string, error) {
+	rng := rand.New(rand.NewPCG(d.Seed, d.Seed))
+
+	reviewers := make([]string, 0, len(d.Picks))
+	for _, p := range d.Picks {
+		if p.Tier == "" {
+			reviewers = append(reviewers, p.UserID)
+		}
+	}
+	for _, draw := range d.Draws {
+		candidates := make([]Candidate, len(draw.Candidates))
+		for i, c := range draw.Candidates {
+			candidates[i] = Candidate{User: &user.User{Id: c.UserID}, OpenReviews: c.OpenReviews}
+		}
+		ids, err := PR.selector().Select(ctx, SelectionInput{
+			TeamID:     draw.TeamID,
+			Strategy:   d.Strategy,
+			Candidates: candidates,
+			Count:      draw.Count,
+			Tier:       slices.Index(tierNames, draw.Tier),
+			Rand:       rng,
+			Trace:      &Trace{CursorBefore: draw.CursorBefore},
+			Replay:     true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, ids...)
+	}
+	return reviewers, nil
+}
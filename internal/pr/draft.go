@@ -0,0 +1,144 @@
+package pr
+
+import (
+	"context"
+	dbutils "pullreq/internal/db_utils"
+	"pullreq/internal/errs"
+	"pullreq/internal/user"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// createDraft stores a DRAFT PR without reviewers. They are selected by ReadyForReview.
+func (PR *PullRequestRepo) createDraft(ctx context.Context, req CreatePullRequestRequest) (*PullRequest, error) {
+	// the author has to belong to a team to get reviewers later
+	if _, err := PR.TR.GetTeamByUserID(ctx, req.AuthorID); err != nil {
+		return nil, err
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	tx, err := PR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	createdAt := PR.now()
+	insertPR, args, err := psql.Insert("pr").
+		Columns("id", "pr_name", "author_id", "pr_status", "created_ad").
+		Values(req.ID, req.PullRequestName, req.AuthorID, StatusDraft, createdAt).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, insertPR, args...); err != nil {
+		return nil, err
+	}
+
+	draft := newDraft(req)
+	draft.CreatedAt = &createdAt
+	if err := insertLabels(ctx, tx, req.ID, draft.Labels); err != nil {
+		return nil, err
+	}
+	if err := insertFiles(ctx, tx, req.ID, normalizeFiles(req.Files)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return draft, nil
+}
+
+// newDraft returns the DRAFT PR req describes, without reviewers.
+func newDraft(req CreatePullRequestRequest) *PullRequest {
+	return &PullRequest{
+		ID:                req.ID,
+		PullRequestName:   req.PullRequestName,
+		AuthorID:          req.AuthorID,
+		Status:            StatusDraft,
+		Priority:          PriorityNormal,
+		Version:           1,
+		AssignedReviewers: make([]string, 0),
+		Labels:            user.NormalizeTags(req.Labels),
+	}
+}
+
+// ReadyForReview moves a DRAFT PR to OPEN and assigns its reviewers the way Create
+// would, with the team roster and settings of this moment.
+func (PR *PullRequestRepo) ReadyForReview(ctx context.Context, ID string) (*PullRequest, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	tx, err := PR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, err := lockStatus(ctx, tx, ID)
+	if err != nil {
+		return nil, err
+	}
+	if status != StatusDraft {
+		return nil, transitionError(status, StatusOpen)
+	}
+
+	req := CreatePullRequestRequest{ID: ID}
+	prQuery, args, _ := psql.Select("pr_name", "author_id").From("pr").Where(sq.Eq{"id": ID}).ToSql()
+	if err := tx.QueryRowContext(ctx, prQuery, args...).Scan(&req.PullRequestName, &req.AuthorID); err != nil {
+		return nil, err
+	}
+	if req.Labels, err = getLabels(ctx, tx, ID); err != nil {
+		return nil, err
+	}
+	if req.Files, err = getFiles(ctx, tx, ID); err != nil {
+		return nil, err
+	}
+
+	plan, err := PR.planCreate(ctx, tx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	q, args, err := psql.Update("pr").
+		Set("pr_status", StatusOpen).
+		Set("reviewers_required", plan.settings.ReviewersRequired).
+		Where(sq.Eq{"id": ID}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return nil, err
+	}
+
+	if err := insertAssignment(ctx, tx, ID, DecisionReady, plan); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return plan.pr, nil
+}
+
+// hasAssignment reports whether reviewers were ever assigned to the PR, which is not
+// the case for drafts. errs.NotFountError is returned for unknown PRs.
+func hasAssignment(ctx context.Context, q dbutils.RowQueryer, ID string) (bool, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	var exists, assigned bool
+	query, args, err := psql.Select().
+		Column(sq.Expr("EXISTS (SELECT 1 FROM pr WHERE id = ?)", ID)).
+		Column(sq.Expr("EXISTS (SELECT 1 FROM assignment_decisions WHERE request_id = ?)", ID)).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+	if err := q.QueryRowContext(ctx, query, args...).Scan(&exists, &assigned); err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, errs.NotFountError
+	}
+	return assigned, nil
+}
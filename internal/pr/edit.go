@@ -0,0 +1,235 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"pullreq/internal/errs"
+	"pullreq/internal/user"
+	"slices"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// Priorities of a PR.
+const (
+	PriorityLow      = "low"
+	PriorityNormal   = "normal"
+	PriorityHigh     = "high"
+	PriorityCritical = "critical"
+)
+
+func ValidPriority(priority string) bool {
+	switch priority {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityCritical:
+		return true
+	}
+	return false
+}
+
+// Editable fields of a PR.
+const (
+	FieldName        = "pull_request_name"
+	FieldLabels      = "labels"
+	FieldDescription = "description"
+	FieldPriority    = "priority"
+)
+
+// Edit is one version of the PR metadata, the changes that produced it and who made them.
+type Edit struct {
+	Version  int           `json:"version"`
+	EditedBy string        `json:"edited_by"`
+	EditedAt time.Time     `json:"edited_at"`
+	Changes  []FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	Field    string          `json:"field"`
+	OldValue json.RawMessage `json:"old_value"`
+	NewValue json.RawMessage `json:"new_value"`
+}
+
+// Update changes the metadata of a DRAFT or OPEN PR and records the changes as the next
+// version. Drafts are edited too, so that a title can be fixed before the review starts.
+// Fields that are left out or keep their value are not recorded, an update that
+// changes nothing keeps the version. An editor that is not a user gives
+// errs.NotFountError.
+func (PR *PullRequestRepo) Update(ctx context.Context, req UpdateRequest) (*PullRequest, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := PR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status, name, description, priority string
+	var version int
+	lockQuery, args, _ := psql.Select("pr_status", "pr_name", "description", "priority", "version").
+		From("pr").
+		Where(sq.Eq{"id": req.PullRequestID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err := tx.QueryRowContext(ctx, lockQuery, args...).Scan(&status, &name, &description, &priority, &version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+	if !slices.Contains(editStates, status) {
+		return nil, errs.PRNotOpenError
+	}
+	if req.Version != 0 && req.Version != version {
+		return nil, errs.VersionConflictError
+	}
+
+	changes := make([]FieldChange, 0, 4)
+	builder := psql.Update("pr").Where(sq.Eq{"id": req.PullRequestID})
+	if req.PullRequestName != nil && *req.PullRequestName != name {
+		changes = append(changes, change(FieldName, name, *req.PullRequestName))
+		builder = builder.Set("pr_name", *req.PullRequestName)
+	}
+	if req.Description != nil && *req.Description != description {
+		changes = append(changes, change(FieldDescription, description, *req.Description))
+		builder = builder.Set("description", *req.Description)
+	}
+	if req.Priority != nil && *req.Priority != priority {
+		changes = append(changes, change(FieldPriority, priority, *req.Priority))
+		builder = builder.Set("priority", *req.Priority)
+	}
+	if req.Labels != nil {
+		labels := user.NormalizeTags(*req.Labels)
+		current, err := getLabels(ctx, tx, req.PullRequestID)
+		if err != nil {
+			return nil, err
+		}
+		if !sameStrings(current, labels) {
+			changes = append(changes, change(FieldLabels, current, labels))
+			if err := replaceLabels(ctx, tx, req.PullRequestID, labels); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(changes) > 0 {
+		if err := insertEdits(ctx, tx, builder, req, version+1, changes, PR.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return PR.GetPr(ctx, req.PullRequestID)
+}
+
+// insertEdits stores the changes as version of the PR, builder holds the updated columns.
+func insertEdits(ctx context.Context, tx *sql.Tx, builder sq.UpdateBuilder, req UpdateRequest, version int, changes []FieldChange, now time.Time) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := builder.Set("version", version).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return err
+	}
+
+	editBuilder := psql.Insert("pr_edits").
+		Columns("request_id", "version", "edited_by", "field", "old_value", "new_value", "edited_at")
+	for _, c := range changes {
+		editBuilder = editBuilder.Values(req.PullRequestID, version, req.EditedBy, c.Field, string(c.OldValue), string(c.NewValue), now)
+	}
+	q, args, err = editBuilder.ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" {
+			return errs.NotFountError
+		}
+		return err
+	}
+	return nil
+}
+
+// History returns the edits of the PR, oldest first.
+func (PR *PullRequestRepo) History(ctx context.Context, ID string) ([]Edit, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Select("version", "edited_by", "edited_at", "field", "old_value", "new_value").
+		From("pr_edits").
+		Where(sq.Eq{"request_id": ID}).
+		OrderBy("version", "id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := PR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := make([]Edit, 0)
+	for rows.Next() {
+		var e Edit
+		var c FieldChange
+		var oldValue, newValue []byte
+		if err := rows.Scan(&e.Version, &e.EditedBy, &e.EditedAt, &c.Field, &oldValue, &newValue); err != nil {
+			return nil, err
+		}
+		c.OldValue, c.NewValue = oldValue, newValue
+		if n := len(edits); n > 0 && edits[n-1].Version == e.Version {
+			edits[n-1].Changes = append(edits[n-1].Changes, c)
+			continue
+		}
+		e.Changes = []FieldChange{c}
+		edits = append(edits, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(edits) == 0 {
+		var exists bool
+		existsQuery, args, _ := psql.Select().Column(sq.Expr("EXISTS (SELECT 1 FROM pr WHERE id = ?)", ID)).ToSql()
+		if err := PR.DB.QueryRowContext(ctx, existsQuery, args...).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errs.NotFountError
+		}
+	}
+	return edits, nil
+}
+
+func change(field string, oldValue, newValue interface{}) FieldChange {
+	oldJSON, _ := json.Marshal(oldValue)
+	newJSON, _ := json.Marshal(newValue)
+	return FieldChange{Field: field, OldValue: oldJSON, NewValue: newJSON}
+}
+
+// replaceLabels sets the labels of the PR to labels.
+func replaceLabels(ctx context.Context, tx *sql.Tx, prID string, labels []string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	deleteQuery, args, _ := psql.Delete("pr_labels").Where(sq.Eq{"request_id": prID}).ToSql()
+	if _, err := tx.ExecContext(ctx, deleteQuery, args...); err != nil {
+		return err
+	}
+	return insertLabels(ctx, tx, prID, labels)
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
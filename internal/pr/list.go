@@ -0,0 +1,235 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	dbutils "pullreq/internal/db_utils"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Page sizes of List.
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// Sort keys and orders of List.
+const (
+	SortCreatedAt = "created_at"
+	SortMergedAt  = "merged_at" // only merged PRs have the key, others are left out
+	OrderAsc      = "asc"
+	OrderDesc     = "desc"
+)
+
+// ListFilter selects the PRs List returns. Empty fields do not filter.
+type ListFilter struct {
+	Statuses    []string
+	AuthorID    string
+	TeamName    string // team of the author
+	ReviewerID  string // currently assigned reviewer
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MergedFrom  *time.Time
+	MergedTo    *time.Time
+
+	SortBy string
+	Order  string
+	Limit  int
+	After  *Cursor // continue after the last PR of the previous page
+}
+
+// ListPage is one page of List. NextCursor is empty on the last page.
+type ListPage struct {
+	PullRequests []PullRequest
+	NextCursor   string
+}
+
+// Cursor is the sort key of the last PR on a page.
+type Cursor struct {
+	At time.Time `json:"at"`
+	ID string    `json:"id"`
+}
+
+// Encode returns the opaque form of c used in the API.
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a cursor returned in ListPage.NextCursor.
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// sortColumn returns the column behind the sort key. PRs created before created_ad was
+// filled sort as created at the epoch.
+func sortColumn(sortBy string) string {
+	if sortBy == SortMergedAt {
+		return "pr.mergerd_at"
+	}
+	return "COALESCE(pr.created_ad, TIMESTAMP 'epoch')"
+}
+
+// List returns the PRs matching filter, ordered by the sort key and the PR id, one page
+// at a time. Pages are cut by keyset, so PRs created meanwhile do not shift them.
+func (PR *PullRequestRepo) List(ctx context.Context, filter ListFilter) (*ListPage, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	key := sortColumn(filter.SortBy)
+	direction, cmp := "DESC", "<"
+	if filter.Order == OrderAsc {
+		direction, cmp = "ASC", ">"
+	}
+
+	builder := psql.Select(
+		"pr.id", "pr.pr_name", "pr.author_id", "pr.pr_status", "COALESCE(pr.reviewers_required, 0)",
+		"pr.description", "pr.priority", "pr.version", "pr.created_ad", "pr.mergerd_at", "pr.closed_at", key,
+	).From("pr")
+
+	if len(filter.Statuses) > 0 {
+		builder = builder.Where(sq.Eq{"pr.pr_status": filter.Statuses})
+	}
+	if filter.AuthorID != "" {
+		builder = builder.Where(sq.Eq{"pr.author_id": filter.AuthorID})
+	}
+	if filter.TeamName != "" {
+		builder = builder.
+			Join("users a ON a.id = pr.author_id").
+			Join("teams t ON t.id = a.team_id").
+			Where(sq.Eq{"t.team_name": filter.TeamName})
+	}
+	if filter.ReviewerID != "" {
+		builder = builder.Where("EXISTS (SELECT 1 FROM userspr ur WHERE ur.request_id = pr.id AND ur.user_id = ?)", filter.ReviewerID)
+	}
+	if filter.CreatedFrom != nil {
+		builder = builder.Where(sq.GtOrEq{"pr.created_ad": *filter.CreatedFrom})
+	}
+	if filter.CreatedTo != nil {
+		builder = builder.Where(sq.Lt{"pr.created_ad": *filter.CreatedTo})
+	}
+	if filter.MergedFrom != nil {
+		builder = builder.Where(sq.GtOrEq{"pr.mergerd_at": *filter.MergedFrom})
+	}
+	if filter.MergedTo != nil {
+		builder = builder.Where(sq.Lt{"pr.mergerd_at": *filter.MergedTo})
+	}
+	if filter.SortBy == SortMergedAt {
+		builder = builder.Where("pr.mergerd_at IS NOT NULL")
+	}
+	if filter.After != nil {
+		builder = builder.Where("("+key+", pr.id) "+cmp+" (?, ?)", filter.After.At, filter.After.ID)
+	}
+
+	q, args, err := builder.
+		OrderBy(key+" "+direction, "pr.id "+direction).
+		Limit(uint64(filter.Limit + 1)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := PR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &ListPage{PullRequests: make([]PullRequest, 0, filter.Limit)}
+	var last Cursor
+	for rows.Next() {
+		if len(page.PullRequests) == filter.Limit {
+			page.NextCursor = last.Encode()
+			break
+		}
+		var pr PullRequest
+		var createdAt, mergedAt, closedAt sql.NullTime
+		if err := rows.Scan(&pr.ID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.ReviewersRequired,
+			&pr.Description, &pr.Priority, &pr.Version, &createdAt, &mergedAt, &closedAt, &last.At); err != nil {
+			return nil, err
+		}
+		pr.CreatedAt, pr.MergedAt, pr.ClosedAt = timePtr(createdAt), timePtr(mergedAt), timePtr(closedAt)
+		last.ID = pr.ID
+		page.PullRequests = append(page.PullRequests, pr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadReviewers(ctx, PR.DB, page.PullRequests); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// loadReviewers fills the reviewers of prs with a single query.
+func loadReviewers(ctx context.Context, q dbutils.Queryer, prs []PullRequest) error {
+	if len(prs) == 0 {
+		return nil
+	}
+	byID := make(map[string]*PullRequest, len(prs))
+	ids := make([]string, len(prs))
+	for i := range prs {
+		prs[i].AssignedReviewers = make([]string, 0)
+		byID[prs[i].ID] = &prs[i]
+		ids[i] = prs[i].ID
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query, args, err := psql.Select("request_id", "user_id", "fallback").
+		From("userspr").
+		Where(sq.Eq{"request_id": ids}).
+		OrderBy("request_id", "user_id").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var prID, userID string
+		var fallback bool
+		if err := rows.Scan(&prID, &userID, &fallback); err != nil {
+			return err
+		}
+		pr := byID[prID]
+		pr.AssignedReviewers = append(pr.AssignedReviewers, userID)
+		if fallback {
+			pr.FallbackReviewers = append(pr.FallbackReviewers, userID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, pr := range byID {
+		pr.Understaffed = len(pr.AssignedReviewers) < pr.ReviewersRequired
+	}
+	return nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
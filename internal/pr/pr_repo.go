@@ -3,10 +3,13 @@ package pr
 import (
 	"context"
 	"database/sql"
-	"math/rand/v2"
+	"pullreq/internal/affinity"
 	"pullreq/internal/errs"
+	"pullreq/internal/ownership"
+	"pullreq/internal/rotation"
 	"pullreq/internal/team"
 	"pullreq/internal/user"
+	"slices"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -19,9 +22,21 @@ type PullReqestInput struct {
 }
 
 type PullRequestRepo struct {
-	DB *sql.DB
-	TR team.TeamRepoInterface
-	UR user.UserRepoInterface
+	DB       *sql.DB
+	TR       team.TeamRepoInterface
+	UR       user.UserRepoInterface
+	Selector ReviewerSelector                 // nil means the built-in per-team strategies
+	OR       ownership.OwnershipRepoInterface // nil means no code ownership rules
+	AR       affinity.AffinityRepoInterface   // nil means no reviewer affinity rules
+	RR       rotation.RotationRepoInterface   // nil means no duty rotations
+	Clock    func() time.Time                 // nil means time.Now, replaced in tests
+}
+
+func (PR *PullRequestRepo) now() time.Time {
+	if PR.Clock == nil {
+		return time.Now()
+	}
+	return PR.Clock()
 }
 
 type PullRequestShort struct {
@@ -32,89 +47,95 @@ type PullRequestShort struct {
 }
 
 type PullRequestRepoInterface interface {
-	AssignedReviewer(ctx context.Context, PrID, UserID string) (*PullRequest, string, error)
+	AssignedReviewer(ctx context.Context, req ReassignRequest) (*PullRequest, string, error)
 	Check(ctx context.Context, ID string) error
 	GetPr(ctx context.Context, ID string) (*PullRequest, error)
-	Merged(ctx context.Context, ID string) (*PullRequest, error)
+	List(ctx context.Context, filter ListFilter) (*ListPage, error)
+	Merged(ctx context.Context, req MergeRequest) (*PullRequest, error)
+	Close(ctx context.Context, ID string) (*PullRequest, error)
+	Reopen(ctx context.Context, ID string) (*PullRequest, error)
+	ReadyForReview(ctx context.Context, ID string) (*PullRequest, error)
 	Create(ctx context.Context, req CreatePullRequestRequest) (*PullRequest, error)
+	Preview(ctx context.Context, req CreatePullRequestRequest) (*Preview, error)
+	Update(ctx context.Context, req UpdateRequest) (*PullRequest, error)
+	History(ctx context.Context, ID string) ([]Edit, error)
+	Explain(ctx context.Context, ID string) ([]Decision, error)
+	StartReview(ctx context.Context, prID, userID string) (*Review, error)
+	SubmitReview(ctx context.Context, req SubmitReviewRequest) (*PullRequest, error)
+	Rebalance(ctx context.Context, teamName string, dryRun bool) ([]Move, error)
 }
 
-func (PR *PullRequestRepo) AssignedReviewer(ctx context.Context, prID, userID string) (*PullRequest, string, error) {
-	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-
+// AssignedReviewer replaces req.CurrentReviewerID on the PR. The replacement comes from
+// the team of the author and is neither the author nor already assigned; an explicitly
+// requested replacement has to be one the policy would pick from. Every replacement is
+// recorded in reassignment_history.
+func (PR *PullRequestRepo) AssignedReviewer(ctx context.Context, req ReassignRequest) (*PullRequest, string, error) {
 	tx, err := PR.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, "", err
 	}
 	defer tx.Rollback()
 
-	var status string
-	lockQuery, args, _ := psql.Select("pr_status").
-		From("pr").
-		Where(sq.Eq{"id": prID}).
-		Suffix("FOR UPDATE").
-		ToSql()
-
-	err = tx.QueryRowContext(ctx, lockQuery, args...).Scan(&status)
+	newReviewer, err := PR.reassign(ctx, tx, req, req.NewReviewerID != "")
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, "", errs.NotFountError
-		}
 		return nil, "", err
 	}
 
-	if status == "MERGED" {
-		return nil, "", errs.PRMergedError
-	}
-
-	teamID, err := PR.TR.GetTeamByUserID(ctx, userID)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return nil, "", err
 	}
 
-	newReviewerQuery, args, _ := psql.
-		Select("id").
-		From("users").
-		Where(sq.Eq{"team_id": teamID, "is_active": true}).
-		Where(sq.NotEq{"id": userID}).
-		Limit(1).
+	pr, err := PR.GetPr(ctx, req.PullRequestID)
+	return pr, newReviewer, err
+}
+
+// reassign replaces a reviewer as AssignedReviewer does, within tx. requested tells
+// whether a person asked for req.NewReviewerID, as opposed to the service moving the
+// review on its own.
+func (PR *PullRequestRepo) reassign(ctx context.Context, tx *sql.Tx, req ReassignRequest, requested bool) (string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	prID, userID := req.PullRequestID, req.CurrentReviewerID
+
+	var status, authorID string
+	lockQuery, args, _ := psql.Select("pr_status", "author_id").
+		From("pr").
+		Where(sq.Eq{"id": prID}).
+		Suffix("FOR UPDATE").
 		ToSql()
 
-	var newReviewer string
-	err = tx.QueryRowContext(ctx, newReviewerQuery, args...).Scan(&newReviewer)
+	err := tx.QueryRowContext(ctx, lockQuery, args...).Scan(&status, &authorID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, "", errs.NoCandidateError
+			return "", errs.NotFountError
 		}
-		return nil, "", err
+		return "", err
 	}
-	updateQuery, args, _ := psql.Update("userspr").
-		Set("user_id", newReviewer).
-		Where(sq.Eq{"user_id": userID, "request_id": prID}).
-		ToSql()
 
-	_, err = tx.ExecContext(ctx, updateQuery, args...)
-	if err != nil {
-		return nil, "", err
+	if err := RequireStatus(status, "reassignment", reviewStates); err != nil {
+		return "", err
 	}
 
-	historyQuery, args, _ := psql.Insert("usershistory").
-		Columns("user_id", "pr_count").
-		Values(newReviewer, 1).
-		Suffix("ON CONFLICT (user_id) DO UPDATE SET pr_count = usershistory.pr_count + 1").
-		ToSql()
-
-	_, err = tx.ExecContext(ctx, historyQuery, args...)
+	reviewers, err := getReviewers(ctx, tx, prID)
 	if err != nil {
-		return nil, "", err
+		return "", err
+	}
+	if !slices.Contains(reviewers, userID) {
+		return "", errs.NotAssignedError
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, "", err
+	picked, settings, err := PR.pickReplacement(ctx, tx, replacement{
+		prID:      prID,
+		authorID:  authorID,
+		status:    status,
+		reviewers: reviewers,
+		oldID:     userID,
+		requested: req.NewReviewerID,
+	})
+	if err != nil {
+		return "", err
 	}
 
-	pr, err := PR.GetPr(ctx, prID)
-	return pr, newReviewer, err
+	return PR.applyReplacement(ctx, tx, prID, userID, picked, settings.ReviewerStrategy, req.Reason, requested)
 }
 
 func (PR *PullRequestRepo) Check(ctx context.Context, ID string) error {
@@ -141,89 +162,161 @@ func (PR *PullRequestRepo) GetPr(ctx context.Context, ID string) (*PullRequest,
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
 	q, args, err := psql.
-		Select("pr.id, pr.pr_name, pr.author_id, pr.pr_status, ur.user_id").
+		Select("pr.id, pr.pr_name, pr.author_id, pr.pr_status, COALESCE(pr.reviewers_required, 0), pr.description, pr.priority, pr.version, pr.created_ad, pr.mergerd_at, pr.closed_at, ur.user_id, COALESCE(ur.fallback, false)").
 		From("pr").
-		Join("userspr ur on ur.request_id = pr.id").
+		LeftJoin("userspr ur on ur.request_id = pr.id").
 		Where(sq.Eq{"ID": ID}).
+		OrderBy("ur.user_id").
 		ToSql()
 
 	rows, err := PR.DB.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	res := &PullRequest{}
+	res := &PullRequest{AssignedReviewers: make([]string, 0)}
 	var exist bool
 	for rows.Next() {
 		exist = true
-		var userID string
-		rows.Scan(&res.ID, &res.PullRequestName, &res.AuthorID, &res.Status, &userID)
-		res.AssignedReviewers = append(res.AssignedReviewers, userID)
+		// drafts and PRs of teams without active members have no reviewers
+		var userID sql.NullString
+		var fallback bool
+		var createdAt, mergedAt, closedAt sql.NullTime
+		if err := rows.Scan(&res.ID, &res.PullRequestName, &res.AuthorID, &res.Status, &res.ReviewersRequired, &res.Description, &res.Priority, &res.Version, &createdAt, &mergedAt, &closedAt, &userID, &fallback); err != nil {
+			return nil, err
+		}
+		res.CreatedAt, res.MergedAt, res.ClosedAt = timePtr(createdAt), timePtr(mergedAt), timePtr(closedAt)
+		if !userID.Valid {
+			continue
+		}
+		res.AssignedReviewers = append(res.AssignedReviewers, userID.String)
+		if fallback {
+			res.FallbackReviewers = append(res.FallbackReviewers, userID.String)
+		}
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	if !exist {
 		return nil, errs.NotFountError
 	}
+	res.Understaffed = len(res.AssignedReviewers) < res.ReviewersRequired
+
+	res.Labels, err = getLabels(ctx, PR.DB, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Verdicts, err = getVerdicts(ctx, PR.DB, ID)
+	if err != nil {
+		return nil, err
+	}
 
 	return res, nil
 }
 
-func (PR *PullRequestRepo) Merged(ctx context.Context, ID string) (*PullRequest, error) {
-	pr, err := PR.GetPr(ctx, ID)
+// Merged merges the PR once the verdicts of its current reviewers and its comment threads
+// meet the merge quorum of the author's team, a *MergeBlockedError lists the unmet
+// conditions otherwise.
+// A forced merge skips the check and is recorded in forced_merges, only an admin may
+// force one, errs.NotAdminError is returned otherwise.
+func (PR *PullRequestRepo) Merged(ctx context.Context, req MergeRequest) (*PullRequest, error) {
+	if req.Force {
+		if err := user.CheckAdmin(ctx, PR.DB, req.ForcedBy); err != nil {
+			return nil, err
+		}
+	}
+
+	pr, err := PR.GetPr(ctx, req.PullRequestID)
 	if err != nil {
 		return nil, err
 	}
 
-	if pr.Status == "MERGED" {
+	if pr.Status == StatusMerged {
 		return pr, nil
 	}
 
-	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-	q, args, err := psql.Update("pr").
-		Set("pr_status", "MERGED").
-		Set("mergerd_at", time.Now()).
-		Where(sq.Eq{"ID": ID}).
-		ToSql()
-
-	_, err = PR.DB.ExecContext(ctx, q, args...)
+	teamID, err := PR.TR.GetTeamByUserID(ctx, pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := PR.TR.GetTeamSettings(ctx, teamID)
 	if err != nil {
 		return nil, err
 	}
 
-	pr.Status = "MERGED"
-	return pr, nil
-}
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	tx, err := PR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-func (PR *PullRequestRepo) Create(ctx context.Context, req CreatePullRequestRequest) (*PullRequest, error) {
-	if err := PR.Check(ctx, req.ID); err != nil {
+	// verdicts cannot be submitted while the PR is locked
+	status, err := lockStatus(ctx, tx, req.PullRequestID)
+	if err != nil {
 		return nil, err
 	}
+	if status == StatusMerged {
+		pr.Status = status
+		return pr, nil
+	}
+	if !CanTransition(status, StatusMerged) {
+		return nil, transitionError(status, StatusMerged)
+	}
 
-	teamID, err := PR.TR.GetTeamByUserID(ctx, req.AuthorID)
+	pr.Verdicts, err = getVerdicts(ctx, tx, req.PullRequestID)
 	if err != nil {
 		return nil, err
 	}
+	unresolved := 0
+	if settings.RequireResolvedThreads {
+		if unresolved, err = unresolvedThreads(ctx, tx, req.PullRequestID); err != nil {
+			return nil, err
+		}
+	}
+	unmet := checkQuorum(settings, pr.Verdicts, unresolved)
+	if len(unmet) > 0 && !req.Force {
+		return nil, &MergeBlockedError{Unmet: unmet}
+	}
 
-	users, err := PR.TR.GetTeamMember(ctx, teamID)
+	now := PR.now()
+	q, args, err := psql.Update("pr").
+		Set("pr_status", StatusMerged).
+		Set("mergerd_at", now).
+		Where(sq.Eq{"ID": req.PullRequestID}).
+		ToSql()
 	if err != nil {
 		return nil, err
 	}
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return nil, err
+	}
 
-	activeUsers := make([]*user.User, 0)
-	for _, u := range users {
-		if u.IsActive {
-			activeUsers = append(activeUsers, u)
+	if req.Force {
+		if err := insertForcedMerge(ctx, tx, req, unmet, now); err != nil {
+			return nil, err
 		}
 	}
 
-	reviews := selectReviewers(activeUsers)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	pr.Status = StatusMerged
+	pr.MergedAt = &now
+	return pr, nil
+}
+
+func (PR *PullRequestRepo) Create(ctx context.Context, req CreatePullRequestRequest) (*PullRequest, error) {
+	if err := PR.Check(ctx, req.ID); err != nil {
+		return nil, err
+	}
 
-	pr := &PullRequest{
-		ID:                req.ID,
-		PullRequestName:   req.PullRequestName,
-		AuthorID:          req.AuthorID,
-		Status:            "OPEN",
-		AssignedReviewers: reviews,
+	if req.Draft {
+		return PR.createDraft(ctx, req)
 	}
 
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
@@ -234,9 +327,17 @@ func (PR *PullRequestRepo) Create(ctx context.Context, req CreatePullRequestRequ
 	}
 	defer tx.Rollback()
 
+	plan, err := PR.planCreate(ctx, tx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	pr, settings := plan.pr, plan.settings
+
+	createdAt := PR.now()
+	pr.CreatedAt = &createdAt
 	insertPR, args, err := psql.Insert("pr").
-		Columns("id", "pr_name", "author_id", "pr_status", "created_ad").
-		Values(req.ID, req.PullRequestName, req.AuthorID, "OPEN", time.Now()).
+		Columns("id", "pr_name", "author_id", "pr_status", "created_ad", "reviewers_required").
+		Values(req.ID, req.PullRequestName, req.AuthorID, StatusOpen, createdAt, settings.ReviewersRequired).
 		ToSql()
 	if err != nil {
 		return nil, err
@@ -245,17 +346,46 @@ func (PR *PullRequestRepo) Create(ctx context.Context, req CreatePullRequestRequ
 		return nil, err
 	}
 
+	if err := insertLabels(ctx, tx, req.ID, pr.Labels); err != nil {
+		return nil, err
+	}
+
+	if err := insertFiles(ctx, tx, req.ID, plan.files); err != nil {
+		return nil, err
+	}
+
+	if err := insertAssignment(ctx, tx, req.ID, DecisionCreate, plan); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// insertAssignment stores the reviewers picked by plan for the PR together with the
+// decision and counts the reviews in usershistory.
+func insertAssignment(ctx context.Context, tx *sql.Tx, prID, kind string, plan *createPlan) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	picked, reviews := plan.picked, plan.pr.AssignedReviewers
+
+	if err := insertDecision(ctx, tx, prID, kind, plan.settings.ReviewerStrategy, picked); err != nil {
+		return err
+	}
+
 	if len(reviews) > 0 {
-		reviewBuilder := psql.Insert("userspr").Columns("user_id", "request_id")
+		reviewBuilder := psql.Insert("userspr").Columns("user_id", "request_id", "fallback")
 		for _, reviewerID := range reviews {
-			reviewBuilder = reviewBuilder.Values(reviewerID, req.ID)
+			reviewBuilder = reviewBuilder.Values(reviewerID, prID, slices.Contains(picked.fallback, reviewerID))
 		}
 		q, args, err := reviewBuilder.ToSql()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if _, err := tx.ExecContext(ctx, q, args...); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
@@ -270,10 +400,10 @@ func (PR *PullRequestRepo) Create(ctx context.Context, req CreatePullRequestRequ
 			Where(sq.Eq{"user_id": userIDs}).
 			ToSql()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if _, err := tx.ExecContext(ctx, updateSQL, args...); err != nil {
-			return nil, err
+			return err
 		}
 
 		insertBuilder := psql.Insert("usershistory").
@@ -285,34 +415,19 @@ func (PR *PullRequestRepo) Create(ctx context.Context, req CreatePullRequestRequ
 
 		insertSQL, args, err := insertBuilder.ToSql()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if _, err := tx.ExecContext(ctx, insertSQL, args...); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
-	}
-
-	return pr, nil
+	return nil
 }
 
-func selectReviewers(users []*user.User) []string {
-	if len(users) == 0 {
-		return []string{}
-	}
-
-	v1 := rand.IntN(len(users))
-
-	v2 := rand.IntN(len(users))
-	if len(users) == 1 {
-		return []string{users[v1].Id}
+func (PR *PullRequestRepo) selector() ReviewerSelector {
+	if PR.Selector == nil {
+		return defaultSelector
 	}
-	if v1 == v2 {
-		v2 = (v1 + 1) % len(users)
-	}
-
-	return []string{users[v1].Id, users[v2].Id}
+	return PR.Selector
 }
@@ -1,27 +1,73 @@
 package pr
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"pullreq/internal/errs"
 	jsonutils "pullreq/internal/json_utils"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // PullRequest represents a pull request object
 type PullRequest struct {
-	ID                string   `json:"pull_request_id"`
-	PullRequestName   string   `json:"pull_request_name"`
-	AuthorID          string   `json:"author_id"`
-	Status            string   `json:"status"`
-	AssignedReviewers []string `json:"assigned_reviewers"`
+	ID                string    `json:"pull_request_id"`
+	PullRequestName   string    `json:"pull_request_name"`
+	AuthorID          string    `json:"author_id"`
+	Status            string    `json:"status"`
+	AssignedReviewers []string  `json:"assigned_reviewers"`
+	Verdicts          []Verdict `json:"verdicts,omitempty"`           // latest verdict of every reviewer who submitted one
+	FallbackReviewers []string  `json:"fallback_reviewers,omitempty"` // assigned reviewers borrowed from a fallback team
+	ReviewersRequired int       `json:"reviewers_required,omitempty"`
+	Understaffed      bool      `json:"understaffed,omitempty"` // fewer candidates were available than required
+	Labels            []string  `json:"labels,omitempty"`
+	PolicyViolations  []string  `json:"policy_violations,omitempty"` // team policies the assignment could not satisfy
+
+	Description string `json:"description,omitempty"`
+	Priority    string `json:"priority,omitempty"`
+	Version     int    `json:"version,omitempty"` // bumped by every update of the metadata
+
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	MergedAt  *time.Time `json:"merged_at,omitempty"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
 }
 
+// Team policies an assignment may fail to satisfy.
+const (
+	PolicyNoSenior = "NO_SENIOR_REVIEWER"
+)
+
 // CreatePullRequestRequest represents the request payload
 type CreatePullRequestRequest struct {
-	ID              string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	ID              string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	Labels          []string `json:"labels,omitempty"` // reviewers whose tags match are preferred
+	Files           []string `json:"files,omitempty"`  // changed paths, checked against code ownership rules
+	Draft           bool     `json:"draft,omitempty"`  // reviewers are assigned once the PR is ready for review
+}
+
+// UpdateRequest changes the metadata of a PR. Fields left out keep their value.
+type UpdateRequest struct {
+	PullRequestID   string    `json:"pull_request_id"`
+	EditedBy        string    `json:"edited_by"`
+	Version         int       `json:"version,omitempty"` // the version the edit is based on, checked when set
+	PullRequestName *string   `json:"pull_request_name,omitempty"`
+	Labels          *[]string `json:"labels,omitempty"`
+	Description     *string   `json:"description,omitempty"`
+	Priority        *string   `json:"priority,omitempty"`
+}
+
+type SetLabelsRequest struct {
+	PullRequestID string   `json:"pull_request_id"`
+	EditedBy      string   `json:"edited_by"`
+	Version       int      `json:"version,omitempty"` // the version the edit is based on, checked when set
+	Labels        []string `json:"labels"`
 }
 
 type PrRouter struct {
@@ -30,11 +76,38 @@ type PrRouter struct {
 
 type MergeRequest struct {
 	PullRequestID string `json:"pull_request_id"`
+	Force         bool   `json:"force,omitempty"`     // merge even when the quorum is not met
+	ForcedBy      string `json:"forced_by,omitempty"` // who forced the merge, required with force
+	Reason        string `json:"reason,omitempty"`
+}
+
+// StatusRequest names the PR to close, reopen or mark ready for review.
+type StatusRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+}
+
+type StartReviewRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+type SubmitReviewRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+	Verdict       string `json:"verdict"`
+	Message       string `json:"message,omitempty"`
+}
+
+type RebalanceRequest struct {
+	TeamName string `json:"team_name,omitempty"` // empty rebalances every team
+	DryRun   bool   `json:"dry_run"`
 }
 
 type ReassignRequest struct {
 	PullRequestID     string `json:"pull_request_id"`
-	CurrentReviewerID string `json:"old_user_id"` // ID ревьювера для замены
+	CurrentReviewerID string `json:"old_user_id"`           // ID ревьювера для замены
+	NewReviewerID     string `json:"new_user_id,omitempty"` // explicitly requested replacement
+	Reason            string `json:"reason,omitempty"`
 }
 
 func (pr *PrRouter) AssignedReviewer(w http.ResponseWriter, r *http.Request) {
@@ -46,7 +119,7 @@ func (pr *PrRouter) AssignedReviewer(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	updatedPR, replacedByID, err := pr.PR.AssignedReviewer(r.Context(), reqBody.PullRequestID, reqBody.CurrentReviewerID)
+	updatedPR, replacedByID, err := pr.PR.AssignedReviewer(r.Context(), reqBody)
 	if err != nil {
 		if errors.Is(err, errs.NotFountError) {
 			errs.JsonCodeResp(w, errs.CodeNotFound, "PR or user not found", http.StatusNotFound)
@@ -56,6 +129,10 @@ func (pr *PrRouter) AssignedReviewer(w http.ResponseWriter, r *http.Request) {
 			errs.JsonCodeResp(w, "PR_MERGED", "cannot reassign on merged PR", http.StatusConflict)
 			return
 		}
+		if errors.Is(err, errs.InvalidTransitionError) {
+			errs.JsonCodeResp(w, errs.CodeInvalidTransition, err.Error(), http.StatusConflict)
+			return
+		}
 		if errors.Is(err, errs.NotAssignedError) {
 			errs.JsonCodeResp(w, "NOT_ASSIGNED", "reviewer is not assigned to this PR", http.StatusConflict)
 			return
@@ -64,6 +141,22 @@ func (pr *PrRouter) AssignedReviewer(w http.ResponseWriter, r *http.Request) {
 			errs.JsonCodeResp(w, "NO_CANDIDATE", "no active replacement candidate in team", http.StatusConflict)
 			return
 		}
+		if errors.Is(err, errs.NoOwnerError) {
+			errs.JsonCodeResp(w, errs.CodeNoOwner, "no code owner can replace the last owner among reviewers", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.InvalidCandidateError) {
+			errs.JsonCodeResp(w, errs.CodeInvalidCandidate, "requested reviewer is not an eligible replacement", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.AffinityViolationError) {
+			errs.JsonCodeResp(w, errs.CodeAffinityViolation, "reviewer is required by a rule of the author", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.SeniorRequiredError) {
+			errs.JsonCodeResp(w, errs.CodeSeniorRequired, "no senior reviewer can replace the last senior", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -76,19 +169,37 @@ func (pr *PrRouter) AssignedReviewer(w http.ResponseWriter, r *http.Request) {
 }
 
 func (pr *PrRouter) Merge(w http.ResponseWriter, r *http.Request) {
-	var id MergeRequest
-	if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
-	res, err := pr.PR.Merged(r.Context(), id.PullRequestID)
+	if req.Force && req.ForcedBy == "" {
+		http.Error(w, "forced_by is required to force a merge", http.StatusBadRequest)
+		return
+	}
+
+	res, err := pr.PR.Merged(r.Context(), req)
 	if err != nil {
 		if errors.Is(err, errs.NotFountError) {
 			errs.JsonCodeResp(w, errs.CodeNotFound, "PR not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, errs.NotAdminError) {
+			errs.JsonCodeResp(w, errs.CodeNotAdmin, "only an admin can force a merge", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, errs.InvalidTransitionError) {
+			errs.JsonCodeResp(w, errs.CodeInvalidTransition, err.Error(), http.StatusConflict)
+			return
+		}
+		var blocked *MergeBlockedError
+		if errors.As(err, &blocked) {
+			errs.JsonCodeDetailsResp(w, errs.CodeMergeBlocked, "merge quorum is not met", blocked.Unmet, http.StatusConflict)
+			return
+		}
 		http.Error(w, "Internal", http.StatusInternalServerError)
 		return
 	}
@@ -105,22 +216,403 @@ func (pr *PrRouter) CreatePullRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res, err := pr.PR.Create(r.Context(), req)
+	if err != nil {
+		createErrorResp(w, err)
+		return
+	}
+	resp := map[string]interface{}{"pr": res}
+	jsonutils.JsonResponse(w, resp, http.StatusCreated)
+}
+
+// PreviewPullRequest answers with the reviewers /pullRequest/create would assign for
+// the same body, without creating anything.
+func (pr *PrRouter) PreviewPullRequest(w http.ResponseWriter, r *http.Request) {
+	var req CreatePullRequestRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	res, err := pr.PR.Preview(r.Context(), req)
+	if err != nil {
+		createErrorResp(w, err)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"preview": res}, http.StatusOK)
+}
+
+// createErrorResp maps the errors of creating a PR, shared with the preview.
+func createErrorResp(w http.ResponseWriter, err error) {
+	if errors.Is(err, errs.NotFountError) {
+		errs.JsonCodeResp(w, errs.CodeNotFound, "Author/Team not exist", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errs.ExistError) {
+		errs.JsonCodeResp(w, errs.CodePRExists, "Pre already exist", 409)
+		return
+	}
+	if errors.Is(err, errs.NoOwnerError) {
+		errs.JsonCodeResp(w, errs.CodeNoOwner, "no owner of the changed files can be assigned", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errs.AffinityViolationError) {
+		errs.JsonCodeResp(w, errs.CodeAffinityViolation, "a reviewer the author always requires cannot be assigned", http.StatusConflict)
+		return
+	}
+	http.Error(w, "Internal", 500)
+}
+
+// SetLabels replaces the labels of a PR. It is an Update of the labels only, so the
+// change is versioned and kept in the edit history.
+func (pr *PrRouter) SetLabels(w http.ResponseWriter, r *http.Request) {
+	var req SetLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.PullRequestID == "" || req.EditedBy == "" {
+		http.Error(w, "pull_request_id and edited_by are required", http.StatusBadRequest)
+		return
+	}
+	labels := req.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	pr.update(r.Context(), w, UpdateRequest{PullRequestID: req.PullRequestID, EditedBy: req.EditedBy, Version: req.Version, Labels: &labels})
+}
+
+func (pr *PrRouter) Explain(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		http.Error(w, "pull_request_id is required", http.StatusBadRequest)
+		return
+	}
+
+	decisions, err := pr.PR.Explain(r.Context(), prID)
 	if err != nil {
 		if errors.Is(err, errs.NotFountError) {
-			errs.JsonCodeResp(w, errs.CodeNotFound, "Author/Team not exist", http.StatusNotFound)
+			errs.JsonCodeResp(w, errs.CodeNotFound, "PR not found", http.StatusNotFound)
 			return
 		}
-		if errors.Is(err, errs.ExistError) {
-			errs.JsonCodeResp(w, errs.CodePRExists, "Pre already exist", 409)
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]interface{}{
+		"pull_request_id": prID,
+		"decisions":       decisions,
+	}
+	jsonutils.JsonResponse(w, resp, http.StatusOK)
+}
+
+func (pr *PrRouter) StartReview(w http.ResponseWriter, r *http.Request) {
+	var req StartReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	review, err := pr.PR.StartReview(r.Context(), req.PullRequestID, req.UserID)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "PR not found", http.StatusNotFound)
 			return
 		}
-		// if errors.Is(err, errs.NoCandidateError) {
-		// 	errs.JsonCodeResp(w, errs.CodePRExists, "Team have no active users", 409)
-		// 	return
-		// }
-		http.Error(w, "Internal", 500)
+		if errors.Is(err, errs.PRMergedError) {
+			errs.JsonCodeResp(w, errs.CodePRMerged, "cannot start a review on merged PR", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.InvalidTransitionError) {
+			errs.JsonCodeResp(w, errs.CodeInvalidTransition, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.NotAssignedError) {
+			errs.JsonCodeResp(w, errs.CodeNotAssigned, "reviewer is not assigned to this PR", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Internal", http.StatusInternalServerError)
 		return
 	}
-	resp := map[string]interface{}{"pr": res}
-	jsonutils.JsonResponse(w, resp, http.StatusCreated)
+	jsonutils.JsonResponse(w, map[string]interface{}{"review": review}, http.StatusOK)
+}
+
+// Rebalance moves reviews from overloaded team members to underloaded ones, or only
+// reports the moves when dry_run is set.
+func (pr *PrRouter) Rebalance(w http.ResponseWriter, r *http.Request) {
+	var req RebalanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	moves, err := pr.PR.Rebalance(r.Context(), req.TeamName, req.DryRun)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "Team not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]interface{}{
+		"dry_run": req.DryRun,
+		"moves":   moves,
+	}
+	jsonutils.JsonResponse(w, resp, http.StatusOK)
+}
+
+func (pr *PrRouter) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	var req SubmitReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !ValidVerdict(req.Verdict) {
+		http.Error(w, "verdict must be one of APPROVED, CHANGES_REQUESTED, COMMENTED", http.StatusBadRequest)
+		return
+	}
+
+	res, err := pr.PR.SubmitReview(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "PR not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errs.PRMergedError) {
+			errs.JsonCodeResp(w, errs.CodePRMerged, "cannot review merged PR", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.InvalidTransitionError) {
+			errs.JsonCodeResp(w, errs.CodeInvalidTransition, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.NotAssignedError) {
+			errs.JsonCodeResp(w, errs.CodeNotAssigned, "reviewer is not assigned to this PR", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"pr": res}, http.StatusOK)
+}
+
+func (pr *PrRouter) Close(w http.ResponseWriter, r *http.Request) {
+	pr.changeStatus(w, r, pr.PR.Close)
+}
+
+func (pr *PrRouter) Reopen(w http.ResponseWriter, r *http.Request) {
+	pr.changeStatus(w, r, pr.PR.Reopen)
+}
+
+func (pr *PrRouter) ReadyForReview(w http.ResponseWriter, r *http.Request) {
+	pr.changeStatus(w, r, pr.PR.ReadyForReview)
+}
+
+// changeStatus serves the endpoints that move a PR through its states with move.
+func (pr *PrRouter) changeStatus(w http.ResponseWriter, r *http.Request, move func(context.Context, string) (*PullRequest, error)) {
+	var req StatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	res, err := move(r.Context(), req.PullRequestID)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "PR not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errs.InvalidTransitionError) {
+			errs.JsonCodeResp(w, errs.CodeInvalidTransition, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"pr": res}, http.StatusOK)
+}
+
+// Get answers with the details of one PR.
+func (pr *PrRouter) Get(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		http.Error(w, "pull_request_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := pr.PR.GetPr(r.Context(), prID)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "PR not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"pr": res}, http.StatusOK)
+}
+
+// List answers with a page of the PRs matching the query. Statuses are comma
+// separated, dates are RFC 3339 and ranges include their start but not their end.
+func (pr *PrRouter) List(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := pr.PR.List(r.Context(), *filter)
+	if err != nil {
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]interface{}{
+		"pull_requests": page.PullRequests,
+		"next_cursor":   page.NextCursor,
+	}
+	jsonutils.JsonResponse(w, resp, http.StatusOK)
+}
+
+func parseListFilter(query url.Values) (*ListFilter, error) {
+	filter := &ListFilter{
+		AuthorID:   query.Get("author_id"),
+		TeamName:   query.Get("team_name"),
+		ReviewerID: query.Get("reviewer_id"),
+		SortBy:     SortCreatedAt,
+		Order:      OrderDesc,
+		Limit:      DefaultListLimit,
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if !ValidStatus(status) {
+				return nil, fmt.Errorf("unknown status %q", status)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	for param, dst := range map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"merged_from":  &filter.MergedFrom,
+		"merged_to":    &filter.MergedTo,
+	} {
+		raw := query.Get(param)
+		if raw == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", param)
+		}
+		*dst = &at
+	}
+
+	if raw := query.Get("sort"); raw != "" {
+		if raw != SortCreatedAt && raw != SortMergedAt {
+			return nil, fmt.Errorf("sort must be %s or %s", SortCreatedAt, SortMergedAt)
+		}
+		filter.SortBy = raw
+	}
+	if raw := query.Get("order"); raw != "" {
+		if raw != OrderAsc && raw != OrderDesc {
+			return nil, fmt.Errorf("order must be %s or %s", OrderAsc, OrderDesc)
+		}
+		filter.Order = raw
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxListLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+		}
+		filter.Limit = limit
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := DecodeCursor(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		filter.After = cursor
+	}
+	return filter, nil
+}
+
+func (pr *PrRouter) Update(w http.ResponseWriter, r *http.Request) {
+	var req UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.PullRequestID == "" || req.EditedBy == "" {
+		http.Error(w, "pull_request_id and edited_by are required", http.StatusBadRequest)
+		return
+	}
+	if req.PullRequestName != nil && strings.TrimSpace(*req.PullRequestName) == "" {
+		http.Error(w, "pull_request_name must not be empty", http.StatusBadRequest)
+		return
+	}
+	if req.Priority != nil && !ValidPriority(*req.Priority) {
+		http.Error(w, "priority must be low, normal, high or critical", http.StatusBadRequest)
+		return
+	}
+
+	pr.update(r.Context(), w, req)
+}
+
+// update applies req and answers with the edited PR.
+func (pr *PrRouter) update(ctx context.Context, w http.ResponseWriter, req UpdateRequest) {
+	res, err := pr.PR.Update(ctx, req)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "PR or editor not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errs.PRNotOpenError) {
+			errs.JsonCodeResp(w, errs.CodePRNotOpen, "only draft and open PRs can be edited", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errs.VersionConflictError) {
+			errs.JsonCodeResp(w, errs.CodeVersionConflict, "the PR was edited since this version", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"pr": res}, http.StatusOK)
+}
+
+// History answers with the edit history of a PR.
+func (pr *PrRouter) History(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		http.Error(w, "pull_request_id is required", http.StatusBadRequest)
+		return
+	}
+
+	edits, err := pr.PR.History(r.Context(), prID)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "PR not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal", http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]interface{}{
+		"pull_request_id": prID,
+		"edits":           edits,
+	}
+	jsonutils.JsonResponse(w, resp, http.StatusOK)
 }
@@ -0,0 +1,145 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"pullreq/internal/team"
+	"pullreq/internal/user"
+)
+
+// Preview is what Create would assign for a request.
+type Preview struct {
+	PullRequest *PullRequest    `json:"pr"`
+	Strategy    string          `json:"strategy"`
+	Pool        []PoolCandidate `json:"candidate_pool"`
+	Excluded    []Exclusion     `json:"excluded"`
+}
+
+// createPlan is the outcome of the reviewer selection for a new PR.
+type createPlan struct {
+	pr       *PullRequest
+	settings *team.TeamSettings
+	files    []string
+	picked   *selection
+}
+
+// planCreate selects the reviewers of a new PR within tx. It is shared by Create and
+// Preview so both follow the same policy, a dry run leaves the selection state as it is.
+func (PR *PullRequestRepo) planCreate(ctx context.Context, tx *sql.Tx, req CreatePullRequestRequest, dryRun bool) (*createPlan, error) {
+	teamID, err := PR.TR.GetTeamByUserID(ctx, req.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := PR.TR.GetTeamSettings(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := user.NormalizeTags(req.Labels)
+	files := normalizeFiles(req.Files)
+	owners, err := PR.ownersForPaths(ctx, files, req.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := PR.rulesFor(ctx, req.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := PR.now()
+	duty, err := PR.dutyFor(ctx, teamID, settings, now)
+	if err != nil {
+		return nil, err
+	}
+
+	picked, err := PR.pickReviewers(ctx, tx, assignment{
+		teamID:   teamID,
+		settings: settings,
+		authorID: req.AuthorID,
+		labels:   labels,
+		owners:   owners,
+		count:    settings.ReviewersRequired,
+		avoid:    rules.Never,
+		prefer:   rules.Prefer,
+		always:   rules.Always,
+		duty:     duty,
+		now:      now,
+
+		requireSenior: settings.RequireSenior,
+		dryRun:        dryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+	reviews := picked.reviewers
+
+	var violations []string
+	if settings.RequireSenior && !picked.senior {
+		violations = append(violations, PolicyNoSenior)
+	}
+
+	pr := &PullRequest{
+		ID:                req.ID,
+		PullRequestName:   req.PullRequestName,
+		AuthorID:          req.AuthorID,
+		Status:            StatusOpen,
+		Priority:          PriorityNormal,
+		Version:           1,
+		AssignedReviewers: reviews,
+		FallbackReviewers: picked.fallback,
+		ReviewersRequired: settings.ReviewersRequired,
+		Understaffed:      len(reviews) < settings.ReviewersRequired,
+		Labels:            labels,
+		PolicyViolations:  violations,
+	}
+
+	return &createPlan{pr: pr, settings: settings, files: files, picked: picked}, nil
+}
+
+// Preview runs the selection Create would run for req without writing or locking
+// anything. Strategies that break ties randomly may pick differently on Create. A draft
+// gets no reviewers until it is ready for review, so neither does its preview.
+func (PR *PullRequestRepo) Preview(ctx context.Context, req CreatePullRequestRequest) (*Preview, error) {
+	if req.ID != "" {
+		if err := PR.Check(ctx, req.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Draft {
+		teamID, err := PR.TR.GetTeamByUserID(ctx, req.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+		settings, err := PR.TR.GetTeamSettings(ctx, teamID)
+		if err != nil {
+			return nil, err
+		}
+		return &Preview{
+			PullRequest: newDraft(req),
+			Strategy:    settings.ReviewerStrategy,
+			Pool:        make([]PoolCandidate, 0),
+			Excluded:    make([]Exclusion, 0),
+		}, nil
+	}
+
+	tx, err := PR.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	plan, err := PR.planCreate(ctx, tx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Preview{
+		PullRequest: plan.pr,
+		Strategy:    plan.settings.ReviewerStrategy,
+		Pool:        plan.picked.pool,
+		Excluded:    plan.picked.excluded,
+	}, nil
+}
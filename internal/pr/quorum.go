@@ -0,0 +1,120 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	dbutils "pullreq/internal/db_utils"
+	"pullreq/internal/errs"
+	"pullreq/internal/team"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// Conditions of the merge quorum of a team.
+const (
+	ConditionApprovals          = "APPROVALS"
+	ConditionNoChangesRequested = "NO_CHANGES_REQUESTED"
+	ConditionThreadsResolved    = "THREADS_RESOLVED"
+)
+
+// UnmetCondition is a quorum condition a PR does not meet.
+type UnmetCondition struct {
+	Condition string   `json:"condition"`
+	Detail    string   `json:"detail"`
+	Users     []string `json:"users,omitempty"` // reviewers the condition waits for
+}
+
+// MergeBlockedError is returned when a PR does not meet the merge quorum of its team.
+// It matches errs.MergeBlockedError.
+type MergeBlockedError struct {
+	Unmet []UnmetCondition
+}
+
+func (e *MergeBlockedError) Error() string {
+	names := make([]string, len(e.Unmet))
+	for i, c := range e.Unmet {
+		names[i] = c.Condition
+	}
+	return fmt.Sprintf("%v: %s", errs.MergeBlockedError, strings.Join(names, ", "))
+}
+
+func (e *MergeBlockedError) Unwrap() error {
+	return errs.MergeBlockedError
+}
+
+// checkQuorum returns the conditions of the quorum the verdicts of the current
+// reviewers and the unresolved comment threads do not meet.
+func checkQuorum(settings *team.TeamSettings, verdicts []Verdict, unresolved int) []UnmetCondition {
+	unmet := make([]UnmetCondition, 0)
+
+	approvals := 0
+	requesting := make([]string, 0)
+	for _, v := range verdicts {
+		switch v.Verdict {
+		case VerdictApproved:
+			approvals++
+		case VerdictChangesRequested:
+			requesting = append(requesting, v.UserID)
+		}
+	}
+
+	if approvals < settings.ApprovalsRequired {
+		unmet = append(unmet, UnmetCondition{
+			Condition: ConditionApprovals,
+			Detail:    fmt.Sprintf("%d of %d required approvals", approvals, settings.ApprovalsRequired),
+		})
+	}
+	if settings.BlockOnChangesRequested && len(requesting) > 0 {
+		unmet = append(unmet, UnmetCondition{
+			Condition: ConditionNoChangesRequested,
+			Detail:    "changes requested by " + strings.Join(requesting, ", "),
+			Users:     requesting,
+		})
+	}
+	if settings.RequireResolvedThreads && unresolved > 0 {
+		unmet = append(unmet, UnmetCondition{
+			Condition: ConditionThreadsResolved,
+			Detail:    fmt.Sprintf("%d unresolved comment threads", unresolved),
+		})
+	}
+	return unmet
+}
+
+// unresolvedThreads counts the comment threads of the PR nobody resolved.
+func unresolvedThreads(ctx context.Context, q dbutils.RowQueryer, prID string) (int, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query, args, err := psql.Select("COUNT(*)").
+		From("pr_comments").
+		Where(sq.Eq{"request_id": prID, "thread_id": nil, "resolved_at": nil}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	err = q.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// insertForcedMerge records who forced the merge and which conditions were bypassed.
+func insertForcedMerge(ctx context.Context, tx *sql.Tx, req MergeRequest, unmet []UnmetCondition, at time.Time) error {
+	bypassed := make([]string, len(unmet))
+	for i, c := range unmet {
+		bypassed[i] = c.Condition
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Insert("forced_merges").
+		Columns("request_id", "forced_by", "reason", "bypassed", "merged_at").
+		Values(req.PullRequestID, req.ForcedBy, req.Reason, pq.Array(bypassed), at).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, q, args...)
+	return err
+}
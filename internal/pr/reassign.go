@@ -0,0 +1,297 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pullreq/internal/errs"
+	"pullreq/internal/team"
+	"pullreq/internal/user"
+	"slices"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ReasonDeactivated is recorded for reviews moved off users who were deactivated.
+const ReasonDeactivated = "reviewer deactivated"
+
+// replacement describes a reviewer to be replaced on a PR.
+type replacement struct {
+	prID      string
+	authorID  string
+	status    string
+	reviewers []string // current reviewers, the old one included
+	oldID     string
+	requested string
+
+	// forced replacements take the old reviewer away for good, e.g. on deactivation.
+	// Rules of the author cannot keep the old reviewer and a replacement is found
+	// for the last owner or senior when possible, even one that does not fit.
+	forced      bool
+	unavailable []string // left out on top of the current reviewers
+}
+
+// pickReplacement selects the reviewer replacing r.oldID under the policy of the team
+// of the author. The selection is empty only for forced replacements.
+func (PR *PullRequestRepo) pickReplacement(ctx context.Context, tx *sql.Tx, r replacement) (*selection, *team.TeamSettings, error) {
+	teamID, err := PR.TR.GetTeamByUserID(ctx, r.authorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	settings, err := PR.TR.GetTeamSettings(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	labels, err := getLabels(ctx, tx, r.prID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the replacement has to be a code owner when the old reviewer was the last one
+	files, err := getFiles(ctx, tx, r.prID)
+	if err != nil {
+		return nil, nil, err
+	}
+	owners, err := PR.ownersForPaths(ctx, files, r.authorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(owners) > 0 {
+		for _, id := range r.reviewers {
+			if id != r.oldID && slices.Contains(owners, id) {
+				owners = nil
+				break
+			}
+		}
+		if !slices.Contains(owners, r.oldID) {
+			owners = nil
+		}
+	}
+
+	rules, err := PR.rulesFor(ctx, r.authorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if slices.Contains(rules.Always, r.oldID) && !r.forced {
+		return nil, nil, errs.AffinityViolationError
+	}
+
+	exclude := make(map[string]bool, len(r.reviewers)+len(r.unavailable))
+	for _, id := range r.reviewers {
+		exclude[id] = true
+	}
+	for _, id := range r.unavailable {
+		exclude[id] = true
+	}
+
+	// an open PR never loses its last senior reviewer
+	lastSenior := false
+	if settings.RequireSenior && r.status == StatusOpen {
+		current, err := PR.UR.GetUsers(ctx, r.reviewers)
+		if err != nil {
+			return nil, nil, err
+		}
+		seniors := 0
+		oldSenior := false
+		for _, u := range current {
+			if u.IsSenior() {
+				seniors++
+				oldSenior = oldSenior || u.Id == r.oldID
+			}
+		}
+		lastSenior = oldSenior && seniors == 1
+	}
+
+	a := assignment{
+		teamID:    teamID,
+		settings:  settings,
+		authorID:  r.authorID,
+		labels:    labels,
+		owners:    owners,
+		exclude:   exclude,
+		count:     1,
+		avoid:     rules.Never,
+		prefer:    rules.Prefer,
+		requested: r.requested,
+	}
+	seniorOnly := lastSenior && !r.forced
+	a.seniorOnly = seniorOnly
+	a.requireSenior = lastSenior && r.forced
+
+	picked, err := PR.pickReviewers(ctx, tx, a)
+	if r.forced && errors.Is(err, errs.NoOwnerError) {
+		// an owner leaving for good is better replaced by anybody than by nobody
+		a.owners = nil
+		picked, err = PR.pickReviewers(ctx, tx, a)
+	}
+	if seniorOnly && errors.Is(err, errs.InvalidCandidateError) {
+		return nil, nil, errs.SeniorRequiredError
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(picked.reviewers) == 0 && !r.forced {
+		if seniorOnly {
+			return nil, nil, errs.SeniorRequiredError
+		}
+		return nil, nil, errs.NoCandidateError
+	}
+
+	return picked, settings, nil
+}
+
+// applyReplacement moves the review of oldID to the picked reviewer, or drops it when
+// nobody was picked, and records the decision and the history. It returns the new
+// reviewer, empty when the review was dropped.
+func (PR *PullRequestRepo) applyReplacement(ctx context.Context, tx *sql.Tx, prID, oldID string, picked *selection, strategy, reason string, requested bool) (string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	var newReviewer string
+	if len(picked.reviewers) == 0 {
+		deleteQuery, args, _ := psql.Delete("userspr").
+			Where(sq.Eq{"user_id": oldID, "request_id": prID}).
+			ToSql()
+		if _, err := tx.ExecContext(ctx, deleteQuery, args...); err != nil {
+			return "", err
+		}
+	} else {
+		newReviewer = picked.reviewers[0]
+
+		updateQuery, args, _ := psql.Update("userspr").
+			Set("user_id", newReviewer).
+			Set("fallback", len(picked.fallback) > 0).
+			Set("started_at", nil).
+			Where(sq.Eq{"user_id": oldID, "request_id": prID}).
+			ToSql()
+		if _, err := tx.ExecContext(ctx, updateQuery, args...); err != nil {
+			return "", err
+		}
+
+		historyQuery, args, _ := psql.Insert("usershistory").
+			Columns("user_id", "pr_count").
+			Values(newReviewer, 1).
+			Suffix("ON CONFLICT (user_id) DO UPDATE SET pr_count = usershistory.pr_count + 1").
+			ToSql()
+		if _, err := tx.ExecContext(ctx, historyQuery, args...); err != nil {
+			return "", err
+		}
+	}
+
+	if err := insertDecision(ctx, tx, prID, DecisionReassign, strategy, picked); err != nil {
+		return "", err
+	}
+
+	var newUserID interface{}
+	if newReviewer != "" {
+		newUserID = newReviewer
+	}
+	reassignQuery, args, _ := psql.Insert("reassignment_history").
+		Columns("request_id", "old_user_id", "new_user_id", "reason", "requested", "reassigned_at").
+		Values(prID, oldID, newUserID, reason, requested, picked.at).
+		ToSql()
+	if _, err := tx.ExecContext(ctx, reassignQuery, args...); err != nil {
+		return "", err
+	}
+
+	return newReviewer, nil
+}
+
+// ReassignReviews moves every review the users hold on OPEN PRs to replacement reviewers
+// chosen by the usual policy, within tx. The users are treated as unavailable even when
+// tx has not deactivated them yet. Reviews nobody can take over are dropped and their
+// PRs reported understaffed.
+func (PR *PullRequestRepo) ReassignReviews(ctx context.Context, tx *sql.Tx, userIDs []string) ([]user.Reassignment, error) {
+	reassignments := make([]user.Reassignment, 0)
+	if len(userIDs) == 0 {
+		return reassignments, nil
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Select("ur.request_id", "ur.user_id", "pr.author_id", "pr.pr_status").
+		From("userspr ur").
+		Join("pr ON pr.id = ur.request_id").
+		Where(sq.Eq{"ur.user_id": userIDs, "pr.pr_status": StatusOpen}).
+		OrderBy("ur.request_id", "ur.user_id").
+		Suffix("FOR UPDATE OF pr").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	type heldReview struct{ prID, userID, authorID, status string }
+	held := make([]heldReview, 0)
+	for rows.Next() {
+		var h heldReview
+		if err := rows.Scan(&h.prID, &h.userID, &h.authorID, &h.status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		held = append(held, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, h := range held {
+		reviewers, err := getReviewers(ctx, tx, h.prID)
+		if err != nil {
+			return nil, err
+		}
+
+		picked, settings, err := PR.pickReplacement(ctx, tx, replacement{
+			prID:        h.prID,
+			authorID:    h.authorID,
+			status:      h.status,
+			reviewers:   reviewers,
+			oldID:       h.userID,
+			forced:      true,
+			unavailable: userIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		newReviewer, err := PR.applyReplacement(ctx, tx, h.prID, h.userID, picked, settings.ReviewerStrategy, ReasonDeactivated, false)
+		if err != nil {
+			return nil, err
+		}
+		reassignments = append(reassignments, user.Reassignment{
+			PullRequestID: h.prID,
+			OldUserID:     h.userID,
+			NewUserID:     newReviewer,
+		})
+	}
+
+	// a PR is understaffed once all its reviews have been moved
+	understaffed := make(map[string]bool)
+	for _, r := range reassignments {
+		if _, ok := understaffed[r.PullRequestID]; ok {
+			continue
+		}
+		reviewers, err := getReviewers(ctx, tx, r.PullRequestID)
+		if err != nil {
+			return nil, err
+		}
+		var required int
+		requiredQuery, args, _ := psql.Select("COALESCE(reviewers_required, 0)").
+			From("pr").
+			Where(sq.Eq{"id": r.PullRequestID}).
+			ToSql()
+		if err := tx.QueryRowContext(ctx, requiredQuery, args...).Scan(&required); err != nil {
+			return nil, err
+		}
+		understaffed[r.PullRequestID] = len(reviewers) < required
+	}
+	for i := range reassignments {
+		reassignments[i].Understaffed = understaffed[reassignments[i].PullRequestID]
+	}
+
+	return reassignments, nil
+}
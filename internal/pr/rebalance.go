@@ -0,0 +1,224 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pullreq/internal/errs"
+	"sort"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ReasonRebalance is recorded for reviews the rebalancer moves.
+const ReasonRebalance = "rebalance"
+
+// Move is a review the rebalancer moved from one team member to another.
+type Move struct {
+	TeamName      string `json:"team_name"`
+	PullRequestID string `json:"pull_request_id"`
+	OldUserID     string `json:"old_user_id"`
+	NewUserID     string `json:"new_user_id"`
+}
+
+// Rebalance evens out the OPEN reviews among the active members of teams with a
+// rebalance threshold. While the spread between the most and the least loaded member
+// exceeds the threshold, reviews nobody has started are moved from loaded members to
+// less loaded ones through the same policy AssignedReviewer applies, so moves that
+// policy would refuse are skipped. An empty teamName rebalances every team. A dry run
+// plans the same moves and rolls them back.
+func (PR *PullRequestRepo) Rebalance(ctx context.Context, teamName string, dryRun bool) ([]Move, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	builder := psql.Select("id", "team_name", "rebalance_threshold").
+		From("teams").
+		OrderBy("team_name")
+	if teamName != "" {
+		builder = builder.Where(sq.Eq{"team_name": teamName})
+	}
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := PR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	type teamThreshold struct {
+		id        int
+		name      string
+		threshold int
+	}
+	teams := make([]teamThreshold, 0)
+	for rows.Next() {
+		var t teamThreshold
+		if err := rows.Scan(&t.id, &t.name, &t.threshold); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if teamName != "" && len(teams) == 0 {
+		return nil, errs.NotFountError
+	}
+
+	moves := make([]Move, 0)
+	for _, t := range teams {
+		if t.threshold <= 0 {
+			continue
+		}
+		teamMoves, err := PR.rebalanceTeam(ctx, t.id, t.name, t.threshold, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, teamMoves...)
+	}
+	return moves, nil
+}
+
+// rebalanceTeam rebalances one team in its own transaction.
+func (PR *PullRequestRepo) rebalanceTeam(ctx context.Context, teamID int, teamName string, threshold int, dryRun bool) ([]Move, error) {
+	tx, err := PR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	members, err := PR.TR.GetTeamMember(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	active := members[:0]
+	for _, m := range members {
+		if m.IsActive {
+			active = append(active, m)
+		}
+	}
+	candidates, err := loadCandidates(ctx, tx, active)
+	if err != nil {
+		return nil, err
+	}
+	load := make(map[string]int, len(candidates))
+	for _, c := range candidates {
+		load[c.User.Id] = c.OpenReviews
+	}
+
+	moves := make([]Move, 0)
+	for {
+		move, err := PR.nextMove(ctx, tx, candidates, load, threshold)
+		if err != nil {
+			return nil, err
+		}
+		if move == nil {
+			break
+		}
+		move.TeamName = teamName
+		load[move.OldUserID]--
+		load[move.NewUserID]++
+		moves = append(moves, *move)
+	}
+
+	if dryRun {
+		return moves, nil
+	}
+	return moves, tx.Commit()
+}
+
+// nextMove moves one review from the most loaded member that can give one away to the
+// least loaded member that can take it. Only members whose loads differ by more than
+// the threshold are paired, so every move narrows the spread and the search ends.
+func (PR *PullRequestRepo) nextMove(ctx context.Context, tx *sql.Tx, candidates []Candidate, load map[string]int, threshold int) (*Move, error) {
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.User.Id
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		if load[ids[i]] != load[ids[j]] {
+			return load[ids[i]] > load[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	for _, from := range ids {
+		var reviews []string
+		for i := len(ids) - 1; i >= 0; i-- {
+			to := ids[i]
+			if load[from]-load[to] <= threshold {
+				break
+			}
+			if reviews == nil {
+				var err error
+				if reviews, err = unstartedReviews(ctx, tx, from); err != nil {
+					return nil, err
+				}
+			}
+			for _, prID := range reviews {
+				_, err := PR.reassign(ctx, tx, ReassignRequest{
+					PullRequestID:     prID,
+					CurrentReviewerID: from,
+					NewReviewerID:     to,
+					Reason:            ReasonRebalance,
+				}, false)
+				if isPolicyError(err) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				return &Move{PullRequestID: prID, OldUserID: from, NewUserID: to}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// unstartedReviews returns the OPEN PRs userID reviews without having started.
+func unstartedReviews(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Select("ur.request_id").
+		From("userspr ur").
+		Join("pr ON pr.id = ur.request_id").
+		Where(sq.Eq{"ur.user_id": userID, "pr.pr_status": StatusOpen}).
+		Where("ur.started_at IS NULL").
+		OrderBy("pr.created_ad DESC", "ur.request_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prIDs := make([]string, 0)
+	for rows.Next() {
+		var prID string
+		if err := rows.Scan(&prID); err != nil {
+			return nil, err
+		}
+		prIDs = append(prIDs, prID)
+	}
+	return prIDs, rows.Err()
+}
+
+// isPolicyError reports whether err is a refusal of the assignment policy.
+func isPolicyError(err error) bool {
+	for _, target := range []error{
+		errs.InvalidCandidateError,
+		errs.AffinityViolationError,
+		errs.SeniorRequiredError,
+		errs.NoOwnerError,
+		errs.NoCandidateError,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	dbutils "pullreq/internal/db_utils"
+	"pullreq/internal/errs"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Verdicts a reviewer can submit.
+const (
+	VerdictApproved         = "APPROVED"
+	VerdictChangesRequested = "CHANGES_REQUESTED"
+	VerdictCommented        = "COMMENTED"
+)
+
+func ValidVerdict(verdict string) bool {
+	switch verdict {
+	case VerdictApproved, VerdictChangesRequested, VerdictCommented:
+		return true
+	}
+	return false
+}
+
+// Review is the assignment of a reviewer to a PR.
+type Review struct {
+	PullRequestID string     `json:"pull_request_id"`
+	UserID        string     `json:"user_id"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+}
+
+// Verdict is the latest verdict a reviewer submitted on a PR.
+type Verdict struct {
+	UserID      string    `json:"user_id"`
+	Verdict     string    `json:"verdict"`
+	Message     string    `json:"message,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// StartReview marks the review of userID on the PR as started. Started reviews stay
+// with their reviewer when the load is rebalanced.
+func (PR *PullRequestRepo) StartReview(ctx context.Context, prID, userID string) (*Review, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	var status string
+	statusQuery, args, _ := psql.Select("pr_status").From("pr").Where(sq.Eq{"id": prID}).ToSql()
+	if err := PR.DB.QueryRowContext(ctx, statusQuery, args...).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+	if err := RequireStatus(status, "review", reviewStates); err != nil {
+		return nil, err
+	}
+
+	startedAt, err := markStarted(ctx, PR.DB, prID, userID, PR.now())
+	if err != nil {
+		return nil, err
+	}
+	return &Review{PullRequestID: prID, UserID: userID, StartedAt: &startedAt}, nil
+}
+
+// markStarted sets the start of the review unless it has started already and returns
+// it, errs.NotAssignedError when userID does not review the PR.
+func markStarted(ctx context.Context, q dbutils.RowQueryer, prID, userID string, at time.Time) (time.Time, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	updateQuery, args, err := psql.Update("userspr").
+		Set("started_at", sq.Expr("COALESCE(started_at, ?)", at)).
+		Where(sq.Eq{"request_id": prID, "user_id": userID}).
+		Suffix("RETURNING started_at").
+		ToSql()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var startedAt time.Time
+	if err := q.QueryRowContext(ctx, updateQuery, args...).Scan(&startedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, errs.NotAssignedError
+		}
+		return time.Time{}, err
+	}
+	return startedAt, nil
+}
+
+// SubmitReview records the verdict of a reviewer currently assigned to the PR. Earlier
+// verdicts are kept, the PR shows the latest one of every reviewer.
+func (PR *PullRequestRepo) SubmitReview(ctx context.Context, req SubmitReviewRequest) (*PullRequest, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := PR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	lockQuery, args, _ := psql.Select("pr_status").
+		From("pr").
+		Where(sq.Eq{"id": req.PullRequestID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err := tx.QueryRowContext(ctx, lockQuery, args...).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+	if err := RequireStatus(status, "review", reviewStates); err != nil {
+		return nil, err
+	}
+
+	now := PR.now()
+	// a verdict starts the review when it has not been started explicitly
+	if _, err := markStarted(ctx, tx, req.PullRequestID, req.UserID, now); err != nil {
+		return nil, err
+	}
+
+	insertQuery, args, err := psql.Insert("review_verdicts").
+		Columns("request_id", "user_id", "verdict", "message", "submitted_at").
+		Values(req.PullRequestID, req.UserID, req.Verdict, req.Message, now).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, insertQuery, args...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return PR.GetPr(ctx, req.PullRequestID)
+}
+
+// getVerdicts returns the latest verdict of every current reviewer of the PR who submitted one.
+func getVerdicts(ctx context.Context, q dbutils.Queryer, prID string) ([]Verdict, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	verdictsQuery, args, err := psql.
+		Select("DISTINCT ON (v.user_id) v.user_id", "v.verdict", "v.message", "v.submitted_at").
+		From("review_verdicts v").
+		Join("userspr ur ON ur.request_id = v.request_id AND ur.user_id = v.user_id").
+		Where(sq.Eq{"v.request_id": prID}).
+		OrderBy("v.user_id", "v.submitted_at DESC", "v.id DESC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, verdictsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	verdicts := make([]Verdict, 0)
+	for rows.Next() {
+		var v Verdict
+		if err := rows.Scan(&v.UserID, &v.Verdict, &v.Message, &v.SubmittedAt); err != nil {
+			return nil, err
+		}
+		verdicts = append(verdicts, v)
+	}
+	return verdicts, rows.Err()
+}
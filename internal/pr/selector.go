@@ -0,0 +1,324 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"pullreq/internal/team"
+	"pullreq/internal/user"
+	"sort"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Candidate is an active team member who may be assigned to review a PR.
+type Candidate struct {
+	User        *user.User
+	OpenReviews int
+}
+
+// AtCapacity reports whether the candidate cannot take one more review.
+func (c Candidate) AtCapacity() bool {
+	return c.User.MaxOpenReviews != nil && c.OpenReviews >= *c.User.MaxOpenReviews
+}
+
+// SelectionInput describes a single reviewer selection.
+type SelectionInput struct {
+	TeamID     int
+	Strategy   string
+	Candidates []Candidate
+	Count      int
+	Tier       int        // index of the tier SelectPreferred hands over
+	Tx         *sql.Tx    // transaction the assignment is written in
+	DryRun     bool       // the selection is only previewed, nothing may be written or locked
+	Rand       *rand.Rand // source of randomness, nil means a fresh random one
+	Trace      *Trace     // filled with the state the selection depended on when set
+	Replay     bool       // the state is taken from Trace instead of the database
+}
+
+// Trace is the state a selection depended on besides its input, enough to replay it.
+type Trace struct {
+	CursorBefore string // round robin cursor of the team before the selection
+	CursorAfter  string
+}
+
+func (in SelectionInput) rand() *rand.Rand {
+	if in.Rand == nil {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return in.Rand
+}
+
+// ReviewerSelector picks up to in.Count reviewer IDs out of in.Candidates.
+type ReviewerSelector interface {
+	Select(ctx context.Context, in SelectionInput) ([]string, error)
+}
+
+var defaultSelector ReviewerSelector = NewStrategySelector()
+
+// StrategySelector dispatches selection to the strategy stored in the team settings.
+type StrategySelector struct {
+	Strategies map[string]ReviewerSelector
+	Default    string
+}
+
+// NewStrategySelector returns a StrategySelector with all built-in strategies registered.
+func NewStrategySelector() *StrategySelector {
+	return &StrategySelector{
+		Strategies: map[string]ReviewerSelector{
+			team.StrategyRandom:      &RandomSelector{},
+			team.StrategyRoundRobin:  &RoundRobinSelector{},
+			team.StrategyLeastLoaded: &LeastLoadedSelector{},
+			team.StrategyWeighted:    &WeightedSelector{},
+		},
+		Default: team.StrategyLeastLoaded,
+	}
+}
+
+func (s *StrategySelector) Select(ctx context.Context, in SelectionInput) ([]string, error) {
+	selector, ok := s.Strategies[in.Strategy]
+	if !ok {
+		selector = s.Strategies[s.Default]
+	}
+
+	return selector.Select(ctx, in)
+}
+
+// RandomSelector picks reviewers uniformly at random.
+type RandomSelector struct{}
+
+func (s *RandomSelector) Select(ctx context.Context, in SelectionInput) ([]string, error) {
+	shuffled := make([]Candidate, len(in.Candidates))
+	copy(shuffled, in.Candidates)
+	in.rand().Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return takeIDs(shuffled, in.Count), nil
+}
+
+// LeastLoadedSelector picks reviewers with the fewest open reviews, ties are broken randomly.
+type LeastLoadedSelector struct{}
+
+func (s *LeastLoadedSelector) Select(ctx context.Context, in SelectionInput) ([]string, error) {
+	return takeIDs(rankByLoad(in.Candidates, in.rand()), in.Count), nil
+}
+
+// RoundRobinSelector walks over the candidates sorted by ID, continuing after the last
+// reviewer assigned in the team. The cursor is kept in team_review_cursor and is locked
+// and advanced in the transaction of the assignment, so it survives restarts and
+// concurrent requests. A dry run only reads the cursor, a replay starts from the cursor
+// in the trace.
+type RoundRobinSelector struct{}
+
+func (s *RoundRobinSelector) Select(ctx context.Context, in SelectionInput) ([]string, error) {
+	if in.Replay {
+		if in.Trace == nil {
+			return nil, errors.New("round robin replay requires a trace")
+		}
+		return in.traced(in.Trace.CursorBefore, rotate(in.Candidates, in.Trace.CursorBefore, in.Count)), nil
+	}
+	if in.Tx == nil {
+		return nil, errors.New("round robin selection requires a transaction")
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	if in.DryRun {
+		cursorQuery, args, err := psql.Select("COALESCE(last_user_id, '')").
+			From("team_review_cursor").
+			Where(sq.Eq{"team_id": in.TeamID}).
+			ToSql()
+		if err != nil {
+			return nil, err
+		}
+
+		var cursor string
+		err = in.Tx.QueryRowContext(ctx, cursorQuery, args...).Scan(&cursor)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		return in.traced(cursor, rotate(in.Candidates, cursor, in.Count)), nil
+	}
+
+	ensureQuery, args, err := psql.Insert("team_review_cursor").
+		Columns("team_id").
+		Values(in.TeamID).
+		Suffix("ON CONFLICT (team_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := in.Tx.ExecContext(ctx, ensureQuery, args...); err != nil {
+		return nil, err
+	}
+
+	lockQuery, args, err := psql.Select("COALESCE(last_user_id, '')").
+		From("team_review_cursor").
+		Where(sq.Eq{"team_id": in.TeamID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor string
+	if err := in.Tx.QueryRowContext(ctx, lockQuery, args...).Scan(&cursor); err != nil {
+		return nil, err
+	}
+
+	reviewers := in.traced(cursor, rotate(in.Candidates, cursor, in.Count))
+	if len(reviewers) == 0 {
+		return reviewers, nil
+	}
+
+	updateQuery, args, err := psql.Update("team_review_cursor").
+		Set("last_user_id", reviewers[len(reviewers)-1]).
+		Where(sq.Eq{"team_id": in.TeamID}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := in.Tx.ExecContext(ctx, updateQuery, args...); err != nil {
+		return nil, err
+	}
+
+	return reviewers, nil
+}
+
+// traced records the round robin cursor before and after reviewers were picked from
+// it in the trace of the selection, if there is one.
+func (in SelectionInput) traced(cursor string, reviewers []string) []string {
+	if in.Trace == nil {
+		return reviewers
+	}
+	in.Trace.CursorBefore, in.Trace.CursorAfter = cursor, cursor
+	if len(reviewers) > 0 {
+		in.Trace.CursorAfter = reviewers[len(reviewers)-1]
+	}
+	return reviewers
+}
+
+// WeightedSelector picks reviewers at random with a probability inversely proportional
+// to their current number of open reviews.
+type WeightedSelector struct{}
+
+func (s *WeightedSelector) Select(ctx context.Context, in SelectionInput) ([]string, error) {
+	pool := make([]Candidate, len(in.Candidates))
+	copy(pool, in.Candidates)
+
+	rng := in.rand()
+	reviewers := make([]string, 0, in.Count)
+	for len(reviewers) < in.Count && len(pool) > 0 {
+		var total float64
+		for _, c := range pool {
+			total += weight(c)
+		}
+
+		point := rng.Float64() * total
+		picked := len(pool) - 1
+		for i, c := range pool {
+			point -= weight(c)
+			if point < 0 {
+				picked = i
+				break
+			}
+		}
+
+		reviewers = append(reviewers, pool[picked].User.Id)
+		pool = append(pool[:picked], pool[picked+1:]...)
+	}
+
+	return reviewers, nil
+}
+
+func weight(c Candidate) float64 {
+	return 1 / float64(1+c.OpenReviews)
+}
+
+// SelectPreferred fills up to in.Count reviewers from the tiers in order. Each tier is
+// handed to the selector as a candidate pool of its own, so a later tier is used only
+// when the earlier ones run out of candidates.
+func SelectPreferred(ctx context.Context, s ReviewerSelector, in SelectionInput, tiers ...[]Candidate) ([]string, error) {
+	picked := make([]string, 0, in.Count)
+	for i, tier := range tiers {
+		if len(picked) >= in.Count {
+			break
+		}
+		if len(tier) == 0 {
+			continue
+		}
+
+		tierIn := in
+		tierIn.Tier = i
+		tierIn.Candidates = tier
+		tierIn.Count = in.Count - len(picked)
+		ids, err := s.Select(ctx, tierIn)
+		if err != nil {
+			return nil, err
+		}
+		picked = append(picked, ids...)
+	}
+
+	return picked, nil
+}
+
+// partition splits the pool into candidates accepted by preferred and the rest.
+func partition(pool []Candidate, preferred func(Candidate) bool) ([]Candidate, []Candidate) {
+	matched := make([]Candidate, 0, len(pool))
+	rest := make([]Candidate, 0, len(pool))
+	for _, c := range pool {
+		if preferred(c) {
+			matched = append(matched, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	return matched, rest
+}
+
+// rankByLoad orders candidates from the least to the most loaded, ties are broken randomly.
+func rankByLoad(candidates []Candidate, rng *rand.Rand) []Candidate {
+	ranked := make([]Candidate, len(candidates))
+	copy(ranked, candidates)
+
+	rng.Shuffle(len(ranked), func(i, j int) {
+		ranked[i], ranked[j] = ranked[j], ranked[i]
+	})
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].OpenReviews < ranked[j].OpenReviews
+	})
+
+	return ranked
+}
+
+// rotate returns up to count candidate IDs in ID order, starting after cursor and wrapping around.
+func rotate(candidates []Candidate, cursor string, count int) []string {
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.User.Id
+	}
+	sort.Strings(ids)
+
+	start := sort.Search(len(ids), func(i int) bool { return ids[i] > cursor })
+
+	reviewers := make([]string, 0, count)
+	for i := 0; i < len(ids) && len(reviewers) < count; i++ {
+		reviewers = append(reviewers, ids[(start+i)%len(ids)])
+	}
+
+	return reviewers
+}
+
+func takeIDs(candidates []Candidate, count int) []string {
+	ids := make([]string, 0, count)
+	for _, c := range candidates {
+		if len(ids) == count {
+			break
+		}
+		ids = append(ids, c.User.Id)
+	}
+
+	return ids
+}
@@ -0,0 +1,143 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pullreq/internal/errs"
+	"slices"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// States of a PR.
+const (
+	StatusDraft  = "DRAFT"
+	StatusOpen   = "OPEN"
+	StatusClosed = "CLOSED"
+	StatusMerged = "MERGED"
+)
+
+// transitions lists the states a PR can move to from each state. MERGED is final.
+var transitions = map[string][]string{
+	StatusDraft:  {StatusOpen, StatusClosed},
+	StatusOpen:   {StatusClosed, StatusMerged},
+	StatusClosed: {StatusOpen, StatusDraft},
+}
+
+// ValidStatus reports whether status is a state of a PR.
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusDraft, StatusOpen, StatusClosed, StatusMerged:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether a PR in state from can move to state to.
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func transitionError(from, to string) error {
+	return fmt.Errorf("%w: %s -> %s", errs.InvalidTransitionError, from, to)
+}
+
+// States in which a PR accepts changes other than a transition.
+var (
+	reviewStates = []string{StatusOpen}              // reviews are started, submitted and reassigned
+	editStates   = []string{StatusDraft, StatusOpen} // the name, labels, description and priority are edited
+)
+
+// RequireStatus checks that a PR in state status accepts op. A MERGED PR accepts
+// nothing and gets errs.PRMergedError, the other states an errs.InvalidTransitionError.
+func RequireStatus(status, op string, allowed []string) error {
+	if slices.Contains(allowed, status) {
+		return nil
+	}
+	if status == StatusMerged {
+		return errs.PRMergedError
+	}
+	return fmt.Errorf("%w: %s of a %s PR", errs.InvalidTransitionError, op, status)
+}
+
+// Close closes a DRAFT or OPEN PR without merging it.
+func (PR *PullRequestRepo) Close(ctx context.Context, ID string) (*PullRequest, error) {
+	return PR.transition(ctx, ID, StatusClosed)
+}
+
+// Reopen moves a CLOSED PR back to OPEN, or to DRAFT when it was closed as a draft
+// and never got reviewers assigned.
+func (PR *PullRequestRepo) Reopen(ctx context.Context, ID string) (*PullRequest, error) {
+	assigned, err := hasAssignment(ctx, PR.DB, ID)
+	if err != nil {
+		return nil, err
+	}
+	if !assigned {
+		return PR.transition(ctx, ID, StatusDraft, StatusClosed)
+	}
+	return PR.transition(ctx, ID, StatusOpen, StatusClosed)
+}
+
+// transition moves the PR to state to, errs.InvalidTransitionError is returned when the
+// state machine does not allow it or, if from is given, the PR is in none of those states.
+func (PR *PullRequestRepo) transition(ctx context.Context, ID string, to string, from ...string) (*PullRequest, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	tx, err := PR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, err := lockStatus(ctx, tx, ID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransition(status, to) || (len(from) > 0 && !slices.Contains(from, status)) {
+		return nil, transitionError(status, to)
+	}
+
+	builder := psql.Update("pr").
+		Set("pr_status", to).
+		Where(sq.Eq{"id": ID})
+	if to == StatusClosed {
+		builder = builder.Set("closed_at", PR.now())
+	} else {
+		builder = builder.Set("closed_at", nil)
+	}
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return PR.GetPr(ctx, ID)
+}
+
+// lockStatus locks the PR row within tx and returns its state.
+func lockStatus(ctx context.Context, tx *sql.Tx, ID string) (string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	var status string
+	q, args, _ := psql.Select("pr_status").
+		From("pr").
+		Where(sq.Eq{"id": ID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return "", errs.NotFountError
+		}
+		return "", err
+	}
+	return status, nil
+}
@@ -0,0 +1,319 @@
+package rotation
+
+import (
+	"context"
+	"database/sql"
+	"pullreq/internal/errs"
+	"pullreq/internal/user"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// DefaultPeriodDays is the length of a duty period when a rotation does not set one.
+const DefaultPeriodDays = 7
+
+// Rotation hands the duty reviewer role of a team to its members in order, one period each.
+type Rotation struct {
+	TeamName   string    `json:"team_name"`
+	Members    []string  `json:"members"`
+	PeriodDays int       `json:"period_days"`
+	StartsAt   time.Time `json:"starts_at"`
+}
+
+// Shift is one period of a rotation.
+type Shift struct {
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	ScheduledID string    `json:"scheduled_user_id"`
+	UserID      string    `json:"user_id,omitempty"` // on duty after skipping unavailable members, empty when nobody is available
+	Skipped     []string  `json:"skipped,omitempty"` // inactive or out of office
+}
+
+func (r *Rotation) period() time.Duration {
+	return time.Duration(r.PeriodDays) * 24 * time.Hour
+}
+
+// shiftIndex returns the number of the period at falls into, -1 before the rotation starts.
+func (r *Rotation) shiftIndex(at time.Time) int {
+	if at.Before(r.StartsAt) {
+		return -1
+	}
+	return int(at.Sub(r.StartsAt) / r.period())
+}
+
+// Order returns the members starting with the one scheduled at at and followed by the
+// others in rotation order, who stand in when the scheduled one is unavailable. It is
+// empty before the rotation starts.
+func (r *Rotation) Order(at time.Time) []string {
+	idx := r.shiftIndex(at)
+	if idx < 0 || len(r.Members) == 0 {
+		return nil
+	}
+	order := make([]string, 0, len(r.Members))
+	for i := range r.Members {
+		order = append(order, r.Members[(idx+i)%len(r.Members)])
+	}
+	return order
+}
+
+type RotationRepoInterface interface {
+	SetRotation(ctx context.Context, rot Rotation) (*Rotation, error)
+	DeleteRotation(ctx context.Context, teamName string) error
+	GetRotation(ctx context.Context, teamName string) (*Rotation, error)
+	RotationFor(ctx context.Context, teamID int) (*Rotation, error)
+	Schedule(ctx context.Context, teamName string, at time.Time, periods int) (*Shift, []Shift, error)
+}
+
+type RotationRepo struct {
+	DB    *sql.DB
+	Clock func() time.Time // nil means time.Now, replaced in tests
+}
+
+func (RR *RotationRepo) now() time.Time {
+	if RR.Clock == nil {
+		return time.Now()
+	}
+	return RR.Clock()
+}
+
+// SetRotation replaces the rotation of the team. Every member has to belong to the team,
+// otherwise errs.RotationMemberError is returned.
+func (RR *RotationRepo) SetRotation(ctx context.Context, rot Rotation) (*Rotation, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	if rot.PeriodDays == 0 {
+		rot.PeriodDays = DefaultPeriodDays
+	}
+
+	tx, err := RR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var teamID int
+	teamQuery, args, _ := psql.Select("id").From("teams").Where(sq.Eq{"team_name": rot.TeamName}).ToSql()
+	if err := tx.QueryRowContext(ctx, teamQuery, args...).Scan(&teamID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+
+	var members int
+	membersQuery, args, _ := psql.Select("COUNT(*)").
+		From("users").
+		Where(sq.Eq{"id": rot.Members, "team_id": teamID}).
+		ToSql()
+	if err := tx.QueryRowContext(ctx, membersQuery, args...).Scan(&members); err != nil {
+		return nil, err
+	}
+	if members != len(rot.Members) {
+		return nil, errs.RotationMemberError
+	}
+
+	upsertQuery, args, err := psql.Insert("duty_rotations").
+		Columns("team_id", "period_days", "starts_at").
+		Values(teamID, rot.PeriodDays, rot.StartsAt).
+		Suffix("ON CONFLICT (team_id) DO UPDATE SET period_days = EXCLUDED.period_days, starts_at = EXCLUDED.starts_at").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, upsertQuery, args...); err != nil {
+		return nil, err
+	}
+
+	deleteQuery, args, _ := psql.Delete("duty_rotation_members").Where(sq.Eq{"team_id": teamID}).ToSql()
+	if _, err := tx.ExecContext(ctx, deleteQuery, args...); err != nil {
+		return nil, err
+	}
+
+	insertBuilder := psql.Insert("duty_rotation_members").Columns("team_id", "position", "user_id")
+	for i, id := range rot.Members {
+		insertBuilder = insertBuilder.Values(teamID, i, id)
+	}
+	insertQuery, args, err := insertBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, insertQuery, args...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &rot, nil
+}
+
+func (RR *RotationRepo) DeleteRotation(ctx context.Context, teamName string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Delete("duty_rotations").
+		Where("team_id IN (SELECT id FROM teams WHERE team_name = ?)", teamName).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := RR.DB.ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errs.NotFountError
+	}
+	return nil
+}
+
+// GetRotation returns the rotation of the team, errs.NotFountError when it has none.
+func (RR *RotationRepo) GetRotation(ctx context.Context, teamName string) (*Rotation, error) {
+	return RR.rotation(ctx, sq.Eq{"t.team_name": teamName})
+}
+
+// RotationFor returns the rotation of the team, nil when it has none.
+func (RR *RotationRepo) RotationFor(ctx context.Context, teamID int) (*Rotation, error) {
+	rot, err := RR.rotation(ctx, sq.Eq{"t.id": teamID})
+	if err == errs.NotFountError {
+		return nil, nil
+	}
+	return rot, err
+}
+
+func (RR *RotationRepo) rotation(ctx context.Context, team sq.Eq) (*Rotation, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Select("t.team_name", "r.period_days", "r.starts_at", "COALESCE(m.user_id, '')").
+		From("duty_rotations r").
+		Join("teams t ON t.id = r.team_id").
+		LeftJoin("duty_rotation_members m ON m.team_id = r.team_id").
+		Where(team).
+		OrderBy("m.position").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := RR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rot *Rotation
+	for rows.Next() {
+		var r Rotation
+		var userID string
+		if err := rows.Scan(&r.TeamName, &r.PeriodDays, &r.StartsAt, &userID); err != nil {
+			return nil, err
+		}
+		if rot == nil {
+			r.Members = make([]string, 0)
+			rot = &r
+		}
+		if userID != "" {
+			rot.Members = append(rot.Members, userID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if rot == nil {
+		return nil, errs.NotFountError
+	}
+	return rot, nil
+}
+
+// Schedule returns the shift at the moment at and the periods following shifts. The
+// shift in progress is nil before the rotation starts, upcoming shifts then begin with
+// the first one. Members are skipped when they are inactive or out of office at the
+// start of the shift, or at at for the shift in progress. A zero at means now.
+func (RR *RotationRepo) Schedule(ctx context.Context, teamName string, at time.Time, periods int) (*Shift, []Shift, error) {
+	if at.IsZero() {
+		at = RR.now()
+	}
+	rot, err := RR.GetRotation(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upcoming := make([]Shift, 0, periods)
+	if len(rot.Members) == 0 {
+		return nil, upcoming, nil
+	}
+
+	active, err := activeUsers(ctx, RR.DB, rot.Members)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var current *Shift
+	first := rot.shiftIndex(at)
+	if first >= 0 {
+		shift, err := rot.shift(ctx, RR.DB, first, at, active)
+		if err != nil {
+			return nil, nil, err
+		}
+		current = shift
+	}
+	for i := first + 1; len(upcoming) < periods; i++ {
+		start := rot.StartsAt.Add(time.Duration(i) * rot.period())
+		shift, err := rot.shift(ctx, RR.DB, i, start, active)
+		if err != nil {
+			return nil, nil, err
+		}
+		upcoming = append(upcoming, *shift)
+	}
+	return current, upcoming, nil
+}
+
+// shift resolves the duty reviewer of period idx as of the moment at.
+func (r *Rotation) shift(ctx context.Context, db *sql.DB, idx int, at time.Time, active map[string]bool) (*Shift, error) {
+	start := r.StartsAt.Add(time.Duration(idx) * r.period())
+	shift := &Shift{
+		StartsAt:    start,
+		EndsAt:      start.Add(r.period()),
+		ScheduledID: r.Members[idx%len(r.Members)],
+	}
+
+	absent, err := user.Absent(ctx, db, r.Members, at)
+	if err != nil {
+		return nil, err
+	}
+	for i := range r.Members {
+		id := r.Members[(idx+i)%len(r.Members)]
+		if _, away := absent[id]; away || !active[id] {
+			shift.Skipped = append(shift.Skipped, id)
+			continue
+		}
+		shift.UserID = id
+		break
+	}
+	return shift, nil
+}
+
+func activeUsers(ctx context.Context, db *sql.DB, ids []string) (map[string]bool, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Select("id", "is_active").From("users").Where(sq.Eq{"id": ids}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	active := make(map[string]bool, len(ids))
+	for rows.Next() {
+		var id string
+		var isActive bool
+		if err := rows.Scan(&id, &isActive); err != nil {
+			return nil, err
+		}
+		active[id] = isActive
+	}
+	return active, rows.Err()
+}
@@ -0,0 +1,154 @@
+package rotation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"pullreq/internal/errs"
+	jsonutils "pullreq/internal/json_utils"
+	"strconv"
+	"time"
+)
+
+// Number of upcoming shifts shown by default and at most.
+const (
+	DefaultPeriods = 4
+	MaxPeriods     = 52
+)
+
+type RotationRouter struct {
+	RR RotationRepoInterface
+}
+
+type DeleteRotationRequest struct {
+	TeamName string `json:"team_name"`
+}
+
+func (rr *RotationRouter) SetRotationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req Rotation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.TeamName == "" || req.StartsAt.IsZero() {
+		http.Error(w, "team_name and starts_at are required", http.StatusBadRequest)
+		return
+	}
+	if req.PeriodDays < 0 {
+		http.Error(w, "period_days must be positive", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[string]bool, len(req.Members))
+	for _, id := range req.Members {
+		if id == "" || seen[id] {
+			http.Error(w, "members must name distinct users", http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+	}
+	if len(req.Members) == 0 {
+		http.Error(w, "members must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	rot, err := rr.RR.SetRotation(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "Team not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errs.RotationMemberError) {
+			http.Error(w, "members must belong to the team", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"rotation": rot}, http.StatusOK)
+}
+
+func (rr *RotationRouter) DeleteRotationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DeleteRotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := rr.RR.DeleteRotation(r.Context(), req.TeamName); err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "Rotation not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"deleted": req}, http.StatusOK)
+}
+
+// GetScheduleHandler answers with the rotation of a team, the duty reviewer at the
+// moment at (now by default) and the upcoming ones.
+func (rr *RotationRouter) GetScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		http.Error(w, "Missing team_name query parameter", http.StatusBadRequest)
+		return
+	}
+	var at time.Time // the repository fills in now
+	if raw := r.URL.Query().Get("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "at must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		at = parsed
+	}
+	periods := DefaultPeriods
+	if raw := r.URL.Query().Get("periods"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > MaxPeriods {
+			http.Error(w, "periods must be between 0 and 52", http.StatusBadRequest)
+			return
+		}
+		periods = parsed
+	}
+
+	rot, err := rr.RR.GetRotation(r.Context(), teamName)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "Rotation not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	current, upcoming, err := rr.RR.Schedule(r.Context(), teamName, at, periods)
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"rotation": rot,
+		"current":  current,
+		"upcoming": upcoming,
+	}
+	jsonutils.JsonResponse(w, resp, http.StatusOK)
+}
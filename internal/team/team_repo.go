@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	dbutils "pullreq/internal/db_utils"
 	"pullreq/internal/errs"
 	"pullreq/internal/user"
 
@@ -11,29 +12,119 @@ import (
 	"github.com/lib/pq"
 )
 
+// Reviewer selection strategies a team can choose from.
+const (
+	StrategyRandom      = "random"
+	StrategyRoundRobin  = "round_robin"
+	StrategyLeastLoaded = "least_loaded"
+	StrategyWeighted    = "weighted"
+)
+
+func ValidStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyRandom, StrategyRoundRobin, StrategyLeastLoaded, StrategyWeighted:
+		return true
+	}
+	return false
+}
+
 type Team struct {
 	ID       int          `json:"-"`
 	TeamName string       `json:"team_name"`
 	Members  []*user.User `json:"members"`
+	Settings TeamSettings `json:"settings"`
+}
+
+// DefaultReviewersRequired is used when a team does not set reviewers_required.
+const DefaultReviewersRequired = 2
+
+// TeamSettings holds the review assignment policy of a team.
+type TeamSettings struct {
+	ReviewerStrategy   string   `json:"reviewer_strategy"`
+	ReviewersRequired  int      `json:"reviewers_required"`
+	RequireSenior      bool     `json:"require_senior"`           // at least one reviewer has to be senior or above
+	WorkingHoursAhead  int      `json:"working_hours_ahead"`      // reviewers starting work within this many hours count as available
+	RebalanceThreshold int      `json:"rebalance_threshold"`      // open reviews spread the rebalancer tolerates, 0 turns it off
+	DutyReviewer       bool     `json:"duty_reviewer"`            // every PR gets the duty reviewer of the team rotation
+	FallbackTeams      []string `json:"fallback_teams,omitempty"` // asked in order when the team runs out of reviewers
+	FallbackTeamIDs    []int    `json:"-"`
+
+	// merge quorum
+	ApprovalsRequired       int  `json:"approvals_required"`         // approvals a PR needs before it can be merged
+	BlockOnChangesRequested bool `json:"block_on_changes_requested"` // no reviewer may still request changes
+	RequireResolvedThreads  bool `json:"require_resolved_threads"`   // every comment thread has to be resolved
+}
+
+// TeamSettingsInput is a partial update of TeamSettings, nil fields are left unchanged.
+type TeamSettingsInput struct {
+	TeamName           string    `json:"team_name"`
+	ReviewerStrategy   *string   `json:"reviewer_strategy"`
+	ReviewersRequired  *int      `json:"reviewers_required"`
+	FallbackTeams      *[]string `json:"fallback_teams"`
+	RequireSenior      *bool     `json:"require_senior"`
+	WorkingHoursAhead  *int      `json:"working_hours_ahead"`
+	RebalanceThreshold *int      `json:"rebalance_threshold"`
+	DutyReviewer       *bool     `json:"duty_reviewer"`
+
+	ApprovalsRequired       *int  `json:"approvals_required"`
+	BlockOnChangesRequested *bool `json:"block_on_changes_requested"`
+	RequireResolvedThreads  *bool `json:"require_resolved_threads"`
+}
+
+// EligibleReviewers returns the number of active members who may review a PR of
+// authorID. The author is left out whether or not they are an active member of the team.
+func (t *Team) EligibleReviewers(authorID string) int {
+	eligible := 0
+	for _, m := range t.Members {
+		if m.IsActive && m.Id != authorID {
+			eligible++
+		}
+	}
+	return eligible
+}
+
+// UnderstaffedFor reports whether a PR opened by authorID would get fewer reviewers
+// than the team requires.
+func (t *Team) UnderstaffedFor(authorID string) bool {
+	return t.EligibleReviewers(authorID) < t.Settings.ReviewersRequired
+}
+
+// Understaffed reports whether a PR of some possible author, a member or somebody
+// from another team, would get fewer reviewers than the team requires.
+func (t *Team) Understaffed() bool {
+	if t.UnderstaffedFor("") {
+		return true
+	}
+	for _, m := range t.Members {
+		if t.UnderstaffedFor(m.Id) {
+			return true
+		}
+	}
+	return false
 }
 
 type TeamRepoInterface interface {
-	AddTeam(ctx context.Context, teamName string, members []TeamMember) (*Team, error)
+	AddTeam(ctx context.Context, teamName string, members []TeamMember, settings TeamSettings) (*Team, error)
 	GetTeamWithMembers(ctx context.Context, teamName string) (*Team, error)
 	GetTeamByUserID(ctx context.Context, userID string) (int, error)
 	GetTeamMember(ctx context.Context, teamID int) ([]*user.User, error)
-	Deactivation(ctx context.Context, teanName string) error
+	GetTeamSettings(ctx context.Context, teamID int) (*TeamSettings, error)
+	UpdateSettings(ctx context.Context, input TeamSettingsInput) (*TeamSettings, error)
+	Deactivation(ctx context.Context, teanName string, reassign bool) ([]user.Reassignment, error)
 }
 
 type TeamRepo struct {
-	DB *sql.DB
-	UR user.UserRepoInterface
+	DB         *sql.DB
+	UR         user.UserRepoInterface
+	Reassigner user.ReviewReassigner // nil leaves the reviews of deactivated members in place
 }
 
-func (TR *TeamRepo) Deactivation(ctx context.Context, teamName string) error {
+// Deactivation deactivates every member of the team. With reassign set the open reviews
+// of the members move to replacements in the same transaction.
+func (TR *TeamRepo) Deactivation(ctx context.Context, teamName string, reassign bool) ([]user.Reassignment, error) {
 	tx, err := TR.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
@@ -42,19 +133,45 @@ func (TR *TeamRepo) Deactivation(ctx context.Context, teamName string) error {
 		Set("is_active", false).
 		From("teams t").
 		Where("u.team_id = t.id AND t.team_name = ?", teamName).
+		Suffix("RETURNING u.id").
 		ToSql()
 
 	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
-	if _, err = tx.ExecContext(ctx, q, args...); err != nil {
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+	userIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
-	return tx.Commit()
+	reassignments := make([]user.Reassignment, 0)
+	if reassign && TR.Reassigner != nil {
+		reassignments, err = TR.Reassigner.ReassignReviews(ctx, tx, userIDs)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return reassignments, tx.Commit()
 }
 
 func (TR *TeamRepo) GetTeamByUserID(ctx context.Context, userID string) (int, error) {
@@ -86,9 +203,16 @@ func (TR *TeamRepo) GetTeamByUserID(ctx context.Context, userID string) (int, er
 	return id, nil
 }
 
-func (TR *TeamRepo) AddTeam(ctx context.Context, teamName string, members []TeamMember) (*Team, error) {
+func (TR *TeamRepo) AddTeam(ctx context.Context, teamName string, members []TeamMember, settings TeamSettings) (*Team, error) {
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
+	if settings.ReviewerStrategy == "" {
+		settings.ReviewerStrategy = StrategyLeastLoaded
+	}
+	if settings.ReviewersRequired == 0 {
+		settings.ReviewersRequired = DefaultReviewersRequired
+	}
+
 	tx, err := TR.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -103,8 +227,8 @@ func (TR *TeamRepo) AddTeam(ctx context.Context, teamName string, members []Team
 
 	sqlTeam, argsTeam, err := psql.
 		Insert("teams").
-		Columns("team_name").
-		Values(teamName).
+		Columns("team_name", "reviewer_strategy", "reviewers_required").
+		Values(teamName, settings.ReviewerStrategy, settings.ReviewersRequired).
 		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
@@ -123,6 +247,7 @@ func (TR *TeamRepo) AddTeam(ctx context.Context, teamName string, members []Team
 		ID:       teamID,
 		TeamName: teamName,
 		Members:  make([]*user.User, 0, len(members)),
+		Settings: settings,
 	}
 
 	if len(members) > 0 {
@@ -160,6 +285,11 @@ func (TR *TeamRepo) GetTeamMember(ctx context.Context, teamID int) ([]*user.User
 			"id",
 			"username",
 			"is_active",
+			"max_open_reviews",
+			"seniority",
+			"timezone",
+			"COALESCE(work_start, '')",
+			"COALESCE(work_end, '')",
 		).
 		From("users").
 		Where(sq.Eq{"team_id": teamID})
@@ -177,15 +307,25 @@ func (TR *TeamRepo) GetTeamMember(ctx context.Context, teamID int) ([]*user.User
 
 	teamMembers := make([]*user.User, 0)
 	for rows.Next() {
-		user := &user.User{}
+		user := &user.User{TeamID: teamID}
+		var maxOpenReviews sql.NullInt64
 		err = rows.Scan(
 			&user.Id,
 			&user.Username,
 			&user.IsActive,
+			&maxOpenReviews,
+			&user.Seniority,
+			&user.Timezone,
+			&user.WorkStart,
+			&user.WorkEnd,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if maxOpenReviews.Valid {
+			v := int(maxOpenReviews.Int64)
+			user.MaxOpenReviews = &v
+		}
 		teamMembers = append(teamMembers, user)
 
 	}
@@ -199,6 +339,15 @@ func (TR *TeamRepo) GetTeamWithMembers(ctx context.Context, teamName string) (*T
 		Select(
 			"t.id",
 			"t.team_name",
+			"t.reviewer_strategy",
+			"t.reviewers_required",
+			"t.require_senior",
+			"t.working_hours_ahead",
+			"t.rebalance_threshold",
+			"t.duty_reviewer",
+			"t.approvals_required",
+			"t.block_on_changes_requested",
+			"t.require_resolved_threads",
 			"COALESCE(u.id, '-1') AS user_id",
 			"COALESCE(u.username, ' ') AS username",
 			"COALESCE(u.is_active, FALSE) AS is_active",
@@ -225,6 +374,15 @@ func (TR *TeamRepo) GetTeamWithMembers(ctx context.Context, teamName string) (*T
 		err = rows.Scan(
 			&team.ID,
 			&team.TeamName,
+			&team.Settings.ReviewerStrategy,
+			&team.Settings.ReviewersRequired,
+			&team.Settings.RequireSenior,
+			&team.Settings.WorkingHoursAhead,
+			&team.Settings.RebalanceThreshold,
+			&team.Settings.DutyReviewer,
+			&team.Settings.ApprovalsRequired,
+			&team.Settings.BlockOnChangesRequested,
+			&team.Settings.RequireResolvedThreads,
 			&user.Id,
 			&user.Username,
 			&user.IsActive,
@@ -245,5 +403,210 @@ func (TR *TeamRepo) GetTeamWithMembers(ctx context.Context, teamName string) (*T
 		return nil, sql.ErrNoRows
 	}
 
+	team.Settings.FallbackTeamIDs, team.Settings.FallbackTeams, err = getFallbackTeams(ctx, TR.DB, team.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return team, nil
 }
+
+func (TR *TeamRepo) GetTeamSettings(ctx context.Context, teamID int) (*TeamSettings, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.
+		Select("reviewer_strategy", "reviewers_required", "require_senior", "working_hours_ahead", "rebalance_threshold", "duty_reviewer",
+			"approvals_required", "block_on_changes_requested", "require_resolved_threads").
+		From("teams").
+		Where(sq.Eq{"id": teamID}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &TeamSettings{}
+	err = TR.DB.QueryRowContext(ctx, q, args...).Scan(&settings.ReviewerStrategy, &settings.ReviewersRequired, &settings.RequireSenior, &settings.WorkingHoursAhead, &settings.RebalanceThreshold, &settings.DutyReviewer,
+		&settings.ApprovalsRequired, &settings.BlockOnChangesRequested, &settings.RequireResolvedThreads)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+
+	settings.FallbackTeamIDs, settings.FallbackTeams, err = getFallbackTeams(ctx, TR.DB, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (TR *TeamRepo) UpdateSettings(ctx context.Context, input TeamSettingsInput) (*TeamSettings, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := TR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// team_name is set to itself so the statement stays valid when nothing else changes.
+	builder := psql.Update("teams").
+		Set("team_name", input.TeamName).
+		Where(sq.Eq{"team_name": input.TeamName}).
+		Suffix("RETURNING id, reviewer_strategy, reviewers_required, require_senior, working_hours_ahead, rebalance_threshold, duty_reviewer, approvals_required, block_on_changes_requested, require_resolved_threads")
+
+	if input.ReviewerStrategy != nil {
+		builder = builder.Set("reviewer_strategy", *input.ReviewerStrategy)
+	}
+	if input.ReviewersRequired != nil {
+		builder = builder.Set("reviewers_required", *input.ReviewersRequired)
+	}
+	if input.RequireSenior != nil {
+		builder = builder.Set("require_senior", *input.RequireSenior)
+	}
+	if input.WorkingHoursAhead != nil {
+		builder = builder.Set("working_hours_ahead", *input.WorkingHoursAhead)
+	}
+	if input.RebalanceThreshold != nil {
+		builder = builder.Set("rebalance_threshold", *input.RebalanceThreshold)
+	}
+	if input.DutyReviewer != nil {
+		builder = builder.Set("duty_reviewer", *input.DutyReviewer)
+	}
+	if input.ApprovalsRequired != nil {
+		builder = builder.Set("approvals_required", *input.ApprovalsRequired)
+	}
+	if input.BlockOnChangesRequested != nil {
+		builder = builder.Set("block_on_changes_requested", *input.BlockOnChangesRequested)
+	}
+	if input.RequireResolvedThreads != nil {
+		builder = builder.Set("require_resolved_threads", *input.RequireResolvedThreads)
+	}
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var teamID int
+	settings := &TeamSettings{}
+	err = tx.QueryRowContext(ctx, q, args...).Scan(&teamID, &settings.ReviewerStrategy, &settings.ReviewersRequired, &settings.RequireSenior, &settings.WorkingHoursAhead, &settings.RebalanceThreshold, &settings.DutyReviewer,
+		&settings.ApprovalsRequired, &settings.BlockOnChangesRequested, &settings.RequireResolvedThreads)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+
+	if input.FallbackTeams != nil {
+		if err := setFallbackTeams(ctx, tx, teamID, *input.FallbackTeams); err != nil {
+			return nil, err
+		}
+	}
+
+	settings.FallbackTeamIDs, settings.FallbackTeams, err = getFallbackTeams(ctx, tx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// getFallbackTeams returns the IDs and names of the fallback teams in the order they are asked.
+func getFallbackTeams(ctx context.Context, q dbutils.Queryer, teamID int) ([]int, []string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query, args, err := psql.
+		Select("t.id", "t.team_name").
+		From("team_fallbacks f").
+		Join("teams t ON t.id = f.fallback_team_id").
+		Where(sq.Eq{"f.team_id": teamID}).
+		OrderBy("f.position").
+		ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	names := make([]string, 0)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+
+	return ids, names, rows.Err()
+}
+
+// setFallbackTeams replaces the fallback teams of teamID. Unknown teams, the team
+// itself and duplicates are rejected with errs.FallbackTeamError.
+func setFallbackTeams(ctx context.Context, tx *sql.Tx, teamID int, names []string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	deleteQuery, args, err := psql.Delete("team_fallbacks").Where(sq.Eq{"team_id": teamID}).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, deleteQuery, args...); err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	lookupQuery, args, err := psql.Select("id", "team_name").From("teams").Where(sq.Eq{"team_name": names}).ToSql()
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, lookupQuery, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int, len(names))
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		ids[name] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool, len(names))
+	insertBuilder := psql.Insert("team_fallbacks").Columns("team_id", "position", "fallback_team_id")
+	for i, name := range names {
+		id, ok := ids[name]
+		if !ok || id == teamID || seen[id] {
+			return errs.FallbackTeamError
+		}
+		seen[id] = true
+		insertBuilder = insertBuilder.Values(teamID, i+1, id)
+	}
+
+	insertQuery, args, err := insertBuilder.ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, insertQuery, args...)
+	return err
+}
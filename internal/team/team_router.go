@@ -4,16 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"pullreq/internal/errs"
 	jsonutils "pullreq/internal/json_utils"
+	"pullreq/internal/user"
 )
 
 type TeamInput struct {
-	TeamName string       `json:"team_name"`
-	Members  []TeamMember `json:"members"`
+	TeamName          string       `json:"team_name"`
+	Members           []TeamMember `json:"members"`
+	ReviewerStrategy  string       `json:"reviewer_strategy,omitempty"`
+	ReviewersRequired int          `json:"reviewers_required,omitempty"`
+}
+
+type TeamAddRes struct {
+	TeamInput
+	Understaffed bool `json:"understaffed"`
 }
 
 type TeamMember struct {
@@ -41,9 +50,22 @@ type UserResp struct {
 }
 
 type TeamRes struct {
-	ID       int         `json:"-"`
-	TeamName string      `json:"team_name"`
-	Members  []*UserResp `json:"members"`
+	ID                 int         `json:"-"`
+	TeamName           string      `json:"team_name"`
+	Members            []*UserResp `json:"members"`
+	ReviewerStrategy   string      `json:"reviewer_strategy"`
+	ReviewersRequired  int         `json:"reviewers_required"`
+	FallbackTeams      []string    `json:"fallback_teams,omitempty"`
+	RequireSenior      bool        `json:"require_senior,omitempty"`
+	WorkingHoursAhead  int         `json:"working_hours_ahead,omitempty"`
+	RebalanceThreshold int         `json:"rebalance_threshold,omitempty"`
+	DutyReviewer       bool        `json:"duty_reviewer,omitempty"`
+
+	ApprovalsRequired       int  `json:"approvals_required,omitempty"`
+	BlockOnChangesRequested bool `json:"block_on_changes_requested,omitempty"`
+	RequireResolvedThreads  bool `json:"require_resolved_threads,omitempty"`
+
+	Understaffed bool `json:"understaffed"`
 }
 
 type DeactivateTeamRequest struct {
@@ -67,7 +89,13 @@ func (tr *TeamRouter) DeactivateTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := tr.TR.Deactivation(ctx, req.TeamName)
+	reassign, err := user.ReassignFlag(r)
+	if err != nil {
+		http.Error(w, "reassign must be a boolean", http.StatusBadRequest)
+		return
+	}
+
+	reassignments, err := tr.TR.Deactivation(ctx, req.TeamName, reassign)
 	if err != nil {
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
@@ -78,7 +106,12 @@ func (tr *TeamRouter) DeactivateTeam(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
-	jsonutils.JsonResponse(w, map[string]interface{}{"team": team}, http.StatusOK)
+	response := map[string]interface{}{
+		"team":          team,
+		"reassignments": reassignments,
+		"understaffed":  user.UnderstaffedPRs(reassignments),
+	}
+	jsonutils.JsonResponse(w, response, http.StatusOK)
 }
 
 func (tr *TeamRouter) HandleAddTeam(w http.ResponseWriter, r *http.Request) {
@@ -100,15 +133,28 @@ func (tr *TeamRouter) HandleAddTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = tr.TR.AddTeam(r.Context(), newTeam.TeamName, newTeam.Members)
+	if newTeam.ReviewerStrategy != "" && !ValidStrategy(newTeam.ReviewerStrategy) {
+		http.Error(w, "Unknown reviewer_strategy", http.StatusBadRequest)
+		return
+	}
+
+	if newTeam.ReviewersRequired < 0 {
+		http.Error(w, "reviewers_required must be positive", http.StatusBadRequest)
+		return
+	}
+
+	settings := TeamSettings{ReviewerStrategy: newTeam.ReviewerStrategy, ReviewersRequired: newTeam.ReviewersRequired}
+	created, err := tr.TR.AddTeam(r.Context(), newTeam.TeamName, newTeam.Members, settings)
 	if err != nil {
 		// Team already exists
 		errs.JsonCodeResp(w, errs.CodeTeamExists, fmt.Sprintf("Team '%s' already exists", newTeam.TeamName), http.StatusBadRequest)
 		return
 	}
 
+	newTeam.ReviewerStrategy = created.Settings.ReviewerStrategy
+	newTeam.ReviewersRequired = created.Settings.ReviewersRequired
 	response := map[string]interface{}{
-		"team": newTeam,
+		"team": TeamAddRes{TeamInput: newTeam, Understaffed: created.Understaffed()},
 	}
 	jsonutils.JsonResponse(w, response, http.StatusCreated)
 }
@@ -134,6 +180,17 @@ func (tr *TeamRouter) GetTeamWithMembersHandler(w http.ResponseWriter, r *http.R
 	}
 	var resTeam TeamRes
 	resTeam.TeamName = Team.TeamName
+	resTeam.ReviewerStrategy = Team.Settings.ReviewerStrategy
+	resTeam.ReviewersRequired = Team.Settings.ReviewersRequired
+	resTeam.FallbackTeams = Team.Settings.FallbackTeams
+	resTeam.RequireSenior = Team.Settings.RequireSenior
+	resTeam.WorkingHoursAhead = Team.Settings.WorkingHoursAhead
+	resTeam.RebalanceThreshold = Team.Settings.RebalanceThreshold
+	resTeam.DutyReviewer = Team.Settings.DutyReviewer
+	resTeam.ApprovalsRequired = Team.Settings.ApprovalsRequired
+	resTeam.BlockOnChangesRequested = Team.Settings.BlockOnChangesRequested
+	resTeam.RequireResolvedThreads = Team.Settings.RequireResolvedThreads
+	resTeam.Understaffed = Team.Understaffed()
 	for _, x := range Team.Members {
 		resTeam.Members = append(resTeam.Members, &UserResp{Id: x.Id, Username: x.Username, Teamname: resTeam.TeamName, Is_active: x.IsActive})
 	}
@@ -142,3 +199,62 @@ func (tr *TeamRouter) GetTeamWithMembersHandler(w http.ResponseWriter, r *http.R
 	}
 	jsonutils.JsonResponse(w, response, http.StatusOK)
 }
+
+func (tr *TeamRouter) UpdateTeamSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TeamSettingsInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.TeamName == "" {
+		http.Error(w, "team_name is required", http.StatusBadRequest)
+		return
+	}
+	if req.ReviewerStrategy != nil && !ValidStrategy(*req.ReviewerStrategy) {
+		http.Error(w, "Unknown reviewer_strategy", http.StatusBadRequest)
+		return
+	}
+	if req.ReviewersRequired != nil && *req.ReviewersRequired < 1 {
+		http.Error(w, "reviewers_required must be positive", http.StatusBadRequest)
+		return
+	}
+	if req.WorkingHoursAhead != nil && (*req.WorkingHoursAhead < 0 || *req.WorkingHoursAhead > 24) {
+		http.Error(w, "working_hours_ahead must be between 0 and 24", http.StatusBadRequest)
+		return
+	}
+	if req.RebalanceThreshold != nil && *req.RebalanceThreshold < 0 {
+		http.Error(w, "rebalance_threshold must not be negative", http.StatusBadRequest)
+		return
+	}
+	if req.ApprovalsRequired != nil && *req.ApprovalsRequired < 0 {
+		http.Error(w, "approvals_required must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := tr.TR.UpdateSettings(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "Team not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errs.FallbackTeamError) {
+			http.Error(w, "fallback_teams must name other existing teams once", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"team_name": req.TeamName,
+		"settings":  settings,
+	}
+	jsonutils.JsonResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,199 @@
+package user
+
+import (
+	"context"
+	dbutils "pullreq/internal/db_utils"
+	"pullreq/internal/errs"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// OutOfOffice is a period the user is unavailable for reviews. Reviews that would go
+// to the user in that period go to the delegate instead when one is set.
+type OutOfOffice struct {
+	ID         int       `json:"id"`
+	UserID     string    `json:"user_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	DelegateID string    `json:"delegate_id,omitempty"`
+}
+
+// Availability is the computed status of a user at a moment.
+type Availability struct {
+	UserID      string     `json:"user_id"`
+	At          time.Time  `json:"at"`
+	IsActive    bool       `json:"is_active"`
+	OutOfOffice bool       `json:"out_of_office"`
+	BackAt      *time.Time `json:"back_at,omitempty"`
+	DelegateID  string     `json:"delegate_id,omitempty"`
+	Working     bool       `json:"working"` // inside the working hours of the user
+	Available   bool       `json:"available"`
+}
+
+// AddOutOfOffice stores the period, an unknown user or delegate is reported as NotFountError.
+func (UR *UserRepo) AddOutOfOffice(ctx context.Context, ooo OutOfOffice) (*OutOfOffice, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	var delegate interface{}
+	if ooo.DelegateID != "" {
+		delegate = ooo.DelegateID
+	}
+	q, args, err := psql.Insert("out_of_office").
+		Columns("user_id", "starts_at", "ends_at", "delegate_id").
+		Values(ooo.UserID, ooo.StartsAt, ooo.EndsAt, delegate).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := UR.DB.QueryRowContext(ctx, q, args...).Scan(&ooo.ID); err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+	return &ooo, nil
+}
+
+func (UR *UserRepo) DeleteOutOfOffice(ctx context.Context, userID string, ID int) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Delete("out_of_office").
+		Where(sq.Eq{"id": ID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := UR.DB.ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errs.NotFountError
+	}
+	return nil
+}
+
+// GetOutOfOffice returns the periods of the user that have not ended at from, earliest
+// first. A zero from means now.
+func (UR *UserRepo) GetOutOfOffice(ctx context.Context, userID string, from time.Time) ([]OutOfOffice, error) {
+	if from.IsZero() {
+		from = UR.now()
+	}
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.Select("id", "user_id", "starts_at", "ends_at", "COALESCE(delegate_id, '')").
+		From("out_of_office").
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Gt{"ends_at": from}).
+		OrderBy("starts_at", "id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := UR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := make([]OutOfOffice, 0)
+	for rows.Next() {
+		var p OutOfOffice
+		if err := rows.Scan(&p.ID, &p.UserID, &p.StartsAt, &p.EndsAt, &p.DelegateID); err != nil {
+			return nil, err
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+// Availability computes whether the user can be assigned reviews at the moment at. A
+// zero at means now.
+func (UR *UserRepo) Availability(ctx context.Context, userID string, at time.Time) (*Availability, error) {
+	if at.IsZero() {
+		at = UR.now()
+	}
+	users, err := UR.GetUsers(ctx, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errs.NotFountError
+	}
+	u := users[0]
+
+	res := &Availability{
+		UserID:   u.Id,
+		At:       at,
+		IsActive: u.IsActive,
+		Working:  u.UntilWorking(at) == 0,
+	}
+
+	periods, err := UR.GetOutOfOffice(ctx, userID, at)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range periods {
+		if p.StartsAt.After(at) {
+			break
+		}
+		// overlapping periods keep the user away until the last of them ends
+		if res.BackAt == nil || p.EndsAt.After(*res.BackAt) {
+			end := p.EndsAt
+			res.BackAt = &end
+			res.DelegateID = p.DelegateID
+		}
+		res.OutOfOffice = true
+	}
+	res.Available = res.IsActive && !res.OutOfOffice
+
+	return res, nil
+}
+
+// Absent returns the users of userIDs who are out of office at the moment at, mapped
+// to their delegates. Users without a delegate map to an empty string.
+func Absent(ctx context.Context, q dbutils.Queryer, userIDs []string, at time.Time) (map[string]string, error) {
+	absent := make(map[string]string)
+	if len(userIDs) == 0 {
+		return absent, nil
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query, args, err := psql.Select("user_id", "COALESCE(delegate_id, '')").
+		From("out_of_office").
+		Where(sq.Eq{"user_id": userIDs}).
+		Where(sq.LtOrEq{"starts_at": at}).
+		Where(sq.Gt{"ends_at": at}).
+		OrderBy("user_id", "ends_at").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, delegateID string
+		if err := rows.Scan(&userID, &delegateID); err != nil {
+			return nil, err
+		}
+		// the delegate of the period ending last wins unless that period has none
+		if delegateID != "" || absent[userID] == "" {
+			absent[userID] = delegateID
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return absent, nil
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	dbutils "pullreq/internal/db_utils"
 	"pullreq/internal/errs"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	_ "github.com/lib/pq"
@@ -18,22 +23,172 @@ type PullRequestShort struct {
 }
 
 type User struct {
-	Id       string
-	Username string
-	TeamID   int
-	IsActive bool
+	Id             string   `json:"user_id"`
+	Username       string   `json:"username"`
+	TeamID         int      `json:"team_id"`
+	IsActive       bool     `json:"is_active"`
+	MaxOpenReviews *int     `json:"max_open_reviews,omitempty"` // nil means unlimited
+	Tags           []string `json:"tags,omitempty"`
+	Seniority      string   `json:"seniority,omitempty"`
+	IsAdmin        bool     `json:"is_admin"`             // may force a merge past the quorum and grant the role
+	Timezone       string   `json:"timezone,omitempty"`   // IANA name, empty means UTC
+	WorkStart      string   `json:"work_start,omitempty"` // local "15:04", empty together with WorkEnd means no working hours
+	WorkEnd        string   `json:"work_end,omitempty"`   // may be before WorkStart for a window spanning midnight
+}
+
+// Seniority levels, from the least to the most senior.
+const (
+	SeniorityJunior = "junior"
+	SeniorityMid    = "mid"
+	SenioritySenior = "senior"
+	SeniorityStaff  = "staff"
+)
+
+func ValidSeniority(level string) bool {
+	switch level {
+	case SeniorityJunior, SeniorityMid, SenioritySenior, SeniorityStaff:
+		return true
+	}
+	return false
+}
+
+// IsSenior reports whether the user is senior or above.
+func (u *User) IsSenior() bool {
+	return u.Seniority == SenioritySenior || u.Seniority == SeniorityStaff
+}
+
+// ValidWorkingHours reports whether timezone names a known location and start and end
+// are both "15:04" times or both empty.
+func ValidWorkingHours(timezone, start, end string) bool {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return false
+	}
+	if start == "" && end == "" {
+		return true
+	}
+	if _, err := time.Parse("15:04", start); err != nil {
+		return false
+	}
+	_, err := time.Parse("15:04", end)
+	return err == nil
+}
+
+// UntilWorking returns how long after now the working window of the user opens. It is
+// zero while the user is inside the window or has no working hours set.
+func (u *User) UntilWorking(now time.Time) time.Duration {
+	start, errStart := time.Parse("15:04", u.WorkStart)
+	end, errEnd := time.Parse("15:04", u.WorkEnd)
+	if errStart != nil || errEnd != nil || start.Equal(end) {
+		return 0
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	cur := local.Sub(midnight)
+	from := time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	to := time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute
+
+	inside := cur >= from && cur < to
+	if from > to {
+		inside = cur >= from || cur < to
+	}
+	if inside {
+		return 0
+	}
+	if cur < from {
+		return from - cur
+	}
+	return 24*time.Hour - cur + from
+}
+
+// NormalizeTags lowercases, trims and deduplicates tags, empty tags are dropped.
+// It is used for both user expertise tags and pull request labels.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	res := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		res = append(res, t)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// HasAnyTag reports whether the user carries at least one of the given tags.
+func (u *User) HasAnyTag(tags []string) bool {
+	for _, have := range u.Tags {
+		for _, want := range tags {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 type UserRepo struct {
-	DB *sql.DB
+	DB         *sql.DB
+	Reassigner ReviewReassigner // nil leaves the reviews of deactivated users in place
+	Clock      func() time.Time // nil means time.Now, replaced in tests
+}
+
+func (UR *UserRepo) now() time.Time {
+	if UR.Clock == nil {
+		return time.Now()
+	}
+	return UR.Clock()
+}
+
+// Reassignment is an open review moved off a deactivated user.
+type Reassignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	OldUserID     string `json:"old_user_id"`
+	NewUserID     string `json:"new_user_id,omitempty"` // empty when nobody could take the review over
+	Understaffed  bool   `json:"understaffed"`
+}
+
+// ReviewReassigner moves the reviews the users hold on open pull requests to
+// replacement reviewers within tx.
+type ReviewReassigner interface {
+	ReassignReviews(ctx context.Context, tx *sql.Tx, userIDs []string) ([]Reassignment, error)
+}
+
+// UnderstaffedPRs returns the pull requests left understaffed by the reassignments.
+func UnderstaffedPRs(reassignments []Reassignment) []string {
+	prs := make([]string, 0)
+	for _, r := range reassignments {
+		if r.Understaffed && !slices.Contains(prs, r.PullRequestID) {
+			prs = append(prs, r.PullRequestID)
+		}
+	}
+	return prs
 }
 
 type UserRepoInterface interface {
 	AddUser(ctx context.Context, tx *sql.Tx, user *User) error //Better use transaction manager from avito:)
 	UpdateUser(ctx context.Context, user User) error
-	UpdateUserActivity(ctx context.Context, userID string, isActive bool) (*User, error)
+	UpdateUserActivity(ctx context.Context, userID string, isActive, reassign bool) (*User, []Reassignment, error)
 	GetUsersPrShort(ctx context.Context, userID string) ([]PullRequestShort, error)
 	GetStatAboutUser(ctx context.Context, userID string) (int, error)
+	SetMaxOpenReviews(ctx context.Context, userID string, maxOpenReviews *int) (*User, error)
+	SetTags(ctx context.Context, userID string, tags []string) ([]string, error)
+	GetTags(ctx context.Context, userID string) ([]string, error)
+	GetUsers(ctx context.Context, userIDs []string) ([]*User, error)
+	SetSeniority(ctx context.Context, userID, level string) (*User, error)
+	SetAdmin(ctx context.Context, grantedBy, userID string, isAdmin bool) (*User, error)
+	SetWorkingHours(ctx context.Context, userID, timezone, start, end string) (*User, error)
+	AddOutOfOffice(ctx context.Context, ooo OutOfOffice) (*OutOfOffice, error)
+	DeleteOutOfOffice(ctx context.Context, userID string, ID int) error
+	GetOutOfOffice(ctx context.Context, userID string, from time.Time) ([]OutOfOffice, error)
+	Availability(ctx context.Context, userID string, at time.Time) (*Availability, error)
 }
 
 func (UR *UserRepo) GetStatAboutUser(ctx context.Context, userID string) (int, error) {
@@ -159,7 +314,10 @@ func (UR *UserRepo) UpdateUser(ctx context.Context, user User) error {
 	return nil
 }
 
-func (UR *UserRepo) UpdateUserActivity(ctx context.Context, userID string, isActive bool) (*User, error) {
+// UpdateUserActivity sets is_active of the user. When the user is deactivated and
+// reassign is set, the open reviews of the user move to replacements in the same
+// transaction.
+func (UR *UserRepo) UpdateUserActivity(ctx context.Context, userID string, isActive, reassign bool) (*User, []Reassignment, error) {
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
 	q, args, err := psql.Update("users").
@@ -167,24 +325,350 @@ func (UR *UserRepo) UpdateUserActivity(ctx context.Context, userID string, isAct
 		Where(sq.Eq{"id": userID}).
 		Suffix("RETURNING id, username, team_id, is_active").
 		ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := UR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	updatedUser := &User{}
+
+	err = tx.QueryRowContext(ctx, q, args...).Scan(
+		&updatedUser.Id,
+		&updatedUser.Username,
+		&updatedUser.TeamID,
+		&updatedUser.IsActive,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, sql.ErrNoRows
+		}
+		return nil, nil, err
+	}
+
+	reassignments := make([]Reassignment, 0)
+	if !isActive && reassign && UR.Reassigner != nil {
+		reassignments, err = UR.Reassigner.ReassignReviews(ctx, tx, []string{userID})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return updatedUser, reassignments, nil
+}
+
+func (UR *UserRepo) SetMaxOpenReviews(ctx context.Context, userID string, maxOpenReviews *int) (*User, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Update("users").
+		Set("max_open_reviews", maxOpenReviews).
+		Where(sq.Eq{"id": userID}).
+		Suffix("RETURNING id, username, team_id, is_active, max_open_reviews").
+		ToSql()
 	if err != nil {
 		return nil, err
 	}
 
 	updatedUser := &User{}
+	var max sql.NullInt64
+	err = UR.DB.QueryRowContext(ctx, q, args...).Scan(
+		&updatedUser.Id,
+		&updatedUser.Username,
+		&updatedUser.TeamID,
+		&updatedUser.IsActive,
+		&max,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+	if max.Valid {
+		v := int(max.Int64)
+		updatedUser.MaxOpenReviews = &v
+	}
+
+	return updatedUser, nil
+}
+
+func (UR *UserRepo) SetTags(ctx context.Context, userID string, tags []string) ([]string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	tags = NormalizeTags(tags)
+
+	tx, err := UR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	lockQuery, args, err := psql.Select("id").
+		From("users").
+		Where(sq.Eq{"id": userID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	if err := tx.QueryRowContext(ctx, lockQuery, args...).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+
+	deleteQuery, args, err := psql.Delete("user_tags").Where(sq.Eq{"user_id": userID}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, deleteQuery, args...); err != nil {
+		return nil, err
+	}
+
+	if len(tags) > 0 {
+		insertBuilder := psql.Insert("user_tags").Columns("user_id", "tag")
+		for _, tag := range tags {
+			insertBuilder = insertBuilder.Values(userID, tag)
+		}
+		insertQuery, args, err := insertBuilder.ToSql()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, insertQuery, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (UR *UserRepo) GetTags(ctx context.Context, userID string) ([]string, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
+	q, args, err := psql.Select("t.tag").
+		From("users u").
+		LeftJoin("user_tags t ON t.user_id = u.id").
+		Where(sq.Eq{"u.id": userID}).
+		OrderBy("t.tag").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := UR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found bool
+	tags := make([]string, 0)
+	for rows.Next() {
+		found = true
+		var tag sql.NullString
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		if tag.Valid {
+			tags = append(tags, tag.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errs.NotFountError
+	}
+
+	return tags, nil
+}
+
+func (UR *UserRepo) GetUsers(ctx context.Context, userIDs []string) ([]*User, error) {
+	users := make([]*User, 0, len(userIDs))
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q, args, err := psql.
+		Select("id", "username", "COALESCE(team_id, 0)", "is_active", "max_open_reviews", "seniority",
+			"timezone", "COALESCE(work_start, '')", "COALESCE(work_end, '')").
+		From("users").
+		Where(sq.Eq{"id": userIDs}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := UR.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := &User{}
+		var max sql.NullInt64
+		if err := rows.Scan(&u.Id, &u.Username, &u.TeamID, &u.IsActive, &max, &u.Seniority,
+			&u.Timezone, &u.WorkStart, &u.WorkEnd); err != nil {
+			return nil, err
+		}
+		if max.Valid {
+			v := int(max.Int64)
+			u.MaxOpenReviews = &v
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+func (UR *UserRepo) SetSeniority(ctx context.Context, userID, level string) (*User, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Update("users").
+		Set("seniority", level).
+		Where(sq.Eq{"id": userID}).
+		Suffix("RETURNING id, username, team_id, is_active, seniority").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	updatedUser := &User{}
 	err = UR.DB.QueryRowContext(ctx, q, args...).Scan(
 		&updatedUser.Id,
 		&updatedUser.Username,
 		&updatedUser.TeamID,
 		&updatedUser.IsActive,
+		&updatedUser.Seniority,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+
+	return updatedUser, nil
+}
+
+// SetAdmin grants or revokes the admin role of the user. Only an existing admin may
+// do so, errs.NotAdminError is returned when grantedBy is not one.
+func (UR *UserRepo) SetAdmin(ctx context.Context, grantedBy, userID string, isAdmin bool) (*User, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := UR.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := CheckAdmin(ctx, tx, grantedBy); err != nil {
+		return nil, err
+	}
+
+	q, args, err := psql.Update("users").
+		Set("is_admin", isAdmin).
+		Where(sq.Eq{"id": userID}).
+		Suffix("RETURNING id, username, team_id, is_active, is_admin").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	updatedUser := &User{}
+	err = tx.QueryRowContext(ctx, q, args...).Scan(
+		&updatedUser.Id,
+		&updatedUser.Username,
+		&updatedUser.TeamID,
+		&updatedUser.IsActive,
+		&updatedUser.IsAdmin,
 	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NotFountError
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
 
+// CheckAdmin returns errs.NotAdminError unless userID is an existing admin.
+func CheckAdmin(ctx context.Context, q dbutils.RowQueryer, userID string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query, args, err := psql.Select("is_admin").From("users").Where(sq.Eq{"id": userID}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var isAdmin bool
+	if err := q.QueryRowContext(ctx, query, args...).Scan(&isAdmin); err != nil {
+		if err == sql.ErrNoRows {
+			return errs.NotAdminError
+		}
+		return err
+	}
+	if !isAdmin {
+		return errs.NotAdminError
+	}
+	return nil
+}
+
+// SetWorkingHours stores the timezone and working hours of the user, empty start and
+// end clear the working hours.
+func (UR *UserRepo) SetWorkingHours(ctx context.Context, userID, timezone, start, end string) (*User, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	var workStart, workEnd interface{}
+	if start != "" {
+		workStart, workEnd = start, end
+	}
+	q, args, err := psql.Update("users").
+		Set("timezone", timezone).
+		Set("work_start", workStart).
+		Set("work_end", workEnd).
+		Where(sq.Eq{"id": userID}).
+		Suffix("RETURNING id, username, team_id, is_active, timezone, COALESCE(work_start, ''), COALESCE(work_end, '')").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	updatedUser := &User{}
+	err = UR.DB.QueryRowContext(ctx, q, args...).Scan(
+		&updatedUser.Id,
+		&updatedUser.Username,
+		&updatedUser.TeamID,
+		&updatedUser.IsActive,
+		&updatedUser.Timezone,
+		&updatedUser.WorkStart,
+		&updatedUser.WorkEnd,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, sql.ErrNoRows
+			return nil, errs.NotFountError
 		}
 		return nil, err
 	}
+
 	return updatedUser, nil
 }
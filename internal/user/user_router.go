@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"pullreq/internal/errs"
 	jsonutils "pullreq/internal/json_utils"
+	"strconv"
+	"time"
 )
 
 type UserRouter struct {
@@ -20,6 +22,39 @@ type UserShortInput struct {
 	IsActive bool   `json:"is_active"`
 }
 
+type TagsInput struct {
+	UserID string   `json:"user_id"`
+	Tags   []string `json:"tags"`
+}
+
+type SeniorityInput struct {
+	UserID    string `json:"user_id"`
+	Seniority string `json:"seniority"`
+}
+
+type AdminInput struct {
+	UserID    string `json:"user_id"`
+	IsAdmin   bool   `json:"is_admin"`
+	GrantedBy string `json:"granted_by"` // an existing admin
+}
+
+type DeleteOutOfOfficeInput struct {
+	UserID string `json:"user_id"`
+	ID     int    `json:"id"`
+}
+
+type WorkingHoursInput struct {
+	UserID    string `json:"user_id"`
+	Timezone  string `json:"timezone"`
+	WorkStart string `json:"work_start"`
+	WorkEnd   string `json:"work_end"`
+}
+
+type MaxOpenReviewsInput struct {
+	UserID         string `json:"user_id"`
+	MaxOpenReviews *int   `json:"max_open_reviews"` // null removes the limit
+}
+
 func (ur *UserRouter) GetStat(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -70,6 +105,16 @@ func (ur *UserRouter) GetUserReviewsHandler(w http.ResponseWriter, r *http.Reque
 
 }
 
+// ReassignFlag reads the reassign query parameter which opts out of moving the open
+// reviews of deactivated users when set to false. It defaults to true.
+func ReassignFlag(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("reassign")
+	if raw == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
 func (ur *UserRouter) RouterSetActiviry(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -90,7 +135,13 @@ func (ur *UserRouter) RouterSetActiviry(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	user, err := ur.UR.UpdateUserActivity(context.Background(), newTeam.UserID, newTeam.IsActive)
+	reassign, err := ReassignFlag(r)
+	if err != nil {
+		http.Error(w, "reassign must be a boolean", http.StatusBadRequest)
+		return
+	}
+
+	user, reassignments, err := ur.UR.UpdateUserActivity(context.Background(), newTeam.UserID, newTeam.IsActive, reassign)
 	if errors.Is(err, sql.ErrNoRows) {
 		errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
 		return
@@ -99,5 +150,317 @@ func (ur *UserRouter) RouterSetActiviry(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
+	response := map[string]interface{}{
+		"user":          user,
+		"reassignments": reassignments,
+		"understaffed":  UnderstaffedPRs(reassignments),
+	}
+	jsonutils.JsonResponse(w, response, http.StatusOK)
+}
+
+func (ur *UserRouter) RouterSetMaxOpenReviews(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input MaxOpenReviewsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if input.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if input.MaxOpenReviews != nil && *input.MaxOpenReviews < 0 {
+		http.Error(w, "max_open_reviews must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	user, err := ur.UR.SetMaxOpenReviews(r.Context(), input.UserID, input.MaxOpenReviews)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
 	jsonutils.JsonResponse(w, map[string]interface{}{"user": user}, http.StatusOK)
 }
+
+func (ur *UserRouter) RouterSetTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input TagsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if input.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	tags, err := ur.UR.SetTags(r.Context(), input.UserID, input.Tags)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"user_id": input.UserID, "tags": tags}, http.StatusOK)
+}
+
+func (ur *UserRouter) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "Missing user_id query parameter", http.StatusBadRequest)
+		return
+	}
+
+	tags, err := ur.UR.GetTags(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"user_id": userID, "tags": tags}, http.StatusOK)
+}
+
+func (ur *UserRouter) RouterSetSeniority(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input SeniorityInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if input.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if !ValidSeniority(input.Seniority) {
+		http.Error(w, "seniority must be one of junior, mid, senior, staff", http.StatusBadRequest)
+		return
+	}
+
+	user, err := ur.UR.SetSeniority(r.Context(), input.UserID, input.Seniority)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"user": user}, http.StatusOK)
+}
+
+func (ur *UserRouter) RouterSetAdmin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input AdminInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if input.UserID == "" || input.GrantedBy == "" {
+		http.Error(w, "user_id and granted_by are required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := ur.UR.SetAdmin(r.Context(), input.GrantedBy, input.UserID, input.IsAdmin)
+	if err != nil {
+		if errors.Is(err, errs.NotAdminError) {
+			errs.JsonCodeResp(w, errs.CodeNotAdmin, "only an admin can grant or revoke the admin role", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"user": user}, http.StatusOK)
+}
+
+func (ur *UserRouter) RouterSetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input WorkingHoursInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if input.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if input.Timezone == "" {
+		input.Timezone = "UTC"
+	}
+	if !ValidWorkingHours(input.Timezone, input.WorkStart, input.WorkEnd) {
+		http.Error(w, "timezone must be an IANA name and work_start, work_end HH:MM times", http.StatusBadRequest)
+		return
+	}
+
+	user, err := ur.UR.SetWorkingHours(r.Context(), input.UserID, input.Timezone, input.WorkStart, input.WorkEnd)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"user": user}, http.StatusOK)
+}
+
+func (ur *UserRouter) RouterAddOutOfOffice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input OutOfOffice
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if input.UserID == "" || input.DelegateID == input.UserID {
+		http.Error(w, "user_id is required and cannot be its own delegate", http.StatusBadRequest)
+		return
+	}
+	if !input.EndsAt.After(input.StartsAt) {
+		http.Error(w, "ends_at must be after starts_at", http.StatusBadRequest)
+		return
+	}
+
+	ooo, err := ur.UR.AddOutOfOffice(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User or delegate not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"out_of_office": ooo}, http.StatusCreated)
+}
+
+func (ur *UserRouter) RouterDeleteOutOfOffice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input DeleteOutOfOfficeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := ur.UR.DeleteOutOfOffice(r.Context(), input.UserID, input.ID); err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "Out of office period not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"deleted": input}, http.StatusOK)
+}
+
+// GetOutOfOfficeHandler lists the periods of the user that have not ended yet.
+func (ur *UserRouter) GetOutOfOfficeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "Missing user_id query parameter", http.StatusBadRequest)
+		return
+	}
+
+	periods, err := ur.UR.GetOutOfOffice(r.Context(), userID, time.Time{})
+	if err != nil {
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"user_id": userID, "out_of_office": periods}, http.StatusOK)
+}
+
+// AvailabilityHandler reports the status of the user at the RFC 3339 timestamp in the
+// at query parameter, or now when it is missing.
+func (ur *UserRouter) AvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "Missing user_id query parameter", http.StatusBadRequest)
+		return
+	}
+	var at time.Time // the repository fills in now
+	if raw := r.URL.Query().Get("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "at must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		at = parsed
+	}
+
+	availability, err := ur.UR.Availability(r.Context(), userID, at)
+	if err != nil {
+		if errors.Is(err, errs.NotFountError) {
+			errs.JsonCodeResp(w, errs.CodeNotFound, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	jsonutils.JsonResponse(w, map[string]interface{}{"availability": availability}, http.StatusOK)
+}
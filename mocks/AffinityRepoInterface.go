@@ -0,0 +1,125 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package routermocks
+
+import (
+	context "context"
+	affinity "pullreq/internal/affinity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AffinityRepoInterface is an autogenerated mock type for the AffinityRepoInterface type
+type AffinityRepoInterface struct {
+	mock.Mock
+}
+
+// DeleteRule provides a mock function with given fields: ctx, authorID, reviewerID
+func (_m *AffinityRepoInterface) DeleteRule(ctx context.Context, authorID string, reviewerID string) error {
+	ret := _m.Called(ctx, authorID, reviewerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRule")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, authorID, reviewerID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetRules provides a mock function with given fields: ctx, authorID
+func (_m *AffinityRepoInterface) GetRules(ctx context.Context, authorID string) ([]affinity.Rule, error) {
+	ret := _m.Called(ctx, authorID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRules")
+	}
+
+	var r0 []affinity.Rule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]affinity.Rule, error)); ok {
+		return rf(ctx, authorID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []affinity.Rule); ok {
+		r0 = rf(ctx, authorID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]affinity.Rule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, authorID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RulesFor provides a mock function with given fields: ctx, authorID
+func (_m *AffinityRepoInterface) RulesFor(ctx context.Context, authorID string) (*affinity.RuleSet, error) {
+	ret := _m.Called(ctx, authorID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RulesFor")
+	}
+
+	var r0 *affinity.RuleSet
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*affinity.RuleSet, error)); ok {
+		return rf(ctx, authorID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *affinity.RuleSet); ok {
+		r0 = rf(ctx, authorID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*affinity.RuleSet)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, authorID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRule provides a mock function with given fields: ctx, rule
+func (_m *AffinityRepoInterface) SetRule(ctx context.Context, rule affinity.Rule) error {
+	ret := _m.Called(ctx, rule)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRule")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, affinity.Rule) error); ok {
+		r0 = rf(ctx, rule)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewAffinityRepoInterface creates a new instance of AffinityRepoInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAffinityRepoInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AffinityRepoInterface {
+	mock := &AffinityRepoInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
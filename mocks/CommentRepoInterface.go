@@ -0,0 +1,149 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package routermocks
+
+import (
+	context "context"
+	comment "pullreq/internal/comment"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// CommentRepoInterface is an autogenerated mock type for the CommentRepoInterface type
+type CommentRepoInterface struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: ctx, req
+func (_m *CommentRepoInterface) Add(ctx context.Context, req comment.AddRequest) (*comment.Comment, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Add")
+	}
+
+	var r0 *comment.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, comment.AddRequest) (*comment.Comment, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, comment.AddRequest) *comment.Comment); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*comment.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, comment.AddRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Edit provides a mock function with given fields: ctx, req
+func (_m *CommentRepoInterface) Edit(ctx context.Context, req comment.EditRequest) (*comment.Comment, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Edit")
+	}
+
+	var r0 *comment.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, comment.EditRequest) (*comment.Comment, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, comment.EditRequest) *comment.Comment); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*comment.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, comment.EditRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, prID, unresolvedOnly
+func (_m *CommentRepoInterface) List(ctx context.Context, prID string, unresolvedOnly bool) ([]comment.Thread, error) {
+	ret := _m.Called(ctx, prID, unresolvedOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []comment.Thread
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) ([]comment.Thread, error)); ok {
+		return rf(ctx, prID, unresolvedOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) []comment.Thread); ok {
+		r0 = rf(ctx, prID, unresolvedOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]comment.Thread)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, prID, unresolvedOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Resolve provides a mock function with given fields: ctx, req, resolved
+func (_m *CommentRepoInterface) Resolve(ctx context.Context, req comment.ResolveRequest, resolved bool) (*comment.Thread, error) {
+	ret := _m.Called(ctx, req, resolved)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Resolve")
+	}
+
+	var r0 *comment.Thread
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, comment.ResolveRequest, bool) (*comment.Thread, error)); ok {
+		return rf(ctx, req, resolved)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, comment.ResolveRequest, bool) *comment.Thread); ok {
+		r0 = rf(ctx, req, resolved)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*comment.Thread)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, comment.ResolveRequest, bool) error); ok {
+		r1 = rf(ctx, req, resolved)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCommentRepoInterface creates a new instance of CommentRepoInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCommentRepoInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CommentRepoInterface {
+	mock := &CommentRepoInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,119 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package routermocks
+
+import (
+	context "context"
+	ownership "pullreq/internal/ownership"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OwnershipRepoInterface is an autogenerated mock type for the OwnershipRepoInterface type
+type OwnershipRepoInterface struct {
+	mock.Mock
+}
+
+// GetRules provides a mock function with given fields: ctx
+func (_m *OwnershipRepoInterface) GetRules(ctx context.Context) ([]ownership.Rule, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRules")
+	}
+
+	var r0 []ownership.Rule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]ownership.Rule, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []ownership.Rule); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ownership.Rule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportRules provides a mock function with given fields: ctx, rules
+func (_m *OwnershipRepoInterface) ImportRules(ctx context.Context, rules []ownership.Rule) (*ownership.ImportResult, error) {
+	ret := _m.Called(ctx, rules)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportRules")
+	}
+
+	var r0 *ownership.ImportResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []ownership.Rule) (*ownership.ImportResult, error)); ok {
+		return rf(ctx, rules)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []ownership.Rule) *ownership.ImportResult); ok {
+		r0 = rf(ctx, rules)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ownership.ImportResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []ownership.Rule) error); ok {
+		r1 = rf(ctx, rules)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OwnersForPaths provides a mock function with given fields: ctx, paths
+func (_m *OwnershipRepoInterface) OwnersForPaths(ctx context.Context, paths []string) ([]string, error) {
+	ret := _m.Called(ctx, paths)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OwnersForPaths")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]string, error)); ok {
+		return rf(ctx, paths)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []string); ok {
+		r0 = rf(ctx, paths)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, paths)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOwnershipRepoInterface creates a new instance of OwnershipRepoInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnershipRepoInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnershipRepoInterface {
+	mock := &OwnershipRepoInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -14,9 +14,9 @@ type PullRequestRepoInterface struct {
 	mock.Mock
 }
 
-// AssignedReviewer provides a mock function with given fields: ctx, PrID, UserID
-func (_m *PullRequestRepoInterface) AssignedReviewer(ctx context.Context, PrID string, UserID string) (*pr.PullRequest, string, error) {
-	ret := _m.Called(ctx, PrID, UserID)
+// AssignedReviewer provides a mock function with given fields: ctx, req
+func (_m *PullRequestRepoInterface) AssignedReviewer(ctx context.Context, req pr.ReassignRequest) (*pr.PullRequest, string, error) {
+	ret := _m.Called(ctx, req)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AssignedReviewer")
@@ -25,25 +25,25 @@ func (_m *PullRequestRepoInterface) AssignedReviewer(ctx context.Context, PrID s
 	var r0 *pr.PullRequest
 	var r1 string
 	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*pr.PullRequest, string, error)); ok {
-		return rf(ctx, PrID, UserID)
+	if rf, ok := ret.Get(0).(func(context.Context, pr.ReassignRequest) (*pr.PullRequest, string, error)); ok {
+		return rf(ctx, req)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) *pr.PullRequest); ok {
-		r0 = rf(ctx, PrID, UserID)
+	if rf, ok := ret.Get(0).(func(context.Context, pr.ReassignRequest) *pr.PullRequest); ok {
+		r0 = rf(ctx, req)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*pr.PullRequest)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) string); ok {
-		r1 = rf(ctx, PrID, UserID)
+	if rf, ok := ret.Get(1).(func(context.Context, pr.ReassignRequest) string); ok {
+		r1 = rf(ctx, req)
 	} else {
 		r1 = ret.Get(1).(string)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, string, string) error); ok {
-		r2 = rf(ctx, PrID, UserID)
+	if rf, ok := ret.Get(2).(func(context.Context, pr.ReassignRequest) error); ok {
+		r2 = rf(ctx, req)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -69,6 +69,36 @@ func (_m *PullRequestRepoInterface) Check(ctx context.Context, ID string) error
 	return r0
 }
 
+// Close provides a mock function with given fields: ctx, ID
+func (_m *PullRequestRepoInterface) Close(ctx context.Context, ID string) (*pr.PullRequest, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 *pr.PullRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*pr.PullRequest, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *pr.PullRequest); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.PullRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, req
 func (_m *PullRequestRepoInterface) Create(ctx context.Context, req pr.CreatePullRequestRequest) (*pr.PullRequest, error) {
 	ret := _m.Called(ctx, req)
@@ -99,6 +129,36 @@ func (_m *PullRequestRepoInterface) Create(ctx context.Context, req pr.CreatePul
 	return r0, r1
 }
 
+// Explain provides a mock function with given fields: ctx, ID
+func (_m *PullRequestRepoInterface) Explain(ctx context.Context, ID string) ([]pr.Decision, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Explain")
+	}
+
+	var r0 []pr.Decision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]pr.Decision, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []pr.Decision); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pr.Decision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPr provides a mock function with given fields: ctx, ID
 func (_m *PullRequestRepoInterface) GetPr(ctx context.Context, ID string) (*pr.PullRequest, error) {
 	ret := _m.Called(ctx, ID)
@@ -129,14 +189,134 @@ func (_m *PullRequestRepoInterface) GetPr(ctx context.Context, ID string) (*pr.P
 	return r0, r1
 }
 
-// Merged provides a mock function with given fields: ctx, ID
-func (_m *PullRequestRepoInterface) Merged(ctx context.Context, ID string) (*pr.PullRequest, error) {
+// History provides a mock function with given fields: ctx, ID
+func (_m *PullRequestRepoInterface) History(ctx context.Context, ID string) ([]pr.Edit, error) {
 	ret := _m.Called(ctx, ID)
 
+	if len(ret) == 0 {
+		panic("no return value specified for History")
+	}
+
+	var r0 []pr.Edit
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]pr.Edit, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []pr.Edit); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pr.Edit)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, filter
+func (_m *PullRequestRepoInterface) List(ctx context.Context, filter pr.ListFilter) (*pr.ListPage, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 *pr.ListPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pr.ListFilter) (*pr.ListPage, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pr.ListFilter) *pr.ListPage); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.ListPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pr.ListFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Merged provides a mock function with given fields: ctx, req
+func (_m *PullRequestRepoInterface) Merged(ctx context.Context, req pr.MergeRequest) (*pr.PullRequest, error) {
+	ret := _m.Called(ctx, req)
+
 	if len(ret) == 0 {
 		panic("no return value specified for Merged")
 	}
 
+	var r0 *pr.PullRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pr.MergeRequest) (*pr.PullRequest, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pr.MergeRequest) *pr.PullRequest); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.PullRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pr.MergeRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Preview provides a mock function with given fields: ctx, req
+func (_m *PullRequestRepoInterface) Preview(ctx context.Context, req pr.CreatePullRequestRequest) (*pr.Preview, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Preview")
+	}
+
+	var r0 *pr.Preview
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pr.CreatePullRequestRequest) (*pr.Preview, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pr.CreatePullRequestRequest) *pr.Preview); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.Preview)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pr.CreatePullRequestRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReadyForReview provides a mock function with given fields: ctx, ID
+func (_m *PullRequestRepoInterface) ReadyForReview(ctx context.Context, ID string) (*pr.PullRequest, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReadyForReview")
+	}
+
 	var r0 *pr.PullRequest
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, string) (*pr.PullRequest, error)); ok {
@@ -159,6 +339,156 @@ func (_m *PullRequestRepoInterface) Merged(ctx context.Context, ID string) (*pr.
 	return r0, r1
 }
 
+// Rebalance provides a mock function with given fields: ctx, teamName, dryRun
+func (_m *PullRequestRepoInterface) Rebalance(ctx context.Context, teamName string, dryRun bool) ([]pr.Move, error) {
+	ret := _m.Called(ctx, teamName, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rebalance")
+	}
+
+	var r0 []pr.Move
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) ([]pr.Move, error)); ok {
+		return rf(ctx, teamName, dryRun)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) []pr.Move); ok {
+		r0 = rf(ctx, teamName, dryRun)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pr.Move)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, teamName, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reopen provides a mock function with given fields: ctx, ID
+func (_m *PullRequestRepoInterface) Reopen(ctx context.Context, ID string) (*pr.PullRequest, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reopen")
+	}
+
+	var r0 *pr.PullRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*pr.PullRequest, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *pr.PullRequest); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.PullRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StartReview provides a mock function with given fields: ctx, prID, userID
+func (_m *PullRequestRepoInterface) StartReview(ctx context.Context, prID string, userID string) (*pr.Review, error) {
+	ret := _m.Called(ctx, prID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StartReview")
+	}
+
+	var r0 *pr.Review
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*pr.Review, error)); ok {
+		return rf(ctx, prID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *pr.Review); ok {
+		r0 = rf(ctx, prID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.Review)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, prID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SubmitReview provides a mock function with given fields: ctx, req
+func (_m *PullRequestRepoInterface) SubmitReview(ctx context.Context, req pr.SubmitReviewRequest) (*pr.PullRequest, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SubmitReview")
+	}
+
+	var r0 *pr.PullRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pr.SubmitReviewRequest) (*pr.PullRequest, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pr.SubmitReviewRequest) *pr.PullRequest); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.PullRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pr.SubmitReviewRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, req
+func (_m *PullRequestRepoInterface) Update(ctx context.Context, req pr.UpdateRequest) (*pr.PullRequest, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *pr.PullRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pr.UpdateRequest) (*pr.PullRequest, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pr.UpdateRequest) *pr.PullRequest); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*pr.PullRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pr.UpdateRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewPullRequestRepoInterface creates a new instance of PullRequestRepoInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewPullRequestRepoInterface(t interface {
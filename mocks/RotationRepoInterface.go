@@ -0,0 +1,178 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package routermocks
+
+import (
+	context "context"
+	rotation "pullreq/internal/rotation"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// RotationRepoInterface is an autogenerated mock type for the RotationRepoInterface type
+type RotationRepoInterface struct {
+	mock.Mock
+}
+
+// DeleteRotation provides a mock function with given fields: ctx, teamName
+func (_m *RotationRepoInterface) DeleteRotation(ctx context.Context, teamName string) error {
+	ret := _m.Called(ctx, teamName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRotation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, teamName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetRotation provides a mock function with given fields: ctx, teamName
+func (_m *RotationRepoInterface) GetRotation(ctx context.Context, teamName string) (*rotation.Rotation, error) {
+	ret := _m.Called(ctx, teamName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRotation")
+	}
+
+	var r0 *rotation.Rotation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*rotation.Rotation, error)); ok {
+		return rf(ctx, teamName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *rotation.Rotation); ok {
+		r0 = rf(ctx, teamName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*rotation.Rotation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, teamName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RotationFor provides a mock function with given fields: ctx, teamID
+func (_m *RotationRepoInterface) RotationFor(ctx context.Context, teamID int) (*rotation.Rotation, error) {
+	ret := _m.Called(ctx, teamID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotationFor")
+	}
+
+	var r0 *rotation.Rotation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*rotation.Rotation, error)); ok {
+		return rf(ctx, teamID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *rotation.Rotation); ok {
+		r0 = rf(ctx, teamID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*rotation.Rotation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, teamID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Schedule provides a mock function with given fields: ctx, teamName, at, periods
+func (_m *RotationRepoInterface) Schedule(ctx context.Context, teamName string, at time.Time, periods int) (*rotation.Shift, []rotation.Shift, error) {
+	ret := _m.Called(ctx, teamName, at, periods)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Schedule")
+	}
+
+	var r0 *rotation.Shift
+	var r1 []rotation.Shift
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, int) (*rotation.Shift, []rotation.Shift, error)); ok {
+		return rf(ctx, teamName, at, periods)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, int) *rotation.Shift); ok {
+		r0 = rf(ctx, teamName, at, periods)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*rotation.Shift)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, int) []rotation.Shift); ok {
+		r1 = rf(ctx, teamName, at, periods)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]rotation.Shift)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, time.Time, int) error); ok {
+		r2 = rf(ctx, teamName, at, periods)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SetRotation provides a mock function with given fields: ctx, rot
+func (_m *RotationRepoInterface) SetRotation(ctx context.Context, rot rotation.Rotation) (*rotation.Rotation, error) {
+	ret := _m.Called(ctx, rot)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRotation")
+	}
+
+	var r0 *rotation.Rotation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, rotation.Rotation) (*rotation.Rotation, error)); ok {
+		return rf(ctx, rot)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, rotation.Rotation) *rotation.Rotation); ok {
+		r0 = rf(ctx, rot)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*rotation.Rotation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, rotation.Rotation) error); ok {
+		r1 = rf(ctx, rot)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewRotationRepoInterface creates a new instance of RotationRepoInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRotationRepoInterface(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RotationRepoInterface {
+	mock := &RotationRepoInterface{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -16,9 +16,9 @@ type TeamRepoInterface struct {
 	mock.Mock
 }
 
-// AddTeam provides a mock function with given fields: ctx, teamName, members
-func (_m *TeamRepoInterface) AddTeam(ctx context.Context, teamName string, members []team.TeamMember) (*team.Team, error) {
-	ret := _m.Called(ctx, teamName, members)
+// AddTeam provides a mock function with given fields: ctx, teamName, members, settings
+func (_m *TeamRepoInterface) AddTeam(ctx context.Context, teamName string, members []team.TeamMember, settings team.TeamSettings) (*team.Team, error) {
+	ret := _m.Called(ctx, teamName, members, settings)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AddTeam")
@@ -26,19 +26,19 @@ func (_m *TeamRepoInterface) AddTeam(ctx context.Context, teamName string, membe
 
 	var r0 *team.Team
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, []team.TeamMember) (*team.Team, error)); ok {
-		return rf(ctx, teamName, members)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []team.TeamMember, team.TeamSettings) (*team.Team, error)); ok {
+		return rf(ctx, teamName, members, settings)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, []team.TeamMember) *team.Team); ok {
-		r0 = rf(ctx, teamName, members)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []team.TeamMember, team.TeamSettings) *team.Team); ok {
+		r0 = rf(ctx, teamName, members, settings)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*team.Team)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, []team.TeamMember) error); ok {
-		r1 = rf(ctx, teamName, members)
+	if rf, ok := ret.Get(1).(func(context.Context, string, []team.TeamMember, team.TeamSettings) error); ok {
+		r1 = rf(ctx, teamName, members, settings)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -46,22 +46,34 @@ func (_m *TeamRepoInterface) AddTeam(ctx context.Context, teamName string, membe
 	return r0, r1
 }
 
-// Deactivation provides a mock function with given fields: ctx, teanName
-func (_m *TeamRepoInterface) Deactivation(ctx context.Context, teanName string) error {
-	ret := _m.Called(ctx, teanName)
+// Deactivation provides a mock function with given fields: ctx, teanName, reassign
+func (_m *TeamRepoInterface) Deactivation(ctx context.Context, teanName string, reassign bool) ([]user.Reassignment, error) {
+	ret := _m.Called(ctx, teanName, reassign)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Deactivation")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, teanName)
+	var r0 []user.Reassignment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) ([]user.Reassignment, error)); ok {
+		return rf(ctx, teanName, reassign)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) []user.Reassignment); ok {
+		r0 = rf(ctx, teanName, reassign)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]user.Reassignment)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, teanName, reassign)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetTeamByUserID provides a mock function with given fields: ctx, userID
@@ -122,6 +134,36 @@ func (_m *TeamRepoInterface) GetTeamMember(ctx context.Context, teamID int) ([]*
 	return r0, r1
 }
 
+// GetTeamSettings provides a mock function with given fields: ctx, teamID
+func (_m *TeamRepoInterface) GetTeamSettings(ctx context.Context, teamID int) (*team.TeamSettings, error) {
+	ret := _m.Called(ctx, teamID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTeamSettings")
+	}
+
+	var r0 *team.TeamSettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*team.TeamSettings, error)); ok {
+		return rf(ctx, teamID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *team.TeamSettings); ok {
+		r0 = rf(ctx, teamID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*team.TeamSettings)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, teamID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTeamWithMembers provides a mock function with given fields: ctx, teamName
 func (_m *TeamRepoInterface) GetTeamWithMembers(ctx context.Context, teamName string) (*team.Team, error) {
 	ret := _m.Called(ctx, teamName)
@@ -152,6 +194,36 @@ func (_m *TeamRepoInterface) GetTeamWithMembers(ctx context.Context, teamName st
 	return r0, r1
 }
 
+// UpdateSettings provides a mock function with given fields: ctx, input
+func (_m *TeamRepoInterface) UpdateSettings(ctx context.Context, input team.TeamSettingsInput) (*team.TeamSettings, error) {
+	ret := _m.Called(ctx, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSettings")
+	}
+
+	var r0 *team.TeamSettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, team.TeamSettingsInput) (*team.TeamSettings, error)); ok {
+		return rf(ctx, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, team.TeamSettingsInput) *team.TeamSettings); ok {
+		r0 = rf(ctx, input)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*team.TeamSettings)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, team.TeamSettingsInput) error); ok {
+		r1 = rf(ctx, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewTeamRepoInterface creates a new instance of TeamRepoInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewTeamRepoInterface(t interface {
@@ -8,6 +8,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	time "time"
+
 	user "pullreq/internal/user"
 )
 
@@ -16,6 +18,36 @@ type UserRepoInterface struct {
 	mock.Mock
 }
 
+// AddOutOfOffice provides a mock function with given fields: ctx, ooo
+func (_m *UserRepoInterface) AddOutOfOffice(ctx context.Context, ooo user.OutOfOffice) (*user.OutOfOffice, error) {
+	ret := _m.Called(ctx, ooo)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddOutOfOffice")
+	}
+
+	var r0 *user.OutOfOffice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, user.OutOfOffice) (*user.OutOfOffice, error)); ok {
+		return rf(ctx, ooo)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, user.OutOfOffice) *user.OutOfOffice); ok {
+		r0 = rf(ctx, ooo)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.OutOfOffice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, user.OutOfOffice) error); ok {
+		r1 = rf(ctx, ooo)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddUser provides a mock function with given fields: ctx, tx, _a2
 func (_m *UserRepoInterface) AddUser(ctx context.Context, tx *sql.Tx, _a2 *user.User) error {
 	ret := _m.Called(ctx, tx, _a2)
@@ -34,6 +66,84 @@ func (_m *UserRepoInterface) AddUser(ctx context.Context, tx *sql.Tx, _a2 *user.
 	return r0
 }
 
+// Availability provides a mock function with given fields: ctx, userID, at
+func (_m *UserRepoInterface) Availability(ctx context.Context, userID string, at time.Time) (*user.Availability, error) {
+	ret := _m.Called(ctx, userID, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Availability")
+	}
+
+	var r0 *user.Availability
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (*user.Availability, error)); ok {
+		return rf(ctx, userID, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) *user.Availability); ok {
+		r0 = rf(ctx, userID, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.Availability)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, userID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteOutOfOffice provides a mock function with given fields: ctx, userID, ID
+func (_m *UserRepoInterface) DeleteOutOfOffice(ctx context.Context, userID string, ID int) error {
+	ret := _m.Called(ctx, userID, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOutOfOffice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) error); ok {
+		r0 = rf(ctx, userID, ID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetOutOfOffice provides a mock function with given fields: ctx, userID, from
+func (_m *UserRepoInterface) GetOutOfOffice(ctx context.Context, userID string, from time.Time) ([]user.OutOfOffice, error) {
+	ret := _m.Called(ctx, userID, from)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOutOfOffice")
+	}
+
+	var r0 []user.OutOfOffice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) ([]user.OutOfOffice, error)); ok {
+		return rf(ctx, userID, from)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) []user.OutOfOffice); ok {
+		r0 = rf(ctx, userID, from)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]user.OutOfOffice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, userID, from)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetStatAboutUser provides a mock function with given fields: ctx, userID
 func (_m *UserRepoInterface) GetStatAboutUser(ctx context.Context, userID string) (int, error) {
 	ret := _m.Called(ctx, userID)
@@ -62,6 +172,66 @@ func (_m *UserRepoInterface) GetStatAboutUser(ctx context.Context, userID string
 	return r0, r1
 }
 
+// GetTags provides a mock function with given fields: ctx, userID
+func (_m *UserRepoInterface) GetTags(ctx context.Context, userID string) ([]string, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTags")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsers provides a mock function with given fields: ctx, userIDs
+func (_m *UserRepoInterface) GetUsers(ctx context.Context, userIDs []string) ([]*user.User, error) {
+	ret := _m.Called(ctx, userIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsers")
+	}
+
+	var r0 []*user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*user.User, error)); ok {
+		return rf(ctx, userIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*user.User); ok {
+		r0 = rf(ctx, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, userIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUsersPrShort provides a mock function with given fields: ctx, userID
 func (_m *UserRepoInterface) GetUsersPrShort(ctx context.Context, userID string) ([]user.PullRequestShort, error) {
 	ret := _m.Called(ctx, userID)
@@ -92,6 +262,156 @@ func (_m *UserRepoInterface) GetUsersPrShort(ctx context.Context, userID string)
 	return r0, r1
 }
 
+// SetAdmin provides a mock function with given fields: ctx, grantedBy, userID, isAdmin
+func (_m *UserRepoInterface) SetAdmin(ctx context.Context, grantedBy string, userID string, isAdmin bool) (*user.User, error) {
+	ret := _m.Called(ctx, grantedBy, userID, isAdmin)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetAdmin")
+	}
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) (*user.User, error)); ok {
+		return rf(ctx, grantedBy, userID, isAdmin)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) *user.User); ok {
+		r0 = rf(ctx, grantedBy, userID, isAdmin)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, bool) error); ok {
+		r1 = rf(ctx, grantedBy, userID, isAdmin)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetMaxOpenReviews provides a mock function with given fields: ctx, userID, maxOpenReviews
+func (_m *UserRepoInterface) SetMaxOpenReviews(ctx context.Context, userID string, maxOpenReviews *int) (*user.User, error) {
+	ret := _m.Called(ctx, userID, maxOpenReviews)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMaxOpenReviews")
+	}
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *int) (*user.User, error)); ok {
+		return rf(ctx, userID, maxOpenReviews)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *int) *user.User); ok {
+		r0 = rf(ctx, userID, maxOpenReviews)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *int) error); ok {
+		r1 = rf(ctx, userID, maxOpenReviews)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetSeniority provides a mock function with given fields: ctx, userID, level
+func (_m *UserRepoInterface) SetSeniority(ctx context.Context, userID string, level string) (*user.User, error) {
+	ret := _m.Called(ctx, userID, level)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSeniority")
+	}
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*user.User, error)); ok {
+		return rf(ctx, userID, level)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *user.User); ok {
+		r0 = rf(ctx, userID, level)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, level)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetTags provides a mock function with given fields: ctx, userID, tags
+func (_m *UserRepoInterface) SetTags(ctx context.Context, userID string, tags []string) ([]string, error) {
+	ret := _m.Called(ctx, userID, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTags")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) ([]string, error)); ok {
+		return rf(ctx, userID, tags)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) []string); ok {
+		r0 = rf(ctx, userID, tags)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, userID, tags)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetWorkingHours provides a mock function with given fields: ctx, userID, timezone, start, end
+func (_m *UserRepoInterface) SetWorkingHours(ctx context.Context, userID string, timezone string, start string, end string) (*user.User, error) {
+	ret := _m.Called(ctx, userID, timezone, start, end)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetWorkingHours")
+	}
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (*user.User, error)); ok {
+		return rf(ctx, userID, timezone, start, end)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *user.User); ok {
+		r0 = rf(ctx, userID, timezone, start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, userID, timezone, start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateUser provides a mock function with given fields: ctx, _a1
 func (_m *UserRepoInterface) UpdateUser(ctx context.Context, _a1 user.User) error {
 	ret := _m.Called(ctx, _a1)
@@ -110,34 +430,43 @@ func (_m *UserRepoInterface) UpdateUser(ctx context.Context, _a1 user.User) erro
 	return r0
 }
 
-// UpdateUserActivity provides a mock function with given fields: ctx, userID, isActive
-func (_m *UserRepoInterface) UpdateUserActivity(ctx context.Context, userID string, isActive bool) (*user.User, error) {
-	ret := _m.Called(ctx, userID, isActive)
+// UpdateUserActivity provides a mock function with given fields: ctx, userID, isActive, reassign
+func (_m *UserRepoInterface) UpdateUserActivity(ctx context.Context, userID string, isActive bool, reassign bool) (*user.User, []user.Reassignment, error) {
+	ret := _m.Called(ctx, userID, isActive, reassign)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateUserActivity")
 	}
 
 	var r0 *user.User
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, bool) (*user.User, error)); ok {
-		return rf(ctx, userID, isActive)
+	var r1 []user.Reassignment
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, bool) (*user.User, []user.Reassignment, error)); ok {
+		return rf(ctx, userID, isActive, reassign)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, bool) *user.User); ok {
-		r0 = rf(ctx, userID, isActive)
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, bool) *user.User); ok {
+		r0 = rf(ctx, userID, isActive, reassign)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*user.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
-		r1 = rf(ctx, userID, isActive)
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool, bool) []user.Reassignment); ok {
+		r1 = rf(ctx, userID, isActive, reassign)
 	} else {
-		r1 = ret.Error(1)
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]user.Reassignment)
+		}
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, string, bool, bool) error); ok {
+		r2 = rf(ctx, userID, isActive, reassign)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // NewUserRepoInterface creates a new instance of UserRepoInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -11,7 +11,11 @@ import (
 	"reflect"
 	"testing"
 
+	"pullreq/internal/affinity"
+	"pullreq/internal/comment"
+	"pullreq/internal/ownership"
 	"pullreq/internal/pr"
+	"pullreq/internal/rotation"
 	"pullreq/internal/team"
 	"pullreq/internal/user"
 
@@ -110,14 +114,14 @@ func Test_E2EFULL(t *testing.T) {
          }`,
 			Url:            env.Server.URL + "/team/add",
 			ExpectedStatus: 201,
-			ExpectedOutput: `{"team":{"team_name":"payments2","members":[{"user_id":"u1","username":"Alice","is_active":true},{"user_id":"u2","username":"Bob","is_active":true},{"user_id":"u3","username":"Vlad","is_active":true}]}}`,
+			ExpectedOutput: `{"team":{"team_name":"payments2","members":[{"user_id":"u1","username":"Alice","is_active":true},{"user_id":"u2","username":"Bob","is_active":true},{"user_id":"u3","username":"Vlad","is_active":true}],"reviewer_strategy":"least_loaded","reviewers_required":2,"understaffed":false}}`,
 		},
 		&Test{
 			Body:           "",
 			Method:         "GET",
 			Url:            env.Server.URL + "/team/get?team_name=payments2",
 			ExpectedStatus: 200,
-			ExpectedOutput: `{"team":{"team_name":"payments2","members":[{"id":"u1","username":"Alice","team_name":"payments2","is_active":true},{"id":"u2","username":"Bob","team_name":"payments2","is_active":true},{"id":"u3","username":"Vlad","team_name":"payments2","is_active":true}]}}`,
+			ExpectedOutput: `{"team":{"team_name":"payments2","members":[{"id":"u1","username":"Alice","team_name":"payments2","is_active":true},{"id":"u2","username":"Bob","team_name":"payments2","is_active":true},{"id":"u3","username":"Vlad","team_name":"payments2","is_active":true}],"reviewer_strategy":"least_loaded","reviewers_required":2,"understaffed":false}}`,
 		},
 	}
 	for _, test := range tests {
@@ -135,11 +139,22 @@ func SetupTestEnv() (*TestEnv, error) {
 
 	userRepo := &user.UserRepo{DB: db}
 	teamRepo := &team.TeamRepo{DB: db, UR: userRepo}
-	prRepo := &pr.PullRequestRepo{DB: db, UR: userRepo, TR: teamRepo}
+	ownershipRepo := &ownership.OwnershipRepo{DB: db}
+	affinityRepo := &affinity.AffinityRepo{DB: db}
+	rotationRepo := &rotation.RotationRepo{DB: db}
+	commentRepo := &comment.CommentRepo{DB: db}
+	prRepo := &pr.PullRequestRepo{DB: db, UR: userRepo, TR: teamRepo, OR: ownershipRepo, AR: affinityRepo, RR: rotationRepo}
+	// deactivating users moves their open reviews through the PR assignment policy
+	userRepo.Reassigner = prRepo
+	teamRepo.Reassigner = prRepo
 
 	teamRouter := &team.TeamRouter{TR: teamRepo}
 	userRouter := &user.UserRouter{UR: userRepo}
 	prRouter := &pr.PrRouter{PR: prRepo}
+	ownershipRouter := &ownership.OwnershipRouter{OR: ownershipRepo}
+	affinityRouter := &affinity.AffinityRouter{AR: affinityRepo}
+	rotationRouter := &rotation.RotationRouter{RR: rotationRepo}
+	commentRouter := &comment.CommentRouter{CR: commentRepo}
 
 	r := chi.NewRouter()
 
@@ -147,18 +162,67 @@ func SetupTestEnv() (*TestEnv, error) {
 		r.Post("/add", teamRouter.HandleAddTeam)
 		r.Get("/get", teamRouter.GetTeamWithMembersHandler)
 		r.Post("/deactivation", teamRouter.DeactivateTeam)
+		r.Post("/settings", teamRouter.UpdateTeamSettings)
 	})
 
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/setIsActive", userRouter.RouterSetActiviry)
 		r.Get("/getReview", userRouter.GetUserReviewsHandler)
 		r.Get("/getStat", userRouter.GetStat)
+		r.Post("/setMaxOpenReviews", userRouter.RouterSetMaxOpenReviews)
+		r.Post("/setSeniority", userRouter.RouterSetSeniority)
+		r.Post("/setAdmin", userRouter.RouterSetAdmin)
+		r.Post("/setWorkingHours", userRouter.RouterSetWorkingHours)
+		r.Post("/addOutOfOffice", userRouter.RouterAddOutOfOffice)
+		r.Post("/deleteOutOfOffice", userRouter.RouterDeleteOutOfOffice)
+		r.Get("/getOutOfOffice", userRouter.GetOutOfOfficeHandler)
+		r.Get("/availability", userRouter.AvailabilityHandler)
+		r.Post("/setTags", userRouter.RouterSetTags)
+		r.Get("/getTags", userRouter.GetTagsHandler)
 	})
 
 	r.Route("/pullRequest", func(r chi.Router) {
 		r.Post("/create", prRouter.CreatePullRequest)
+		r.Post("/preview", prRouter.PreviewPullRequest)
 		r.Post("/merge", prRouter.Merge)
 		r.Post("/reassign", prRouter.AssignedReviewer)
+		r.Post("/setLabels", prRouter.SetLabels)
+		r.Post("/update", prRouter.Update)
+		r.Get("/history", prRouter.History)
+		r.Get("/explain", prRouter.Explain)
+		r.Get("/get", prRouter.Get)
+		r.Get("/list", prRouter.List)
+		r.Post("/startReview", prRouter.StartReview)
+		r.Post("/review", prRouter.SubmitReview)
+		r.Post("/close", prRouter.Close)
+		r.Post("/reopen", prRouter.Reopen)
+		r.Post("/readyForReview", prRouter.ReadyForReview)
+		r.Post("/rebalance", prRouter.Rebalance)
+	})
+
+	r.Route("/ownership", func(r chi.Router) {
+		r.Post("/import", ownershipRouter.ImportCodeowners)
+		r.Get("/rules", ownershipRouter.GetRulesHandler)
+	})
+
+	r.Route("/affinity", func(r chi.Router) {
+		r.Post("/set", affinityRouter.SetRuleHandler)
+		r.Post("/delete", affinityRouter.DeleteRuleHandler)
+		r.Get("/rules", affinityRouter.GetRulesHandler)
+	})
+
+	r.Route("/rotation", func(r chi.Router) {
+		r.Post("/set", rotationRouter.SetRotationHandler)
+		r.Post("/delete", rotationRouter.DeleteRotationHandler)
+		r.Get("/schedule", rotationRouter.GetScheduleHandler)
+	})
+
+	r.Route("/comment", func(r chi.Router) {
+		r.Post("/add", commentRouter.AddHandler)
+		r.Get("/list", commentRouter.ListHandler)
+		r.Post("/edit", commentRouter.EditHandler)
+		r.Post("/resolve", commentRouter.ResolveHandler)
+		r.Post("/unresolve", commentRouter.UnresolveHandler)
 	})
 
 	return &TestEnv{
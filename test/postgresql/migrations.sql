@@ -1,3 +1,22 @@
+DROP TABLE IF EXISTS pr_comments CASCADE;
+DROP TABLE IF EXISTS pr_edits CASCADE;
+DROP TABLE IF EXISTS forced_merges CASCADE;
+DROP TABLE IF EXISTS review_verdicts CASCADE;
+DROP TABLE IF EXISTS duty_rotation_members CASCADE;
+DROP TABLE IF EXISTS duty_rotations CASCADE;
+DROP TABLE IF EXISTS out_of_office CASCADE;
+DROP TABLE IF EXISTS reviewer_rules CASCADE;
+DROP TABLE IF EXISTS assignment_decision_draws CASCADE;
+DROP TABLE IF EXISTS assignment_decision_users CASCADE;
+DROP TABLE IF EXISTS assignment_decisions CASCADE;
+DROP TABLE IF EXISTS reassignment_history CASCADE;
+DROP TABLE IF EXISTS team_fallbacks CASCADE;
+DROP TABLE IF EXISTS pr_files CASCADE;
+DROP TABLE IF EXISTS ownership_rule_owners CASCADE;
+DROP TABLE IF EXISTS ownership_rules CASCADE;
+DROP TABLE IF EXISTS team_review_cursor CASCADE;
+DROP TABLE IF EXISTS pr_labels CASCADE;
+DROP TABLE IF EXISTS user_tags CASCADE;
 DROP TABLE IF EXISTS userspr CASCADE;
 DROP TABLE IF EXISTS usershistory CASCADE;
 DROP TABLE IF EXISTS pr CASCADE;
@@ -6,37 +25,233 @@ DROP TABLE IF EXISTS teams CASCADE;
 
 CREATE TABLE teams(
     id SERIAL PRIMARY KEY,
-    team_name VARCHAR(128) UNIQUE
+    team_name VARCHAR(128) UNIQUE,
+    reviewer_strategy VARCHAR(32) NOT NULL DEFAULT 'least_loaded',
+    reviewers_required INTEGER NOT NULL DEFAULT 2 CHECK (reviewers_required > 0),
+    require_senior BOOLEAN NOT NULL DEFAULT FALSE,
+    working_hours_ahead INTEGER NOT NULL DEFAULT 0 CHECK (working_hours_ahead BETWEEN 0 AND 24),
+    rebalance_threshold INTEGER NOT NULL DEFAULT 0 CHECK (rebalance_threshold >= 0),
+    duty_reviewer BOOLEAN NOT NULL DEFAULT FALSE,
+    approvals_required INTEGER NOT NULL DEFAULT 0 CHECK (approvals_required >= 0),
+    block_on_changes_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    require_resolved_threads BOOLEAN NOT NULL DEFAULT FALSE
 );
 
 CREATE TABLE users (
     id VARCHAR(256) PRIMARY KEY,
     username VARCHAR(2000) UNIQUE NOT NULL,
     team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
-    is_active BOOLEAN NOT NULL
+    is_active BOOLEAN NOT NULL,
+    max_open_reviews INTEGER CHECK (max_open_reviews >= 0),
+    seniority VARCHAR(16) NOT NULL DEFAULT 'mid' CHECK (seniority IN ('junior', 'mid', 'senior', 'staff')),
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    work_start VARCHAR(5),
+    work_end VARCHAR(5),
+    CHECK ((work_start IS NULL) = (work_end IS NULL))
 );
 
 CREATE TABLE pr (
     id VARCHAR(256) PRIMARY KEY,
     pr_name varchar(2000),
     author_id VARCHAR(256) NOT NULL REFERENCES users(id),
-    pr_status VARCHAR(256),
+    pr_status VARCHAR(256) NOT NULL CHECK (pr_status IN ('DRAFT', 'OPEN', 'CLOSED', 'MERGED')),
     created_ad TIMESTAMP,
-    mergerd_at TIMESTAMP
+    mergerd_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    reviewers_required INTEGER,
+    description TEXT NOT NULL DEFAULT '',
+    priority VARCHAR(16) NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high', 'critical')),
+    version INTEGER NOT NULL DEFAULT 1
 );
 
+CREATE INDEX pr_created_idx ON pr (created_ad, id);
+CREATE INDEX pr_author_idx ON pr (author_id);
+
 CREATE TABLE userspr (
     user_id    VARCHAR(256) NOT NULL REFERENCES users(id),
     request_id VARCHAR(256) NOT NULL REFERENCES pr(id),
+    fallback   BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at TIMESTAMP, -- NULL until the reviewer starts the review
     PRIMARY KEY (user_id, request_id)
 );
 
+CREATE TABLE review_verdicts (
+    id           SERIAL PRIMARY KEY,
+    request_id   VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    user_id      VARCHAR(256) NOT NULL REFERENCES users(id),
+    verdict      VARCHAR(32) NOT NULL CHECK (verdict IN ('APPROVED', 'CHANGES_REQUESTED', 'COMMENTED')),
+    message      TEXT NOT NULL DEFAULT '',
+    submitted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX review_verdicts_request_idx ON review_verdicts (request_id, user_id);
+
+CREATE TABLE pr_edits (
+    id         SERIAL PRIMARY KEY,
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    version    INTEGER NOT NULL,
+    edited_by  VARCHAR(256) NOT NULL REFERENCES users(id),
+    field      VARCHAR(32) NOT NULL,
+    old_value  JSONB NOT NULL,
+    new_value  JSONB NOT NULL,
+    edited_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX pr_edits_request_idx ON pr_edits (request_id, version);
+
+CREATE TABLE pr_comments (
+    id          SERIAL PRIMARY KEY,
+    request_id  VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    thread_id   INTEGER REFERENCES pr_comments(id) ON DELETE CASCADE,
+    parent_id   INTEGER REFERENCES pr_comments(id) ON DELETE CASCADE,
+    author_id   VARCHAR(256) NOT NULL REFERENCES users(id),
+    body        TEXT NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    edited_at   TIMESTAMP,
+    resolved_by VARCHAR(256) REFERENCES users(id),
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX pr_comments_request_idx ON pr_comments (request_id, thread_id);
+
+CREATE TABLE forced_merges (
+    id         SERIAL PRIMARY KEY,
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    forced_by  VARCHAR(256) NOT NULL REFERENCES users(id),
+    reason     VARCHAR(2000) NOT NULL DEFAULT '',
+    bypassed   TEXT[] NOT NULL DEFAULT '{}',
+    merged_at  TIMESTAMP NOT NULL
+);
+
 CREATE TABLE usershistory(
     user_id VARCHAR(256) NOT NULL REFERENCES users(id),
     pr_count INTEGER,
     PRIMARY KEY (user_id)
 );
 
+CREATE TABLE user_tags (
+    user_id VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tag     VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, tag)
+);
+
+CREATE TABLE pr_labels (
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    label      VARCHAR(64) NOT NULL,
+    PRIMARY KEY (request_id, label)
+);
+
+CREATE TABLE team_review_cursor (
+    team_id      INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+    last_user_id VARCHAR(256)
+);
+
+CREATE TABLE team_fallbacks (
+    team_id          INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    position         INTEGER NOT NULL,
+    fallback_team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, fallback_team_id),
+    CHECK (team_id <> fallback_team_id)
+);
+
+CREATE TABLE reassignment_history (
+    id            SERIAL PRIMARY KEY,
+    request_id    VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    old_user_id   VARCHAR(256) NOT NULL REFERENCES users(id),
+    new_user_id   VARCHAR(256) REFERENCES users(id), -- NULL when nobody took the review over
+    reason        VARCHAR(2000) NOT NULL DEFAULT '',
+    requested     BOOLEAN NOT NULL DEFAULT FALSE,
+    reassigned_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE assignment_decisions (
+    id         SERIAL PRIMARY KEY,
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    kind       VARCHAR(32) NOT NULL,
+    strategy   VARCHAR(32) NOT NULL,
+    seed       BIGINT NOT NULL,
+    decided_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE assignment_decision_users (
+    decision_id     INTEGER NOT NULL REFERENCES assignment_decisions(id) ON DELETE CASCADE,
+    user_id         VARCHAR(256) NOT NULL,
+    source          VARCHAR(32),
+    open_reviews    INTEGER,
+    excluded_reason VARCHAR(32),
+    picked          BOOLEAN NOT NULL DEFAULT FALSE,
+    stage           VARCHAR(16), -- the stage and tier that supplied a picked user
+    tier            VARCHAR(16),
+    PRIMARY KEY (decision_id, user_id)
+);
+
+CREATE TABLE assignment_decision_draws (
+    decision_id   INTEGER NOT NULL REFERENCES assignment_decisions(id) ON DELETE CASCADE,
+    position      INTEGER NOT NULL,
+    stage         VARCHAR(16) NOT NULL,
+    tier          VARCHAR(16) NOT NULL,
+    team_id       INTEGER NOT NULL,
+    wanted        INTEGER NOT NULL,
+    candidates    JSONB NOT NULL,
+    picked        JSONB NOT NULL,
+    cursor_before VARCHAR(256),
+    cursor_after  VARCHAR(256),
+    PRIMARY KEY (decision_id, position)
+);
+
+CREATE TABLE reviewer_rules (
+    kind        VARCHAR(16) NOT NULL CHECK (kind IN ('never', 'prefer', 'always')),
+    author_id   VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reviewer_id VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (author_id, reviewer_id),
+    CHECK (author_id <> reviewer_id)
+);
+
+CREATE TABLE out_of_office (
+    id          SERIAL PRIMARY KEY,
+    user_id     VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at   TIMESTAMPTZ NOT NULL,
+    ends_at     TIMESTAMPTZ NOT NULL,
+    delegate_id VARCHAR(256) REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (ends_at > starts_at),
+    CHECK (delegate_id <> user_id)
+);
+
+CREATE INDEX out_of_office_user_idx ON out_of_office (user_id, ends_at);
+
+CREATE TABLE duty_rotations (
+    team_id     INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+    period_days INTEGER NOT NULL DEFAULT 7 CHECK (period_days > 0),
+    starts_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE duty_rotation_members (
+    team_id  INTEGER NOT NULL REFERENCES duty_rotations(team_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    user_id  VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE TABLE ownership_rules (
+    id       SERIAL PRIMARY KEY,
+    position INTEGER NOT NULL,
+    pattern  VARCHAR(1024) NOT NULL
+);
+
+CREATE TABLE ownership_rule_owners (
+    rule_id INTEGER NOT NULL REFERENCES ownership_rules(id) ON DELETE CASCADE,
+    user_id VARCHAR(256) REFERENCES users(id) ON DELETE CASCADE,
+    team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+    CHECK ((user_id IS NULL) <> (team_id IS NULL))
+);
+
+CREATE TABLE pr_files (
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    path       VARCHAR(1024) NOT NULL,
+    PRIMARY KEY (request_id, path)
+);
+
 CREATE INDEX idx_pr_author_id ON pr(author_id);
 CREATE INDEX idx_users_team_id ON users(team_id);
 CREATE INDEX idx_teams_team_name ON teams(team_name);
\ No newline at end of file
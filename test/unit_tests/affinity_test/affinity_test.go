@@ -0,0 +1,101 @@
+package affinity_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pullreq/internal/affinity"
+	"pullreq/internal/errs"
+	routermocks "pullreq/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAffinityRepo_RulesFor(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ar := &affinity.AffinityRepo{DB: db}
+
+	sqlMock.ExpectQuery(`SELECT kind, author_id, reviewer_id FROM reviewer_rules WHERE author_id = \$1 ORDER BY author_id, reviewer_id`).
+		WithArgs("junior").
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "author_id", "reviewer_id"}).
+			AddRow(affinity.KindAlways, "junior", "lead").
+			AddRow(affinity.KindPrefer, "junior", "mentor").
+			AddRow(affinity.KindNever, "junior", "sibling"))
+
+	set, err := ar.RulesFor(context.Background(), "junior")
+	require.NoError(t, err)
+	require.Equal(t, []string{"lead"}, set.Always)
+	require.Equal(t, []string{"mentor"}, set.Prefer)
+	require.Equal(t, []string{"sibling"}, set.Never)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAffinityRepo_DeleteRule_NotFound(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ar := &affinity.AffinityRepo{DB: db}
+
+	sqlMock.ExpectExec(`DELETE FROM reviewer_rules WHERE author_id = \$1 AND reviewer_id = \$2`).
+		WithArgs("a", "b").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ar.DeleteRule(context.Background(), "a", "b")
+	require.True(t, errors.Is(err, errs.NotFountError))
+}
+
+func TestSetRuleHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockAR := routermocks.NewAffinityRepoInterface(t)
+		router := &affinity.AffinityRouter{AR: mockAR}
+
+		rule := affinity.Rule{Kind: affinity.KindNever, AuthorID: "u1", ReviewerID: "u2"}
+		mockAR.On("SetRule", mock.Anything, rule).Return(nil)
+
+		body, _ := json.Marshal(rule)
+		req := httptest.NewRequest(http.MethodPost, "/affinity/set", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.SetRuleHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("invalid_rule", func(t *testing.T) {
+		router := &affinity.AffinityRouter{AR: routermocks.NewAffinityRepoInterface(t)}
+
+		for _, body := range []string{
+			`{"kind":"sometimes","author_id":"u1","reviewer_id":"u2"}`,
+			`{"kind":"never","author_id":"u1","reviewer_id":"u1"}`,
+			`{"kind":"never","author_id":"u1"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/affinity/set", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+
+			router.SetRuleHandler(w, req)
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("unknown_user", func(t *testing.T) {
+		mockAR := routermocks.NewAffinityRepoInterface(t)
+		router := &affinity.AffinityRouter{AR: mockAR}
+		mockAR.On("SetRule", mock.Anything, mock.Anything).Return(errs.NotFountError)
+
+		req := httptest.NewRequest(http.MethodPost, "/affinity/set", bytes.NewBufferString(`{"kind":"always","author_id":"u1","reviewer_id":"ghost"}`))
+		w := httptest.NewRecorder()
+
+		router.SetRuleHandler(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
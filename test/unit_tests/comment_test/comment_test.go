@@ -0,0 +1,274 @@
+package comment_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pullreq/internal/comment"
+	"pullreq/internal/errs"
+	routermocks "pullreq/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var createdAt = time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+
+func TestCommentRepo_AddReply(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cr := &comment.CommentRepo{DB: db, Clock: func() time.Time { return createdAt }}
+	parentID := 7
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT pr.pr_status, pr.author_id = \$1 OR EXISTS \(SELECT 1 FROM userspr ur WHERE ur.request_id = pr.id AND ur.user_id = \$2\) FROM pr WHERE pr.id = \$3 FOR SHARE`).
+		WithArgs("u2", "u2", "pr-1").
+		WillReturnRows(sqlmock.NewRows([]string{"pr_status", "participant"}).AddRow("OPEN", true))
+	sqlMock.ExpectQuery(`SELECT COALESCE\(thread_id, id\) FROM pr_comments WHERE id = \$1 AND request_id = \$2`).
+		WithArgs(parentID, "pr-1").
+		WillReturnRows(sqlmock.NewRows([]string{"thread_id"}).AddRow(5))
+	sqlMock.ExpectQuery(`INSERT INTO pr_comments \(request_id,thread_id,parent_id,author_id,body,created_at\) VALUES`).
+		WithArgs("pr-1", 5, &parentID, "u2", "agreed", createdAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(8, createdAt))
+	sqlMock.ExpectCommit()
+
+	c, err := cr.Add(context.Background(), comment.AddRequest{PullRequestID: "pr-1", AuthorID: "u2", Body: "agreed", ParentID: &parentID})
+	require.NoError(t, err)
+	require.Equal(t, 8, c.ID)
+	require.Equal(t, 5, c.ThreadID)
+	require.Equal(t, createdAt, c.CreatedAt)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCommentRepo_AddNotParticipant(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cr := &comment.CommentRepo{DB: db}
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT pr.pr_status`).
+		WithArgs("stranger", "stranger", "pr-1").
+		WillReturnRows(sqlmock.NewRows([]string{"pr_status", "participant"}).AddRow("OPEN", false))
+	sqlMock.ExpectRollback()
+
+	_, err = cr.Add(context.Background(), comment.AddRequest{PullRequestID: "pr-1", AuthorID: "stranger", Body: "hi"})
+	require.ErrorIs(t, err, errs.NotParticipantError)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCommentRepo_Resolve(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	resolvedAt := createdAt.Add(time.Hour)
+	cr := &comment.CommentRepo{DB: db, Clock: func() time.Time { return resolvedAt }}
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT request_id, COALESCE\(thread_id, id\) FROM pr_comments WHERE id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"request_id", "thread_id"}).AddRow("pr-1", 1))
+	sqlMock.ExpectQuery(`SELECT pr.pr_status`).
+		WithArgs("u1", "u1", "pr-1").
+		WillReturnRows(sqlmock.NewRows([]string{"pr_status", "participant"}).AddRow("OPEN", true))
+	sqlMock.ExpectExec(`UPDATE pr_comments SET resolved_by = \$1, resolved_at = \$2 WHERE id = \$3`).
+		WithArgs("u1", resolvedAt, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectQuery(`SELECT c.id`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "request_id", "thread_id", "parent_id", "author_id", "body", "created_at", "edited_at", "resolved_by", "resolved_at"}).
+			AddRow(1, "pr-1", 1, nil, "u2", "rename it", createdAt, nil, "u1", resolvedAt))
+
+	thread, err := cr.Resolve(context.Background(), comment.ResolveRequest{CommentID: 2, UserID: "u1"}, true)
+	require.NoError(t, err)
+	require.True(t, thread.Resolved)
+	require.Equal(t, resolvedAt, *thread.ResolvedAt)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCommentRepo_ClosedOrMergedPR(t *testing.T) {
+	for _, tc := range []struct {
+		status string
+		err    error
+	}{
+		{status: "CLOSED", err: errs.InvalidTransitionError},
+		{status: "MERGED", err: errs.PRMergedError},
+	} {
+		t.Run(tc.status, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			cr := &comment.CommentRepo{DB: db}
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT pr.pr_status`).
+				WithArgs("u2", "u2", "pr-1").
+				WillReturnRows(sqlmock.NewRows([]string{"pr_status", "participant"}).AddRow(tc.status, true))
+			sqlMock.ExpectRollback()
+
+			_, err = cr.Add(context.Background(), comment.AddRequest{PullRequestID: "pr-1", AuthorID: "u2", Body: "hi"})
+			require.ErrorIs(t, err, tc.err)
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT request_id, COALESCE\(thread_id, id\) FROM pr_comments WHERE id = \$1`).
+				WithArgs(2).
+				WillReturnRows(sqlmock.NewRows([]string{"request_id", "thread_id"}).AddRow("pr-1", 1))
+			sqlMock.ExpectQuery(`SELECT pr.pr_status`).
+				WithArgs("u2", "u2", "pr-1").
+				WillReturnRows(sqlmock.NewRows([]string{"pr_status", "participant"}).AddRow(tc.status, true))
+			sqlMock.ExpectRollback()
+
+			_, err = cr.Resolve(context.Background(), comment.ResolveRequest{CommentID: 2, UserID: "u2"}, true)
+			require.ErrorIs(t, err, tc.err)
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery(`SELECT c.author_id, pr.pr_status FROM pr_comments c JOIN pr ON pr.id = c.request_id WHERE c.id = \$1 FOR SHARE OF pr`).
+				WithArgs(2).
+				WillReturnRows(sqlmock.NewRows([]string{"author_id", "pr_status"}).AddRow("u2", tc.status))
+			sqlMock.ExpectRollback()
+
+			_, err = cr.Edit(context.Background(), comment.EditRequest{CommentID: 2, AuthorID: "u2", Body: "nit"})
+			require.ErrorIs(t, err, tc.err)
+			require.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCommentRepo_Edit(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	editedAt := createdAt.Add(time.Hour)
+	cr := &comment.CommentRepo{DB: db, Clock: func() time.Time { return editedAt }}
+
+	// the PR stays locked until the edit is committed
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT c.author_id, pr.pr_status FROM pr_comments c JOIN pr ON pr.id = c.request_id WHERE c.id = \$1 FOR SHARE OF pr`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"author_id", "pr_status"}).AddRow("u2", "OPEN"))
+	sqlMock.ExpectQuery(`UPDATE pr_comments SET body = \$1, edited_at = \$2 WHERE id = \$3 RETURNING`).
+		WithArgs("nit: rename it", editedAt, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "request_id", "thread_id", "parent_id", "author_id", "body", "created_at", "edited_at"}).
+			AddRow(2, "pr-1", 1, 1, "u2", "nit: rename it", createdAt, editedAt))
+	sqlMock.ExpectCommit()
+
+	c, err := cr.Edit(context.Background(), comment.EditRequest{CommentID: 2, AuthorID: "u2", Body: "nit: rename it"})
+	require.NoError(t, err)
+	require.Equal(t, 1, c.ThreadID)
+	require.Equal(t, editedAt, *c.EditedAt)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`SELECT c.author_id, pr.pr_status`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"author_id", "pr_status"}).AddRow("u2", "OPEN"))
+	sqlMock.ExpectRollback()
+
+	_, err = cr.Edit(context.Background(), comment.EditRequest{CommentID: 2, AuthorID: "u3", Body: "nit"})
+	require.ErrorIs(t, err, errs.NotCommentAuthorError)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestCommentRepo_List(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cr := &comment.CommentRepo{DB: db}
+	resolvedAt := createdAt.Add(time.Hour)
+
+	sqlMock.ExpectQuery(`SELECT c.id, c.request_id, t.id, c.parent_id, (.+) FROM pr_comments c JOIN pr_comments t ON t.id = COALESCE\(c.thread_id, c.id\) WHERE \(c.request_id = \$1\) ORDER BY t.id, c.id`).
+		WithArgs("pr-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "request_id", "thread_id", "parent_id", "author_id", "body", "created_at", "edited_at", "resolved_by", "resolved_at"}).
+			AddRow(1, "pr-1", 1, nil, "u2", "rename it", createdAt, nil, "u1", resolvedAt).
+			AddRow(2, "pr-1", 1, 1, "u1", "done", createdAt, nil, "u1", resolvedAt).
+			AddRow(3, "pr-1", 3, nil, "u3", "add a test", createdAt, nil, "", nil))
+
+	threads, err := cr.List(context.Background(), "pr-1", false)
+	require.NoError(t, err)
+	require.Len(t, threads, 2)
+	require.True(t, threads[0].Resolved)
+	require.Equal(t, "u1", threads[0].ResolvedBy)
+	require.Len(t, threads[0].Comments, 2)
+	require.Equal(t, 1, *threads[0].Comments[1].ParentID)
+	require.False(t, threads[1].Resolved)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAddHandler(t *testing.T) {
+	mockCR := routermocks.NewCommentRepoInterface(t)
+	router := &comment.CommentRouter{CR: mockCR}
+
+	req := comment.AddRequest{PullRequestID: "pr-1", AuthorID: "u2", Body: "rename it"}
+	mockCR.On("Add", mock.Anything, req).Return(&comment.Comment{ID: 1, PullRequestID: "pr-1", ThreadID: 1, AuthorID: "u2", Body: "rename it", CreatedAt: createdAt}, nil)
+	mockCR.On("Add", mock.Anything, comment.AddRequest{PullRequestID: "pr-1", AuthorID: "stranger", Body: "hi"}).Return(nil, errs.NotParticipantError)
+
+	w := httptest.NewRecorder()
+	router.AddHandler(w, httptest.NewRequest(http.MethodPost, "/comment/add", bytes.NewBufferString(`{"pull_request_id":"pr-1","author_id":"u2","body":"rename it"}`)))
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Contains(t, w.Body.String(), `"thread_id":1`)
+
+	w = httptest.NewRecorder()
+	router.AddHandler(w, httptest.NewRequest(http.MethodPost, "/comment/add", bytes.NewBufferString(`{"pull_request_id":"pr-1","author_id":"stranger","body":"hi"}`)))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), `"NOT_PARTICIPANT"`)
+
+	w = httptest.NewRecorder()
+	router.AddHandler(w, httptest.NewRequest(http.MethodPost, "/comment/add", bytes.NewBufferString(`{"pull_request_id":"pr-1","author_id":"u2","body":"  "}`)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEditHandler(t *testing.T) {
+	mockCR := routermocks.NewCommentRepoInterface(t)
+	router := &comment.CommentRouter{CR: mockCR}
+
+	mockCR.On("Edit", mock.Anything, comment.EditRequest{CommentID: 1, AuthorID: "u3", Body: "nit"}).Return(nil, errs.NotCommentAuthorError)
+
+	w := httptest.NewRecorder()
+	router.EditHandler(w, httptest.NewRequest(http.MethodPost, "/comment/edit", bytes.NewBufferString(`{"comment_id":1,"author_id":"u3","body":"nit"}`)))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), `"NOT_COMMENT_AUTHOR"`)
+
+	mockCR.On("Edit", mock.Anything, comment.EditRequest{CommentID: 2, AuthorID: "u2", Body: "nit"}).Return(nil, errs.InvalidTransitionError)
+
+	w = httptest.NewRecorder()
+	router.EditHandler(w, httptest.NewRequest(http.MethodPost, "/comment/edit", bytes.NewBufferString(`{"comment_id":2,"author_id":"u2","body":"nit"}`)))
+	require.Equal(t, http.StatusConflict, w.Code)
+	require.Contains(t, w.Body.String(), `"INVALID_TRANSITION"`)
+}
+
+func TestResolveHandlers(t *testing.T) {
+	mockCR := routermocks.NewCommentRepoInterface(t)
+	router := &comment.CommentRouter{CR: mockCR}
+
+	req := comment.ResolveRequest{CommentID: 2, UserID: "u1"}
+	mockCR.On("Resolve", mock.Anything, req, true).Return(&comment.Thread{ID: 1, Resolved: true, ResolvedBy: "u1"}, nil)
+	mockCR.On("Resolve", mock.Anything, req, false).Return(&comment.Thread{ID: 1}, nil)
+	mockCR.On("Resolve", mock.Anything, comment.ResolveRequest{CommentID: 99, UserID: "u1"}, true).Return(nil, errs.NotFountError)
+
+	w := httptest.NewRecorder()
+	router.ResolveHandler(w, httptest.NewRequest(http.MethodPost, "/comment/resolve", bytes.NewBufferString(`{"comment_id":2,"user_id":"u1"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"resolved":true`)
+
+	w = httptest.NewRecorder()
+	router.UnresolveHandler(w, httptest.NewRequest(http.MethodPost, "/comment/unresolve", bytes.NewBufferString(`{"comment_id":2,"user_id":"u1"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, strings.Contains(w.Body.String(), `"resolved":false`))
+
+	w = httptest.NewRecorder()
+	router.ResolveHandler(w, httptest.NewRequest(http.MethodPost, "/comment/resolve", bytes.NewBufferString(`{"comment_id":99,"user_id":"u1"}`)))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
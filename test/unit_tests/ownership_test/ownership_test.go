@@ -0,0 +1,114 @@
+package ownership_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pullreq/internal/ownership"
+	routermocks "pullreq/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	rules, err := ownership.Parse(`
+# backend
+*.go      @u1 @org/back   # inline comment
+/docs/    @u2
+
+internal/pr/*
+`)
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	require.Equal(t, 1, rules[0].Position)
+	require.Equal(t, "*.go", rules[0].Pattern)
+	require.Equal(t, []string{"@u1", "@org/back"}, rules[0].Owners)
+
+	require.Equal(t, "/docs/", rules[1].Pattern)
+	require.Equal(t, []string{"@u2"}, rules[1].Owners)
+
+	require.Equal(t, 3, rules[2].Position)
+	require.Empty(t, rules[2].Owners)
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*", "any/file.txt", true},
+		{"*.go", "main.go", true},
+		{"*.go", "internal/pr/pr_repo.go", true},
+		{"*.go", "internal/pr/pr_repo.sql", false},
+		{"/docs/", "docs/example.txt", true},
+		{"/docs/", "internal/docs/example.txt", false},
+		{"docs/", "internal/docs/example.txt", true},
+		{"apps", "internal/apps/main.go", true},
+		{"internal/pr/*", "internal/pr/pr_repo.go", true},
+		{"internal/pr/*", "internal/pr/sub/file.go", false},
+		{"**/migrations", "deployments/migrations/init.sql", true},
+		{"internal/**/router.go", "internal/pr/router.go", true},
+		{"internal/**/router.go", "internal/router.go", true},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file/.txt", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern+" "+c.path, func(t *testing.T) {
+			require.Equal(t, c.want, ownership.Match(c.pattern, c.path))
+		})
+	}
+}
+
+func TestImportCodeowners(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockOR := routermocks.NewOwnershipRepoInterface(t)
+		router := &ownership.OwnershipRouter{OR: mockOR}
+
+		mockOR.On("ImportRules", mock.Anything, mock.MatchedBy(func(rules []ownership.Rule) bool {
+			return len(rules) == 2 && rules[1].Pattern == "/internal/pr/"
+		})).Return(&ownership.ImportResult{Imported: 2, Unresolved: []string{"@ghost"}}, nil)
+
+		body, _ := json.Marshal(ownership.ImportRequest{Codeowners: "*.sql @u1\n/internal/pr/ @ghost\n"})
+		req := httptest.NewRequest(http.MethodPost, "/ownership/import", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.ImportCodeowners(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, float64(2), resp["imported"])
+		require.Equal(t, []interface{}{"@ghost"}, resp["unresolved_owners"])
+	})
+
+	t.Run("invalid_json", func(t *testing.T) {
+		router := &ownership.OwnershipRouter{OR: routermocks.NewOwnershipRepoInterface(t)}
+
+		req := httptest.NewRequest(http.MethodPost, "/ownership/import", bytes.NewBufferString("{bad"))
+		w := httptest.NewRecorder()
+
+		router.ImportCodeowners(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("internal_error", func(t *testing.T) {
+		mockOR := routermocks.NewOwnershipRepoInterface(t)
+		router := &ownership.OwnershipRouter{OR: mockOR}
+		mockOR.On("ImportRules", mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
+
+		body, _ := json.Marshal(ownership.ImportRequest{Codeowners: "* @u1"})
+		req := httptest.NewRequest(http.MethodPost, "/ownership/import", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.ImportCodeowners(w, req)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
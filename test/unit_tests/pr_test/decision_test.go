@@ -0,0 +1,93 @@
+package pr_test
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+
+	"pullreq/internal/pr"
+	"pullreq/internal/team"
+	"pullreq/internal/user"
+
+	"github.com/stretchr/testify/require"
+)
+
+func pool(ids ...string) []pr.Candidate {
+	res := make([]pr.Candidate, len(ids))
+	for i, id := range ids {
+		res[i] = pr.Candidate{User: &user.User{Id: id, IsActive: true}, OpenReviews: i % 3}
+	}
+	return res
+}
+
+func drawCandidates(candidates []pr.Candidate) []pr.DrawCandidate {
+	res := make([]pr.DrawCandidate, len(candidates))
+	for i, c := range candidates {
+		res[i] = pr.DrawCandidate{UserID: c.User.Id, OpenReviews: c.OpenReviews}
+	}
+	return res
+}
+
+func TestReplay_RandomStrategies(t *testing.T) {
+	ctx := context.Background()
+	s := pr.NewStrategySelector()
+
+	for _, strategy := range []string{team.StrategyRandom, team.StrategyLeastLoaded, team.StrategyWeighted} {
+		t.Run(strategy, func(t *testing.T) {
+			for seed := uint64(1); seed <= 20; seed++ {
+				// a rule pick followed by two draws sharing the seeded source, as an assignment makes them
+				d := pr.Decision{Strategy: strategy, Seed: seed, Reviewers: []string{"lead"}}
+				d.Picks = append(d.Picks, pr.Pick{UserID: "lead", Stage: pr.StageRule})
+				rng := rand.New(rand.NewPCG(seed, seed))
+
+				for _, draw := range []pr.Draw{
+					{Stage: pr.StageTeam, Tier: pr.TierMatching, TeamID: 1, Count: 1, Candidates: drawCandidates(pool("a", "b", "c"))},
+					{Stage: pr.StageTeam, Tier: pr.TierOther, TeamID: 1, Count: 2, Candidates: drawCandidates(pool("d", "e", "f", "g"))},
+				} {
+					candidates := make([]pr.Candidate, len(draw.Candidates))
+					for i, c := range draw.Candidates {
+						candidates[i] = pr.Candidate{User: &user.User{Id: c.UserID}, OpenReviews: c.OpenReviews}
+					}
+					picked, err := s.Select(ctx, pr.SelectionInput{Strategy: strategy, TeamID: draw.TeamID, Candidates: candidates, Count: draw.Count, Rand: rng})
+					require.NoError(t, err)
+
+					draw.Picked = picked
+					d.Draws = append(d.Draws, draw)
+					d.Reviewers = append(d.Reviewers, picked...)
+				}
+
+				replayed, err := (&pr.PullRequestRepo{}).Replay(ctx, d)
+				require.NoError(t, err)
+				require.Equal(t, d.Reviewers, replayed, "seed %d", seed)
+			}
+		})
+	}
+}
+
+func TestReplay_RoundRobinUsesStoredCursor(t *testing.T) {
+	d := pr.Decision{
+		Strategy:  team.StrategyRoundRobin,
+		Seed:      7,
+		Reviewers: []string{"u2", "u3"},
+		Draws: []pr.Draw{{
+			Stage:        pr.StageTeam,
+			Tier:         pr.TierOther,
+			TeamID:       1,
+			Count:        2,
+			Candidates:   drawCandidates(pool("u1", "u2", "u3")),
+			Picked:       []string{"u2", "u3"},
+			CursorBefore: "u1",
+			CursorAfter:  "u3",
+		}},
+	}
+
+	// no database is needed, the cursor is taken from the draw
+	replayed, err := (&pr.PullRequestRepo{}).Replay(context.Background(), d)
+	require.NoError(t, err)
+	require.Equal(t, d.Reviewers, replayed)
+}
+
+func TestRoundRobinSelector_ReplayWithoutTrace(t *testing.T) {
+	_, err := (&pr.RoundRobinSelector{}).Select(context.Background(), pr.SelectionInput{Candidates: pool("u1"), Count: 1, Replay: true})
+	require.Error(t, err)
+}
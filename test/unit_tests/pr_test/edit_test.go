@@ -0,0 +1,113 @@
+package pr_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pullreq/internal/errs"
+	"pullreq/internal/pr"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+const editLockQuery = `SELECT pr_status, pr_name, description, priority, version FROM pr WHERE id = \$1 FOR UPDATE`
+
+func editLockRows(status string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"pr_status", "pr_name", "description", "priority", "version"}).
+		AddRow(status, "Add search", "", pr.PriorityNormal, 1)
+}
+
+// expectGetPr expects the PR to be read back outside of the transaction.
+func expectGetPr(mock sqlmock.Sqlmock, status, name string, version int) {
+	mock.ExpectQuery(`SELECT pr.id`).
+		WithArgs("pr-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pr_name", "author_id", "pr_status", "reviewers_required", "description", "priority", "version", "created_ad", "mergerd_at", "closed_at", "user_id", "fallback"}).
+			AddRow("pr-1", name, "u1", status, 0, "", pr.PriorityNormal, version, nil, nil, nil, nil, false))
+	mock.ExpectQuery(`SELECT label FROM pr_labels`).WithArgs("pr-1").WillReturnRows(sqlmock.NewRows([]string{"label"}))
+	mock.ExpectQuery(`FROM review_verdicts`).WithArgs("pr-1").WillReturnRows(sqlmock.NewRows([]string{"user_id", "verdict", "message", "submitted_at"}))
+}
+
+func TestUpdate_States(t *testing.T) {
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	name := "Add full-text search"
+
+	for _, tc := range []struct {
+		status string
+		err    error
+	}{
+		{status: pr.StatusDraft},
+		{status: pr.StatusOpen},
+		{status: pr.StatusClosed, err: errs.PRNotOpenError},
+		{status: pr.StatusMerged, err: errs.PRNotOpenError},
+	} {
+		t.Run(tc.status, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(editLockQuery).WithArgs("pr-1").WillReturnRows(editLockRows(tc.status))
+			if tc.err != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(`UPDATE pr SET pr_name = \$1, version = \$2 WHERE id = \$3`).
+					WithArgs(name, 2, "pr-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO pr_edits`).
+					WithArgs("pr-1", 2, "u1", pr.FieldName, `"Add search"`, `"Add full-text search"`, now).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+				expectGetPr(mock, tc.status, name, 2)
+			}
+
+			repo := &pr.PullRequestRepo{DB: db, Clock: func() time.Time { return now }}
+			got, err := repo.Update(context.Background(), pr.UpdateRequest{PullRequestID: "pr-1", EditedBy: "u1", PullRequestName: &name})
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, 2, got.Version)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUpdate_UnknownEditor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	name := "Add full-text search"
+	mock.ExpectBegin()
+	mock.ExpectQuery(editLockQuery).WithArgs("pr-1").WillReturnRows(editLockRows(pr.StatusOpen))
+	mock.ExpectExec(`UPDATE pr SET pr_name`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO pr_edits`).WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+
+	repo := &pr.PullRequestRepo{DB: db}
+	_, err = repo.Update(context.Background(), pr.UpdateRequest{PullRequestID: "pr-1", EditedBy: "ghost", PullRequestName: &name})
+	require.ErrorIs(t, err, errs.NotFountError)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate_NoChangeCommitsBeforeRead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	name := "Add search"
+	mock.ExpectBegin()
+	mock.ExpectQuery(editLockQuery).WithArgs("pr-1").WillReturnRows(editLockRows(pr.StatusOpen))
+	mock.ExpectCommit()
+	expectGetPr(mock, pr.StatusOpen, name, 1)
+
+	repo := &pr.PullRequestRepo{DB: db}
+	got, err := repo.Update(context.Background(), pr.UpdateRequest{PullRequestID: "pr-1", EditedBy: "u1", PullRequestName: &name})
+	require.NoError(t, err)
+	require.Equal(t, 1, got.Version)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
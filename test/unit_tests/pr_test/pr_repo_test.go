@@ -3,10 +3,19 @@ package pr_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"pullreq/internal/affinity"
+	"pullreq/internal/comment"
+	"pullreq/internal/errs"
+	"pullreq/internal/ownership"
 	"pullreq/internal/pr"
+	"pullreq/internal/rotation"
 	"pullreq/internal/team"
 	"pullreq/internal/user"
 
@@ -28,6 +37,25 @@ func TestMain(m *testing.M) {
 
 func cleanDB(db *sql.DB) error {
 	schema := `
+DROP TABLE IF EXISTS pr_comments CASCADE;
+DROP TABLE IF EXISTS pr_edits CASCADE;
+DROP TABLE IF EXISTS forced_merges CASCADE;
+DROP TABLE IF EXISTS review_verdicts CASCADE;
+DROP TABLE IF EXISTS duty_rotation_members CASCADE;
+DROP TABLE IF EXISTS duty_rotations CASCADE;
+DROP TABLE IF EXISTS out_of_office CASCADE;
+DROP TABLE IF EXISTS reviewer_rules CASCADE;
+DROP TABLE IF EXISTS assignment_decision_draws CASCADE;
+DROP TABLE IF EXISTS assignment_decision_users CASCADE;
+DROP TABLE IF EXISTS assignment_decisions CASCADE;
+DROP TABLE IF EXISTS reassignment_history CASCADE;
+DROP TABLE IF EXISTS team_fallbacks CASCADE;
+DROP TABLE IF EXISTS pr_files CASCADE;
+DROP TABLE IF EXISTS ownership_rule_owners CASCADE;
+DROP TABLE IF EXISTS ownership_rules CASCADE;
+DROP TABLE IF EXISTS team_review_cursor CASCADE;
+DROP TABLE IF EXISTS pr_labels CASCADE;
+DROP TABLE IF EXISTS user_tags CASCADE;
 DROP TABLE IF EXISTS userspr CASCADE;
 DROP TABLE IF EXISTS usershistory CASCADE;
 DROP TABLE IF EXISTS pr CASCADE;
@@ -36,37 +64,233 @@ DROP TABLE IF EXISTS teams CASCADE;
 
 CREATE TABLE teams(
     id SERIAL PRIMARY KEY,
-    team_name VARCHAR(128) UNIQUE
+    team_name VARCHAR(128) UNIQUE,
+    reviewer_strategy VARCHAR(32) NOT NULL DEFAULT 'least_loaded',
+    reviewers_required INTEGER NOT NULL DEFAULT 2 CHECK (reviewers_required > 0),
+    require_senior BOOLEAN NOT NULL DEFAULT FALSE,
+    working_hours_ahead INTEGER NOT NULL DEFAULT 0 CHECK (working_hours_ahead BETWEEN 0 AND 24),
+    rebalance_threshold INTEGER NOT NULL DEFAULT 0 CHECK (rebalance_threshold >= 0),
+    duty_reviewer BOOLEAN NOT NULL DEFAULT FALSE,
+    approvals_required INTEGER NOT NULL DEFAULT 0 CHECK (approvals_required >= 0),
+    block_on_changes_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    require_resolved_threads BOOLEAN NOT NULL DEFAULT FALSE
 );
 
 CREATE TABLE users (
     id VARCHAR(256) PRIMARY KEY,
     username VARCHAR(2000) UNIQUE NOT NULL,
     team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
-    is_active BOOLEAN NOT NULL
+    is_active BOOLEAN NOT NULL,
+    max_open_reviews INTEGER CHECK (max_open_reviews >= 0),
+    seniority VARCHAR(16) NOT NULL DEFAULT 'mid' CHECK (seniority IN ('junior', 'mid', 'senior', 'staff')),
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    work_start VARCHAR(5),
+    work_end VARCHAR(5),
+    CHECK ((work_start IS NULL) = (work_end IS NULL))
 );
 
 CREATE TABLE pr (
     id VARCHAR(256) PRIMARY KEY,
     pr_name varchar(2000),
     author_id VARCHAR(256) NOT NULL REFERENCES users(id),
-    pr_status VARCHAR(256),
+    pr_status VARCHAR(256) NOT NULL CHECK (pr_status IN ('DRAFT', 'OPEN', 'CLOSED', 'MERGED')),
     created_ad TIMESTAMP,
-    mergerd_at TIMESTAMP
+    mergerd_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    reviewers_required INTEGER,
+    description TEXT NOT NULL DEFAULT '',
+    priority VARCHAR(16) NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high', 'critical')),
+    version INTEGER NOT NULL DEFAULT 1
 );
 
+CREATE INDEX pr_created_idx ON pr (created_ad, id);
+CREATE INDEX pr_author_idx ON pr (author_id);
+
 CREATE TABLE userspr (
     user_id    VARCHAR(256) NOT NULL REFERENCES users(id),
     request_id VARCHAR(256) NOT NULL REFERENCES pr(id),
+    fallback   BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at TIMESTAMP, -- NULL until the reviewer starts the review
     PRIMARY KEY (user_id, request_id)
 );
 
+CREATE TABLE review_verdicts (
+    id           SERIAL PRIMARY KEY,
+    request_id   VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    user_id      VARCHAR(256) NOT NULL REFERENCES users(id),
+    verdict      VARCHAR(32) NOT NULL CHECK (verdict IN ('APPROVED', 'CHANGES_REQUESTED', 'COMMENTED')),
+    message      TEXT NOT NULL DEFAULT '',
+    submitted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX review_verdicts_request_idx ON review_verdicts (request_id, user_id);
+
+CREATE TABLE pr_edits (
+    id         SERIAL PRIMARY KEY,
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    version    INTEGER NOT NULL,
+    edited_by  VARCHAR(256) NOT NULL REFERENCES users(id),
+    field      VARCHAR(32) NOT NULL,
+    old_value  JSONB NOT NULL,
+    new_value  JSONB NOT NULL,
+    edited_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX pr_edits_request_idx ON pr_edits (request_id, version);
+
+CREATE TABLE pr_comments (
+    id          SERIAL PRIMARY KEY,
+    request_id  VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    thread_id   INTEGER REFERENCES pr_comments(id) ON DELETE CASCADE,
+    parent_id   INTEGER REFERENCES pr_comments(id) ON DELETE CASCADE,
+    author_id   VARCHAR(256) NOT NULL REFERENCES users(id),
+    body        TEXT NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    edited_at   TIMESTAMP,
+    resolved_by VARCHAR(256) REFERENCES users(id),
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX pr_comments_request_idx ON pr_comments (request_id, thread_id);
+
+CREATE TABLE forced_merges (
+    id         SERIAL PRIMARY KEY,
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    forced_by  VARCHAR(256) NOT NULL REFERENCES users(id),
+    reason     VARCHAR(2000) NOT NULL DEFAULT '',
+    bypassed   TEXT[] NOT NULL DEFAULT '{}',
+    merged_at  TIMESTAMP NOT NULL
+);
+
 CREATE TABLE usershistory(
     user_id VARCHAR(256) NOT NULL REFERENCES users(id),
     pr_count INTEGER,
     PRIMARY KEY (user_id)
 );
 
+CREATE TABLE user_tags (
+    user_id VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tag     VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, tag)
+);
+
+CREATE TABLE pr_labels (
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    label      VARCHAR(64) NOT NULL,
+    PRIMARY KEY (request_id, label)
+);
+
+CREATE TABLE team_review_cursor (
+    team_id      INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+    last_user_id VARCHAR(256)
+);
+
+CREATE TABLE team_fallbacks (
+    team_id          INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    position         INTEGER NOT NULL,
+    fallback_team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, fallback_team_id),
+    CHECK (team_id <> fallback_team_id)
+);
+
+CREATE TABLE reassignment_history (
+    id            SERIAL PRIMARY KEY,
+    request_id    VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    old_user_id   VARCHAR(256) NOT NULL REFERENCES users(id),
+    new_user_id   VARCHAR(256) REFERENCES users(id), -- NULL when nobody took the review over
+    reason        VARCHAR(2000) NOT NULL DEFAULT '',
+    requested     BOOLEAN NOT NULL DEFAULT FALSE,
+    reassigned_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE assignment_decisions (
+    id         SERIAL PRIMARY KEY,
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    kind       VARCHAR(32) NOT NULL,
+    strategy   VARCHAR(32) NOT NULL,
+    seed       BIGINT NOT NULL,
+    decided_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE assignment_decision_users (
+    decision_id     INTEGER NOT NULL REFERENCES assignment_decisions(id) ON DELETE CASCADE,
+    user_id         VARCHAR(256) NOT NULL,
+    source          VARCHAR(32),
+    open_reviews    INTEGER,
+    excluded_reason VARCHAR(32),
+    picked          BOOLEAN NOT NULL DEFAULT FALSE,
+    stage           VARCHAR(16), -- the stage and tier that supplied a picked user
+    tier            VARCHAR(16),
+    PRIMARY KEY (decision_id, user_id)
+);
+
+CREATE TABLE assignment_decision_draws (
+    decision_id   INTEGER NOT NULL REFERENCES assignment_decisions(id) ON DELETE CASCADE,
+    position      INTEGER NOT NULL,
+    stage         VARCHAR(16) NOT NULL,
+    tier          VARCHAR(16) NOT NULL,
+    team_id       INTEGER NOT NULL,
+    wanted        INTEGER NOT NULL,
+    candidates    JSONB NOT NULL,
+    picked        JSONB NOT NULL,
+    cursor_before VARCHAR(256),
+    cursor_after  VARCHAR(256),
+    PRIMARY KEY (decision_id, position)
+);
+
+CREATE TABLE reviewer_rules (
+    kind        VARCHAR(16) NOT NULL CHECK (kind IN ('never', 'prefer', 'always')),
+    author_id   VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reviewer_id VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (author_id, reviewer_id),
+    CHECK (author_id <> reviewer_id)
+);
+
+CREATE TABLE out_of_office (
+    id          SERIAL PRIMARY KEY,
+    user_id     VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at   TIMESTAMPTZ NOT NULL,
+    ends_at     TIMESTAMPTZ NOT NULL,
+    delegate_id VARCHAR(256) REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (ends_at > starts_at),
+    CHECK (delegate_id <> user_id)
+);
+
+CREATE INDEX out_of_office_user_idx ON out_of_office (user_id, ends_at);
+
+CREATE TABLE duty_rotations (
+    team_id     INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+    period_days INTEGER NOT NULL DEFAULT 7 CHECK (period_days > 0),
+    starts_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE duty_rotation_members (
+    team_id  INTEGER NOT NULL REFERENCES duty_rotations(team_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    user_id  VARCHAR(256) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE TABLE ownership_rules (
+    id       SERIAL PRIMARY KEY,
+    position INTEGER NOT NULL,
+    pattern  VARCHAR(1024) NOT NULL
+);
+
+CREATE TABLE ownership_rule_owners (
+    rule_id INTEGER NOT NULL REFERENCES ownership_rules(id) ON DELETE CASCADE,
+    user_id VARCHAR(256) REFERENCES users(id) ON DELETE CASCADE,
+    team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+    CHECK ((user_id IS NULL) <> (team_id IS NULL))
+);
+
+CREATE TABLE pr_files (
+    request_id VARCHAR(256) NOT NULL REFERENCES pr(id) ON DELETE CASCADE,
+    path       VARCHAR(1024) NOT NULL,
+    PRIMARY KEY (request_id, path)
+);
+
 CREATE INDEX idx_pr_author_id ON pr(author_id);
 CREATE INDEX idx_users_team_id ON users(team_id);
 CREATE INDEX idx_teams_team_name ON teams(team_name);
@@ -118,6 +342,23 @@ func setupTeam(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
+// newTestRepo recreates the schema, seeds "Awesome Team" and returns the repositories
+// over testDB. Tests plug in the optional dependencies they need afterwards.
+func newTestRepo(t *testing.T) (*pr.PullRequestRepo, *team.TeamRepo, *user.UserRepo) {
+	t.Helper()
+	ctx := context.Background()
+	if err := setupDB(ctx, testDB); err != nil {
+		t.Fatalf("failed to set up the schema: %v", err)
+	}
+	if err := setupTeam(ctx, testDB); err != nil {
+		t.Fatalf("failed to set up the team: %v", err)
+	}
+
+	UR := &user.UserRepo{DB: testDB}
+	TR := &team.TeamRepo{DB: testDB, UR: UR}
+	return &pr.PullRequestRepo{DB: testDB, TR: TR, UR: UR}, TR, UR
+}
+
 func TestPullRequestRepo_Create_GetMerge(t *testing.T) {
 	ctx := context.Background()
 	if err := setupDB(ctx, testDB); err != nil {
@@ -149,7 +390,7 @@ func TestPullRequestRepo_Create_GetMerge(t *testing.T) {
 		t.Fatalf("expected PR ID %s, got %s", prReq.ID, gotPR.ID)
 	}
 
-	mergedPR, err := repo.Merged(ctx, ID)
+	mergedPR, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: ID})
 	if err != nil {
 		t.Fatalf("failed to merge PR: %v", err)
 	}
@@ -187,7 +428,7 @@ func TestAssignedReviewerIntegration(t *testing.T) {
 
 	insertPR(t, "pr1", "user1", []string{"user1"})
 
-	updatedPR, newReviewer, err := repo.AssignedReviewer(ctx, "pr1", "user1")
+	updatedPR, newReviewer, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr1", CurrentReviewerID: "user1"})
 	if err != nil {
 		t.Fatalf("failed to assign reviewer: %v", err)
 	}
@@ -207,3 +448,1281 @@ func TestAssignedReviewerIntegration(t *testing.T) {
 		t.Fatalf("new reviewer %s not in updated PR reviewers", newReviewer)
 	}
 }
+
+func TestPullRequestRepo_Create_LeastLoaded(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	insertPR(t, "busy1", "u", []string{"user1"})
+	insertPR(t, "busy2", "u", []string{"user1", "user2"})
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-load", PullRequestName: "Load", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+
+	if len(createdPR.AssignedReviewers) != 2 {
+		t.Fatalf("expected 2 reviewers, got %v", createdPR.AssignedReviewers)
+	}
+	if createdPR.AssignedReviewers[0] != "user3" || createdPR.AssignedReviewers[1] != "user2" {
+		t.Fatalf("expected least loaded reviewers [user3 user2], got %v", createdPR.AssignedReviewers)
+	}
+}
+
+func TestPullRequestRepo_Create_ReviewersRequired(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	if _, err := testDB.Exec(`UPDATE teams SET reviewers_required = 3`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-three", PullRequestName: "Three", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 3 || createdPR.Understaffed {
+		t.Fatalf("expected 3 reviewers, got %v (understaffed=%v)", createdPR.AssignedReviewers, createdPR.Understaffed)
+	}
+
+	if _, err := testDB.Exec(`UPDATE teams SET reviewers_required = 5`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-five", PullRequestName: "Five", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 3 || !createdPR.Understaffed {
+		t.Fatalf("expected understaffed PR with 3 reviewers, got %v (understaffed=%v)", createdPR.AssignedReviewers, createdPR.Understaffed)
+	}
+}
+
+func TestPullRequestRepo_Create_RoundRobinSkipsInactive(t *testing.T) {
+	ctx := context.Background()
+	repo, TR, UR := newTestRepo(t)
+
+	if _, err := testDB.Exec(`UPDATE teams SET reviewer_strategy = 'round_robin', reviewers_required = 1`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+
+	first, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "rr1", PullRequestName: "RR", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if first.AssignedReviewers[0] != "user1" {
+		t.Fatalf("expected user1, got %v", first.AssignedReviewers)
+	}
+
+	if _, err := testDB.Exec(`UPDATE users SET is_active = FALSE WHERE id = 'user2'`); err != nil {
+		t.Fatalf("failed to deactivate user: %v", err)
+	}
+
+	second, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "rr2", PullRequestName: "RR", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if second.AssignedReviewers[0] != "user3" {
+		t.Fatalf("expected inactive user2 to be skipped, got %v", second.AssignedReviewers)
+	}
+
+	// a fresh repo continues from the stored cursor
+	restarted := &pr.PullRequestRepo{DB: testDB, TR: TR, UR: UR}
+	third, err := restarted.Create(ctx, pr.CreatePullRequestRequest{ID: "rr3", PullRequestName: "RR", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if third.AssignedReviewers[0] != "user1" {
+		t.Fatalf("expected rotation to wrap to user1, got %v", third.AssignedReviewers)
+	}
+
+	// the second choice replays from the cursor it was made with, not the current one
+	decisions, err := repo.Explain(ctx, "rr2")
+	if err != nil {
+		t.Fatalf("failed to explain: %v", err)
+	}
+	d := decisions[0]
+	if len(d.Draws) != 1 || d.Draws[0].CursorBefore != "user1" || d.Draws[0].CursorAfter != "user3" {
+		t.Fatalf("unexpected draws %+v", d.Draws)
+	}
+	replayed, err := repo.Replay(ctx, d)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if len(replayed) != 1 || replayed[0] != "user3" {
+		t.Fatalf("expected the replay to pick user3, got %v", replayed)
+	}
+}
+
+func TestPullRequestRepo_Capacity(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	if _, err := testDB.Exec(`UPDATE users SET max_open_reviews = 0 WHERE id IN ('user1', 'user2')`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-cap", PullRequestName: "Cap", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 || createdPR.AssignedReviewers[0] != "user3" || !createdPR.Understaffed {
+		t.Fatalf("expected only user3 and understaffed flag, got %v (understaffed=%v)", createdPR.AssignedReviewers, createdPR.Understaffed)
+	}
+
+	// user1 is the least loaded candidate but at their limit, user2 is under theirs
+	insertPR(t, "busy1", "u", []string{"user1", "user2"})
+	insertPR(t, "busy2", "u", []string{"user2"})
+	if _, err := testDB.Exec(`UPDATE users SET max_open_reviews = CASE id WHEN 'user1' THEN 1 ELSE 3 END WHERE id IN ('user1', 'user2')`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+
+	_, newReviewer, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-cap", CurrentReviewerID: "user3"})
+	if err != nil {
+		t.Fatalf("failed to reassign: %v", err)
+	}
+	if newReviewer != "user2" {
+		t.Fatalf("expected user1 at their limit to be skipped for user2, got %s", newReviewer)
+	}
+
+	// with user3 out of capacity as well nobody can take over from user2
+	if _, err := testDB.Exec(`UPDATE users SET max_open_reviews = 0 WHERE id = 'user3'`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+
+	_, _, err = repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-cap", CurrentReviewerID: "user2"})
+	if !errors.Is(err, errs.NoCandidateError) {
+		t.Fatalf("expected NoCandidateError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_Create_PrefersMatchingTags(t *testing.T) {
+	ctx := context.Background()
+	repo, _, UR := newTestRepo(t)
+
+	if _, err := UR.SetTags(ctx, "user2", []string{"Frontend"}); err != nil {
+		t.Fatalf("failed to set tags: %v", err)
+	}
+	if _, err := testDB.Exec(`UPDATE teams SET reviewers_required = 1`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+	// user2 is the busiest reviewer, the label match must win anyway
+	insertPR(t, "busy", "u", []string{"user2"})
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-css", PullRequestName: "CSS", AuthorID: "u", Labels: []string{"frontend"}})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 || createdPR.AssignedReviewers[0] != "user2" {
+		t.Fatalf("expected user2 with matching tag, got %v", createdPR.AssignedReviewers)
+	}
+
+	// nobody matches, the normal pool is used
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-db", PullRequestName: "DB", AuthorID: "u", Labels: []string{"postgres"}})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 {
+		t.Fatalf("expected fallback to the normal pool, got %v", createdPR.AssignedReviewers)
+	}
+}
+
+func TestPullRequestRepo_Create_RequiresOwner(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	// the owner belongs to another team
+	if _, err := testDB.Exec(`INSERT INTO teams (team_name) VALUES ('platform')`); err != nil {
+		t.Fatalf("failed to insert team: %v", err)
+	}
+	if _, err := testDB.Exec(`INSERT INTO users (id, username, team_id, is_active) VALUES ('owner1', 'Owner', 2, true)`); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	OR := &ownership.OwnershipRepo{DB: testDB}
+	repo.OR = OR
+
+	rules, err := ownership.Parse("* @user1\n/internal/ @owner1\n")
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	if _, err := OR.ImportRules(ctx, rules); err != nil {
+		t.Fatalf("failed to import rules: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-own", PullRequestName: "Own", AuthorID: "u", Files: []string{"internal/pr/pr_repo.go"}})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 2 || createdPR.AssignedReviewers[0] != "owner1" {
+		t.Fatalf("expected owner1 among reviewers, got %v", createdPR.AssignedReviewers)
+	}
+
+	if _, err := testDB.Exec(`UPDATE users SET is_active = false WHERE id = 'owner1'`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+	_, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-own2", PullRequestName: "Own", AuthorID: "u", Files: []string{"internal/team/team_repo.go"}})
+	if !errors.Is(err, errs.NoOwnerError) {
+		t.Fatalf("expected NoOwnerError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_FallbackTeams(t *testing.T) {
+	ctx := context.Background()
+	repo, TR, _ := newTestRepo(t)
+
+	if _, err := testDB.Exec(`INSERT INTO teams (team_name) VALUES ('platform')`); err != nil {
+		t.Fatalf("failed to insert team: %v", err)
+	}
+	if _, err := testDB.Exec(`INSERT INTO users (id, username, team_id, is_active) VALUES ('p1', 'Platform', 2, true)`); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+	if _, err := testDB.Exec(`UPDATE users SET is_active = false WHERE id IN ('user2', 'user3')`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+
+	fallbacks := []string{"platform"}
+	if _, err := TR.UpdateSettings(ctx, team.TeamSettingsInput{TeamName: "Awesome Team", FallbackTeams: &fallbacks}); err != nil {
+		t.Fatalf("failed to set fallback teams: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-fb", PullRequestName: "Fallback", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 2 || createdPR.AssignedReviewers[0] != "user1" {
+		t.Fatalf("expected user1 and a fallback reviewer, got %v", createdPR.AssignedReviewers)
+	}
+	if len(createdPR.FallbackReviewers) != 1 || createdPR.FallbackReviewers[0] != "p1" {
+		t.Fatalf("expected p1 from the fallback team, got %v", createdPR.FallbackReviewers)
+	}
+
+	// the home team is exhausted, the reassignment goes to the fallback team
+	if _, err := testDB.Exec(`UPDATE users SET is_active = false WHERE id = 'user1'`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+	if _, err := testDB.Exec(`INSERT INTO users (id, username, team_id, is_active) VALUES ('p2', 'Platform2', 2, true)`); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	updated, newReviewer, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-fb", CurrentReviewerID: "user1"})
+	if err != nil {
+		t.Fatalf("failed to reassign: %v", err)
+	}
+	if newReviewer != "p2" || !slices.Contains(updated.FallbackReviewers, "p2") {
+		t.Fatalf("expected p2 from the fallback team, got %s (fallback %v)", newReviewer, updated.FallbackReviewers)
+	}
+}
+
+func TestPullRequestRepo_AssignedReviewer_Policy(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	insertPR(t, "pr-re", "u", []string{"user1", "user2"})
+
+	_, _, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-re", CurrentReviewerID: "user3"})
+	if !errors.Is(err, errs.NotAssignedError) {
+		t.Fatalf("expected NotAssignedError, got %v", err)
+	}
+
+	// user2 is already assigned and u is the author
+	for _, requested := range []string{"user2", "u"} {
+		_, _, err = repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-re", CurrentReviewerID: "user1", NewReviewerID: requested})
+		if !errors.Is(err, errs.InvalidCandidateError) {
+			t.Fatalf("expected InvalidCandidateError for %s, got %v", requested, err)
+		}
+	}
+
+	// the only eligible replacement is user3
+	_, newReviewer, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-re", CurrentReviewerID: "user1", Reason: "on vacation"})
+	if err != nil {
+		t.Fatalf("failed to reassign: %v", err)
+	}
+	if newReviewer != "user3" {
+		t.Fatalf("expected user3, got %s", newReviewer)
+	}
+
+	_, newReviewer, err = repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-re", CurrentReviewerID: "user3", NewReviewerID: "user1"})
+	if err != nil || newReviewer != "user1" {
+		t.Fatalf("expected requested user1, got %s (%v)", newReviewer, err)
+	}
+
+	var count int
+	var reason string
+	err = testDB.QueryRow(`SELECT COUNT(*), MIN(NULLIF(reason, '')) FROM reassignment_history WHERE request_id = 'pr-re'`).Scan(&count, &reason)
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if count != 2 || reason != "on vacation" {
+		t.Fatalf("expected 2 history rows with the reason, got %d %q", count, reason)
+	}
+}
+
+func TestPullRequestRepo_Explain(t *testing.T) {
+	ctx := context.Background()
+	repo, _, UR := newTestRepo(t)
+
+	if _, err := testDB.Exec(`UPDATE users SET is_active = false WHERE id = 'user3'`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+	zero := 0
+	if _, err := UR.SetMaxOpenReviews(ctx, "user2", &zero); err != nil {
+		t.Fatalf("failed to set capacity: %v", err)
+	}
+
+	if _, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-ex", PullRequestName: "Explain", AuthorID: "u"}); err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+
+	decisions, err := repo.Explain(ctx, "pr-ex")
+	if err != nil {
+		t.Fatalf("failed to explain: %v", err)
+	}
+	if len(decisions) != 1 {
+		t.Fatalf("expected one decision, got %d", len(decisions))
+	}
+
+	d := decisions[0]
+	if d.Kind != pr.DecisionCreate || d.Strategy != team.StrategyLeastLoaded {
+		t.Fatalf("unexpected decision: %+v", d)
+	}
+	if len(d.Pool) != 1 || d.Pool[0].UserID != "user1" || len(d.Reviewers) != 1 || d.Reviewers[0] != "user1" {
+		t.Fatalf("expected user1 as the only candidate and reviewer, got %+v", d)
+	}
+
+	reasons := make(map[string]string)
+	for _, e := range d.Excluded {
+		reasons[e.UserID] = e.Reason
+	}
+	if reasons["u"] != pr.ExcludedAuthor || reasons["user2"] != pr.ExcludedAtCapacity || reasons["user3"] != pr.ExcludedInactive {
+		t.Fatalf("unexpected exclusions: %v", reasons)
+	}
+	if len(d.Picks) != 1 || d.Picks[0] != (pr.Pick{UserID: "user1", Stage: pr.StageTeam, Tier: pr.TierOther}) {
+		t.Fatalf("unexpected picks %+v", d.Picks)
+	}
+	if len(d.Draws) != 1 || d.Draws[0].Count != 1 || len(d.Draws[0].Candidates) != 1 {
+		t.Fatalf("unexpected draws %+v", d.Draws)
+	}
+	replayed, err := repo.Replay(ctx, d)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if strings.Join(replayed, ",") != strings.Join(d.Reviewers, ",") {
+		t.Fatalf("expected the replay to pick %v, got %v", d.Reviewers, replayed)
+	}
+
+	if _, err := repo.Explain(ctx, "missing"); !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected NotFountError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_AffinityRules(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	AR := &affinity.AffinityRepo{DB: testDB}
+	repo.AR = AR
+
+	for _, rule := range []affinity.Rule{
+		{Kind: affinity.KindNever, AuthorID: "u", ReviewerID: "user1"},
+		{Kind: affinity.KindAlways, AuthorID: "u", ReviewerID: "user3"},
+	} {
+		if err := AR.SetRule(ctx, rule); err != nil {
+			t.Fatalf("failed to set rule: %v", err)
+		}
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-aff", PullRequestName: "Affinity", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 2 || createdPR.AssignedReviewers[0] != "user3" || slices.Contains(createdPR.AssignedReviewers, "user1") {
+		t.Fatalf("expected user3 and user2 without user1, got %v", createdPR.AssignedReviewers)
+	}
+
+	// user3 is required by the author and cannot be replaced
+	_, _, err = repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-aff", CurrentReviewerID: "user3"})
+	if !errors.Is(err, errs.AffinityViolationError) {
+		t.Fatalf("expected AffinityViolationError, got %v", err)
+	}
+
+	if _, err := testDB.Exec(`UPDATE users SET is_active = false WHERE id = 'user3'`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+	_, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-aff2", PullRequestName: "Affinity", AuthorID: "u"})
+	if !errors.Is(err, errs.AffinityViolationError) {
+		t.Fatalf("expected AffinityViolationError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_RequireSenior(t *testing.T) {
+	ctx := context.Background()
+	repo, _, UR := newTestRepo(t)
+
+	if _, err := testDB.Exec(`UPDATE teams SET require_senior = true, reviewers_required = 2`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+	if _, err := UR.SetSeniority(ctx, "user3", user.SeniorityStaff); err != nil {
+		t.Fatalf("failed to set seniority: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-senior", PullRequestName: "Senior", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if !slices.Contains(createdPR.AssignedReviewers, "user3") || len(createdPR.PolicyViolations) != 0 {
+		t.Fatalf("expected user3 among reviewers without violations, got %+v", createdPR)
+	}
+
+	// the only other candidate is not senior
+	_, _, err = repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-senior", CurrentReviewerID: "user3"})
+	if !errors.Is(err, errs.SeniorRequiredError) {
+		t.Fatalf("expected SeniorRequiredError, got %v", err)
+	}
+
+	spare := "user1"
+	if slices.Contains(createdPR.AssignedReviewers, spare) {
+		spare = "user2"
+	}
+	if _, err := UR.SetSeniority(ctx, spare, user.SenioritySenior); err != nil {
+		t.Fatalf("failed to set seniority: %v", err)
+	}
+	_, newReviewer, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-senior", CurrentReviewerID: "user3"})
+	if err != nil || newReviewer != spare {
+		t.Fatalf("expected %s to replace user3, got %q, %v", spare, newReviewer, err)
+	}
+
+	if _, err := testDB.Exec(`UPDATE users SET seniority = 'junior'`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-junior", PullRequestName: "Junior", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.PolicyViolations) != 1 || createdPR.PolicyViolations[0] != pr.PolicyNoSenior {
+		t.Fatalf("expected %s violation, got %v", pr.PolicyNoSenior, createdPR.PolicyViolations)
+	}
+}
+
+func TestPullRequestRepo_WorkingHours(t *testing.T) {
+	ctx := context.Background()
+	repo, _, UR := newTestRepo(t)
+
+	// 06:00 UTC is 09:00 in Moscow and 16:00 in Vladivostok
+	now := time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC)
+	repo.Clock = func() time.Time { return now }
+
+	if _, err := testDB.Exec(`UPDATE teams SET reviewers_required = 1`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+	for _, h := range []struct{ id, tz, start, end string }{
+		{"user1", "Europe/Moscow", "10:00", "19:00"},
+		{"user2", "Asia/Vladivostok", "09:00", "18:00"},
+		{"user3", "UTC", "20:00", "21:00"},
+	} {
+		if _, err := UR.SetWorkingHours(ctx, h.id, h.tz, h.start, h.end); err != nil {
+			t.Fatalf("failed to set working hours: %v", err)
+		}
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-vvo", PullRequestName: "Morning", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 || createdPR.AssignedReviewers[0] != "user2" {
+		t.Fatalf("expected user2 who is working, got %v", createdPR.AssignedReviewers)
+	}
+
+	// 15:00 in Moscow, 22:00 in Vladivostok
+	now = now.Add(6 * time.Hour)
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-mow", PullRequestName: "Afternoon", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 || createdPR.AssignedReviewers[0] != "user1" {
+		t.Fatalf("expected user1 who is working, got %v", createdPR.AssignedReviewers)
+	}
+
+	// nobody works at 19:00 UTC but user3 starts within the next two hours
+	now = now.Add(7 * time.Hour)
+	if _, err := testDB.Exec(`UPDATE teams SET working_hours_ahead = 2`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-night", PullRequestName: "Night", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 || createdPR.AssignedReviewers[0] != "user3" {
+		t.Fatalf("expected user3 who starts soon, got %v", createdPR.AssignedReviewers)
+	}
+
+	// every timestamp follows the clock the reviewers were chosen by
+	review, err := repo.StartReview(ctx, "pr-night", "user3")
+	if err != nil {
+		t.Fatalf("failed to start review: %v", err)
+	}
+	var createdAt, decidedAt time.Time
+	if err := testDB.QueryRow(`SELECT pr.created_ad, d.decided_at FROM pr JOIN assignment_decisions d ON d.request_id = pr.id WHERE pr.id = $1`, "pr-night").Scan(&createdAt, &decidedAt); err != nil {
+		t.Fatalf("failed to read timestamps: %v", err)
+	}
+	if !createdAt.Equal(now) || !decidedAt.Equal(now) || !review.StartedAt.Equal(now) {
+		t.Fatalf("expected %v everywhere, got created %v, decided %v, started %v", now, createdAt, decidedAt, review.StartedAt)
+	}
+}
+
+func TestPullRequestRepo_OutOfOffice(t *testing.T) {
+	ctx := context.Background()
+	repo, _, UR := newTestRepo(t)
+	if _, err := testDB.Exec(`INSERT INTO teams (team_name) VALUES ('Other Team')`); err != nil {
+		t.Fatalf("failed to insert team: %v", err)
+	}
+	if _, err := testDB.Exec(`INSERT INTO users (id, username, team_id, is_active) VALUES ('d1', 'Dave', 2, true)`); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	now := time.Date(2025, 7, 3, 12, 0, 0, 0, time.UTC)
+	repo.Clock = func() time.Time { return now }
+
+	// user1 is away, user2 hands reviews over to d1 from another team, user3 leaves later
+	for _, ooo := range []user.OutOfOffice{
+		{UserID: "user1", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(24 * time.Hour)},
+		{UserID: "user2", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(24 * time.Hour), DelegateID: "d1"},
+		{UserID: "user3", StartsAt: now.Add(time.Hour), EndsAt: now.Add(24 * time.Hour)},
+	} {
+		if _, err := UR.AddOutOfOffice(ctx, ooo); err != nil {
+			t.Fatalf("failed to add out of office: %v", err)
+		}
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-ooo", PullRequestName: "Vacation", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 2 || !slices.Contains(createdPR.AssignedReviewers, "user3") || !slices.Contains(createdPR.AssignedReviewers, "d1") {
+		t.Fatalf("expected user3 and the delegate d1, got %v", createdPR.AssignedReviewers)
+	}
+
+	decisions, err := repo.Explain(ctx, "pr-ooo")
+	if err != nil {
+		t.Fatalf("failed to explain: %v", err)
+	}
+	away := 0
+	for _, e := range decisions[0].Excluded {
+		if e.Reason == pr.ExcludedOutOfOffice {
+			away++
+		}
+	}
+	if away != 2 {
+		t.Fatalf("expected user1 and user2 out of office, got %+v", decisions[0].Excluded)
+	}
+
+	// is_active is left alone
+	users, err := UR.GetUsers(ctx, []string{"user1", "user2"})
+	if err != nil || len(users) != 2 || !users[0].IsActive || !users[1].IsActive {
+		t.Fatalf("expected active users, got %v, %v", users, err)
+	}
+
+	// once everybody is back the periods no longer matter
+	now = now.Add(48 * time.Hour)
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-back", PullRequestName: "Back", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 2 || slices.Contains(createdPR.AssignedReviewers, "d1") {
+		t.Fatalf("expected two members of the team, got %v", createdPR.AssignedReviewers)
+	}
+}
+
+func TestPullRequestRepo_DeactivationReassigns(t *testing.T) {
+	ctx := context.Background()
+	repo, TR, UR := newTestRepo(t)
+
+	UR.Reassigner = repo
+	TR.Reassigner = repo
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-deact", PullRequestName: "Deactivation", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 2 {
+		t.Fatalf("expected 2 reviewers, got %v", createdPR.AssignedReviewers)
+	}
+	old := createdPR.AssignedReviewers[0]
+	spare := "user1"
+	for _, id := range []string{"user1", "user2", "user3"} {
+		if !slices.Contains(createdPR.AssignedReviewers, id) {
+			spare = id
+		}
+	}
+
+	_, reassignments, err := UR.UpdateUserActivity(ctx, old, false, true)
+	if err != nil {
+		t.Fatalf("failed to deactivate user: %v", err)
+	}
+	if len(reassignments) != 1 || reassignments[0].OldUserID != old || reassignments[0].NewUserID != spare || reassignments[0].Understaffed {
+		t.Fatalf("expected %s to take over from %s, got %+v", spare, old, reassignments)
+	}
+
+	reloaded, err := repo.GetPr(ctx, "pr-deact")
+	if err != nil {
+		t.Fatalf("failed to get PR: %v", err)
+	}
+	if slices.Contains(reloaded.AssignedReviewers, old) || !slices.Contains(reloaded.AssignedReviewers, spare) {
+		t.Fatalf("expected %s replaced by %s, got %v", old, spare, reloaded.AssignedReviewers)
+	}
+
+	// nobody is left to take the reviews over when the whole team goes
+	reassignments, err = TR.Deactivation(ctx, "Awesome Team", true)
+	if err != nil {
+		t.Fatalf("failed to deactivate team: %v", err)
+	}
+	if len(reassignments) != 2 || reassignments[0].NewUserID != "" || !reassignments[0].Understaffed {
+		t.Fatalf("expected both reviews dropped, got %+v", reassignments)
+	}
+	if prs := user.UnderstaffedPRs(reassignments); len(prs) != 1 || prs[0] != "pr-deact" {
+		t.Fatalf("expected pr-deact understaffed, got %v", prs)
+	}
+
+	// the opt-out keeps reviews in place
+	if _, err := testDB.Exec(`UPDATE users SET is_active = true`); err != nil {
+		t.Fatalf("failed to update users: %v", err)
+	}
+	if _, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-keep", PullRequestName: "Keep", AuthorID: "u"}); err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	reassignments, err = TR.Deactivation(ctx, "Awesome Team", false)
+	if err != nil || len(reassignments) != 0 {
+		t.Fatalf("expected no reassignments, got %v, %v", reassignments, err)
+	}
+	kept, err := repo.GetPr(ctx, "pr-keep")
+	if err != nil || len(kept.AssignedReviewers) != 2 {
+		t.Fatalf("expected reviewers kept, got %+v, %v", kept, err)
+	}
+}
+
+func TestPullRequestRepo_Preview(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	if _, err := testDB.Exec(`UPDATE teams SET reviewer_strategy = 'round_robin', reviewers_required = 1`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+
+	req := pr.CreatePullRequestRequest{ID: "pr-preview", PullRequestName: "Preview", AuthorID: "u"}
+	first, err := repo.Preview(ctx, req)
+	if err != nil {
+		t.Fatalf("failed to preview: %v", err)
+	}
+	if len(first.PullRequest.AssignedReviewers) != 1 || len(first.Pool) != 3 || first.Strategy != "round_robin" {
+		t.Fatalf("unexpected preview: %+v", first)
+	}
+
+	// the preview neither creates the PR nor moves the round robin cursor
+	second, err := repo.Preview(ctx, req)
+	if err != nil {
+		t.Fatalf("failed to preview: %v", err)
+	}
+	if second.PullRequest.AssignedReviewers[0] != first.PullRequest.AssignedReviewers[0] {
+		t.Fatalf("expected the same reviewer, got %v and %v", first.PullRequest.AssignedReviewers, second.PullRequest.AssignedReviewers)
+	}
+	if err := repo.Check(ctx, "pr-preview"); err != nil {
+		t.Fatalf("expected no PR, got %v", err)
+	}
+	var cursors int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM team_review_cursor`).Scan(&cursors); err != nil {
+		t.Fatalf("failed to count cursors: %v", err)
+	}
+	if cursors != 0 {
+		t.Fatalf("expected the preview not to create a cursor, got %d", cursors)
+	}
+
+	// a draft is assigned once it is ready for review
+	draft, err := repo.Preview(ctx, pr.CreatePullRequestRequest{ID: "pr-preview-draft", PullRequestName: "Draft", AuthorID: "u", Draft: true})
+	if err != nil {
+		t.Fatalf("failed to preview draft: %v", err)
+	}
+	if draft.PullRequest.Status != pr.StatusDraft || len(draft.PullRequest.AssignedReviewers) != 0 || len(draft.Pool) != 0 {
+		t.Fatalf("expected a draft without reviewers, got %+v", draft)
+	}
+
+	createdPR, err := repo.Create(ctx, req)
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if createdPR.AssignedReviewers[0] != first.PullRequest.AssignedReviewers[0] {
+		t.Fatalf("expected the previewed reviewer, got %v", createdPR.AssignedReviewers)
+	}
+
+	if _, err := repo.Preview(ctx, req); !errors.Is(err, errs.ExistError) {
+		t.Fatalf("expected ExistError, got %v", err)
+	}
+	if _, err := repo.Preview(ctx, pr.CreatePullRequestRequest{AuthorID: "ghost"}); !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected NotFountError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_Rebalance(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	if _, err := testDB.Exec(`UPDATE teams SET reviewers_required = 1, rebalance_threshold = 1`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+	for i, id := range []string{"pr-1", "pr-2", "pr-3"} {
+		if _, err := testDB.Exec(`INSERT INTO pr (id, pr_name, author_id, pr_status, created_ad, reviewers_required) VALUES ($1, $1, 'u', 'OPEN', $2, 1)`,
+			id, time.Now().Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("failed to insert PR: %v", err)
+		}
+		if _, err := testDB.Exec(`INSERT INTO userspr (user_id, request_id) VALUES ('user1', $1)`, id); err != nil {
+			t.Fatalf("failed to assign PR: %v", err)
+		}
+	}
+
+	// started reviews stay with their reviewer
+	if _, err := repo.StartReview(ctx, "pr-1", "user1"); err != nil {
+		t.Fatalf("failed to start review: %v", err)
+	}
+	if _, err := repo.StartReview(ctx, "pr-1", "user2"); !errors.Is(err, errs.NotAssignedError) {
+		t.Fatalf("expected NotAssignedError, got %v", err)
+	}
+
+	planned, err := repo.Rebalance(ctx, "Awesome Team", true)
+	if err != nil {
+		t.Fatalf("failed to plan rebalance: %v", err)
+	}
+	if len(planned) != 2 {
+		t.Fatalf("expected 2 planned moves, got %+v", planned)
+	}
+	var held int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM userspr WHERE user_id = 'user1'`).Scan(&held); err != nil || held != 3 {
+		t.Fatalf("dry run must not move reviews, user1 holds %d (%v)", held, err)
+	}
+
+	moves, err := repo.Rebalance(ctx, "", false)
+	if err != nil {
+		t.Fatalf("failed to rebalance: %v", err)
+	}
+	if len(moves) != len(planned) {
+		t.Fatalf("expected the planned moves %+v, got %+v", planned, moves)
+	}
+	for i, m := range moves {
+		if m != planned[i] || m.PullRequestID == "pr-1" || m.OldUserID != "user1" || m.NewUserID == "u" {
+			t.Fatalf("unexpected move %+v", m)
+		}
+	}
+
+	var history int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM reassignment_history WHERE reason = $1 AND NOT requested`, pr.ReasonRebalance).Scan(&history); err != nil || history != 2 {
+		t.Fatalf("expected 2 automatic rebalance records, got %d (%v)", history, err)
+	}
+
+	if _, err := repo.Rebalance(ctx, "ghost", true); !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected NotFountError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_DutyReviewer(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	RR := &rotation.RotationRepo{DB: testDB}
+	repo.RR = RR
+
+	if _, err := testDB.Exec(`UPDATE teams SET reviewers_required = 1, duty_reviewer = TRUE`); err != nil {
+		t.Fatalf("failed to update team: %v", err)
+	}
+	if _, err := RR.SetRotation(ctx, rotation.Rotation{
+		TeamName: "Awesome Team",
+		Members:  []string{"user3", "user1"},
+		StartsAt: time.Now().Add(-time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to set rotation: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-duty-1", PullRequestName: "Duty", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 || createdPR.AssignedReviewers[0] != "user3" {
+		t.Fatalf("expected the duty reviewer user3, got %v", createdPR.AssignedReviewers)
+	}
+
+	// the next member of the rotation stands in for the inactive duty reviewer
+	if _, err := testDB.Exec(`UPDATE users SET is_active = FALSE WHERE id = 'user3'`); err != nil {
+		t.Fatalf("failed to deactivate user: %v", err)
+	}
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-duty-2", PullRequestName: "Duty", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if len(createdPR.AssignedReviewers) != 1 || createdPR.AssignedReviewers[0] != "user1" {
+		t.Fatalf("expected the stand-in user1, got %v", createdPR.AssignedReviewers)
+	}
+
+	current, _, err := RR.Schedule(ctx, "Awesome Team", time.Now(), 0)
+	if err != nil {
+		t.Fatalf("failed to get schedule: %v", err)
+	}
+	if current.ScheduledID != "user3" || current.UserID != "user1" {
+		t.Fatalf("unexpected shift %+v", current)
+	}
+
+	if _, err := RR.SetRotation(ctx, rotation.Rotation{TeamName: "Awesome Team", Members: []string{"stranger"}, StartsAt: time.Now()}); !errors.Is(err, errs.RotationMemberError) {
+		t.Fatalf("expected RotationMemberError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_SubmitReview(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-review", PullRequestName: "Review", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	reviewer := createdPR.AssignedReviewers[0]
+
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-review", UserID: reviewer, Verdict: pr.VerdictChangesRequested, Message: "rename it"}); err != nil {
+		t.Fatalf("failed to submit review: %v", err)
+	}
+	res, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-review", UserID: reviewer, Verdict: pr.VerdictApproved})
+	if err != nil {
+		t.Fatalf("failed to submit review: %v", err)
+	}
+	if len(res.Verdicts) != 1 || res.Verdicts[0].UserID != reviewer || res.Verdicts[0].Verdict != pr.VerdictApproved {
+		t.Fatalf("expected the latest verdict only, got %+v", res.Verdicts)
+	}
+
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-review", UserID: "u", Verdict: pr.VerdictApproved}); !errors.Is(err, errs.NotAssignedError) {
+		t.Fatalf("expected NotAssignedError, got %v", err)
+	}
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "ghost", UserID: reviewer, Verdict: pr.VerdictApproved}); !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected NotFountError, got %v", err)
+	}
+
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-review"}); err != nil {
+		t.Fatalf("failed to merge PR: %v", err)
+	}
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-review", UserID: reviewer, Verdict: pr.VerdictCommented}); !errors.Is(err, errs.PRMergedError) {
+		t.Fatalf("expected PRMergedError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_MergeQuorum(t *testing.T) {
+	ctx := context.Background()
+	repo, TR, UR := newTestRepo(t)
+
+	approvals, block := 1, true
+	if _, err := TR.UpdateSettings(ctx, team.TeamSettingsInput{TeamName: "Awesome Team", ApprovalsRequired: &approvals, BlockOnChangesRequested: &block}); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-quorum", PullRequestName: "Quorum", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	reviewer := createdPR.AssignedReviewers[0]
+
+	var blocked *pr.MergeBlockedError
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-quorum"}); !errors.As(err, &blocked) {
+		t.Fatalf("expected MergeBlockedError, got %v", err)
+	}
+	if len(blocked.Unmet) != 1 || blocked.Unmet[0].Condition != pr.ConditionApprovals {
+		t.Fatalf("unexpected unmet conditions %+v", blocked.Unmet)
+	}
+
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-quorum", UserID: reviewer, Verdict: pr.VerdictChangesRequested}); err != nil {
+		t.Fatalf("failed to submit review: %v", err)
+	}
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-quorum"}); !errors.As(err, &blocked) || len(blocked.Unmet) != 2 {
+		t.Fatalf("expected both conditions unmet, got %v", err)
+	}
+
+	// only an admin may force the merge
+	for _, forcedBy := range []string{"u", "ghost"} {
+		if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-quorum", Force: true, ForcedBy: forcedBy, Reason: "hotfix"}); !errors.Is(err, errs.NotAdminError) {
+			t.Fatalf("expected NotAdminError for %s, got %v", forcedBy, err)
+		}
+	}
+	// the role is granted by an admin only, the first one is seeded in the database
+	if _, err := UR.SetAdmin(ctx, "u", "u", true); !errors.Is(err, errs.NotAdminError) {
+		t.Fatalf("expected NotAdminError for a self-grant, got %v", err)
+	}
+	if _, err := testDB.Exec(`UPDATE users SET is_admin = true WHERE id = 'user1'`); err != nil {
+		t.Fatalf("failed to seed admin: %v", err)
+	}
+	if _, err := UR.SetAdmin(ctx, "user1", "user3", true); err != nil {
+		t.Fatalf("failed to grant admin: %v", err)
+	}
+
+	merged, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-quorum", Force: true, ForcedBy: "user3", Reason: "hotfix"})
+	if err != nil {
+		t.Fatalf("failed to force merge: %v", err)
+	}
+	if merged.Status != "MERGED" {
+		t.Fatalf("expected MERGED, got %s", merged.Status)
+	}
+
+	var forcedBy string
+	var bypassed int
+	if err := testDB.QueryRow(`SELECT forced_by, cardinality(bypassed) FROM forced_merges WHERE request_id = $1`, "pr-quorum").Scan(&forcedBy, &bypassed); err != nil {
+		t.Fatalf("failed to read forced merge: %v", err)
+	}
+	if forcedBy != "user3" || bypassed != 2 {
+		t.Fatalf("unexpected audit record %s %d", forcedBy, bypassed)
+	}
+
+	createdPR, err = repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-approved", PullRequestName: "Approved", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-approved", UserID: createdPR.AssignedReviewers[0], Verdict: pr.VerdictApproved}); err != nil {
+		t.Fatalf("failed to submit review: %v", err)
+	}
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-approved"}); err != nil {
+		t.Fatalf("expected the quorum to be met, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_MergeThreads(t *testing.T) {
+	ctx := context.Background()
+	repo, TR, _ := newTestRepo(t)
+
+	CR := &comment.CommentRepo{DB: testDB}
+
+	resolved := true
+	if _, err := TR.UpdateSettings(ctx, team.TeamSettingsInput{TeamName: "Awesome Team", RequireResolvedThreads: &resolved}); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+
+	createdPR, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-threads", PullRequestName: "Threads", AuthorID: "u"})
+	if err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+	reviewer := createdPR.AssignedReviewers[0]
+
+	if _, err := CR.Add(ctx, comment.AddRequest{PullRequestID: "pr-threads", AuthorID: "stranger", Body: "hi"}); !errors.Is(err, errs.NotParticipantError) {
+		t.Fatalf("expected NotParticipantError, got %v", err)
+	}
+	root, err := CR.Add(ctx, comment.AddRequest{PullRequestID: "pr-threads", AuthorID: reviewer, Body: "rename it"})
+	if err != nil {
+		t.Fatalf("failed to add comment: %v", err)
+	}
+	reply, err := CR.Add(ctx, comment.AddRequest{PullRequestID: "pr-threads", AuthorID: "u", Body: "done", ParentID: &root.ID})
+	if err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+	if reply.ThreadID != root.ID {
+		t.Fatalf("expected reply in thread %d, got %d", root.ID, reply.ThreadID)
+	}
+
+	var blocked *pr.MergeBlockedError
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-threads"}); !errors.As(err, &blocked) {
+		t.Fatalf("expected MergeBlockedError, got %v", err)
+	}
+	if len(blocked.Unmet) != 1 || blocked.Unmet[0].Condition != pr.ConditionThreadsResolved {
+		t.Fatalf("unexpected unmet conditions %+v", blocked.Unmet)
+	}
+
+	if _, err := CR.Resolve(ctx, comment.ResolveRequest{CommentID: reply.ID, UserID: "u"}, true); err != nil {
+		t.Fatalf("failed to resolve thread: %v", err)
+	}
+	threads, err := CR.List(ctx, "pr-threads", true)
+	if err != nil {
+		t.Fatalf("failed to list threads: %v", err)
+	}
+	if len(threads) != 0 {
+		t.Fatalf("expected no unresolved threads, got %d", len(threads))
+	}
+
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-threads"}); err != nil {
+		t.Fatalf("expected the merge to pass, got %v", err)
+	}
+	if _, err := CR.Add(ctx, comment.AddRequest{PullRequestID: "pr-threads", AuthorID: "u", Body: "late"}); !errors.Is(err, errs.PRMergedError) {
+		t.Fatalf("expected PRMergedError, got %v", err)
+	}
+}
+
+func TestPullRequestRepo_StateMachine(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	if _, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-states", PullRequestName: "States", AuthorID: "u"}); err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+
+	if _, err := repo.ReadyForReview(ctx, "pr-states"); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError, got %v", err)
+	}
+	closed, err := repo.Close(ctx, "pr-states")
+	if err != nil {
+		t.Fatalf("failed to close PR: %v", err)
+	}
+	if closed.Status != pr.StatusClosed {
+		t.Fatalf("expected CLOSED, got %s", closed.Status)
+	}
+
+	// a closed PR is neither reviewed, reassigned nor labelled
+	reviewer := closed.AssignedReviewers[0]
+	if _, err := repo.StartReview(ctx, "pr-states", reviewer); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError on start, got %v", err)
+	}
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-states", UserID: reviewer, Verdict: pr.VerdictApproved}); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError on review, got %v", err)
+	}
+	if _, _, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-states", CurrentReviewerID: reviewer}); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError on reassign, got %v", err)
+	}
+	labels := []string{"css"}
+	if _, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-states", EditedBy: "u", Labels: &labels}); !errors.Is(err, errs.PRNotOpenError) {
+		t.Fatalf("expected PRNotOpenError on labels, got %v", err)
+	}
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-states"}); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError, got %v", err)
+	}
+
+	reopened, err := repo.Reopen(ctx, "pr-states")
+	if err != nil {
+		t.Fatalf("failed to reopen PR: %v", err)
+	}
+	if reopened.Status != pr.StatusOpen {
+		t.Fatalf("expected OPEN, got %s", reopened.Status)
+	}
+
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-states"}); err != nil {
+		t.Fatalf("failed to merge PR: %v", err)
+	}
+	if _, err := repo.Reopen(ctx, "pr-states"); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError, got %v", err)
+	}
+	if _, err := repo.Close(ctx, "pr-states"); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError, got %v", err)
+	}
+	if _, err := repo.Close(ctx, "ghost"); !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected NotFountError, got %v", err)
+	}
+
+	if _, err := testDB.Exec(`UPDATE pr SET pr_status = 'UNKNOWN' WHERE id = 'pr-states'`); err == nil {
+		t.Fatalf("expected the status check to reject UNKNOWN")
+	}
+}
+
+func TestPullRequestRepo_Draft(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	draft, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-draft", PullRequestName: "Draft", AuthorID: "u", Draft: true})
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	if draft.Status != pr.StatusDraft || len(draft.AssignedReviewers) != 0 {
+		t.Fatalf("unexpected draft %+v", draft)
+	}
+
+	var reviews, counted int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM userspr WHERE request_id = 'pr-draft'`).Scan(&reviews); err != nil {
+		t.Fatalf("failed to count reviews: %v", err)
+	}
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM usershistory`).Scan(&counted); err != nil {
+		t.Fatalf("failed to count history: %v", err)
+	}
+	if reviews != 0 || counted != 0 {
+		t.Fatalf("expected no reviews for a draft, got %d reviews and %d history rows", reviews, counted)
+	}
+
+	got, err := repo.GetPr(ctx, "pr-draft")
+	if err != nil {
+		t.Fatalf("failed to get draft: %v", err)
+	}
+	if got.Status != pr.StatusDraft || len(got.AssignedReviewers) != 0 {
+		t.Fatalf("unexpected draft %+v", got)
+	}
+
+	// a draft is edited but not reviewed
+	labels := []string{"css"}
+	labelled, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-draft", EditedBy: "u", Labels: &labels})
+	if err != nil {
+		t.Fatalf("failed to label draft: %v", err)
+	}
+	if len(labelled.Labels) != 1 || labelled.Labels[0] != "css" || labelled.Version != 2 {
+		t.Fatalf("unexpected draft %+v", labelled)
+	}
+	if _, err := repo.StartReview(ctx, "pr-draft", "user1"); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError on start, got %v", err)
+	}
+	if _, err := repo.SubmitReview(ctx, pr.SubmitReviewRequest{PullRequestID: "pr-draft", UserID: "user1", Verdict: pr.VerdictApproved}); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError on review, got %v", err)
+	}
+	if _, _, err := repo.AssignedReviewer(ctx, pr.ReassignRequest{PullRequestID: "pr-draft", CurrentReviewerID: "user1"}); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError on reassign, got %v", err)
+	}
+
+	// the roster changes before the draft is ready
+	if _, err := testDB.Exec(`UPDATE users SET is_active = false WHERE id IN ('user1', 'user2')`); err != nil {
+		t.Fatalf("failed to deactivate users: %v", err)
+	}
+
+	ready, err := repo.ReadyForReview(ctx, "pr-draft")
+	if err != nil {
+		t.Fatalf("failed to mark draft ready: %v", err)
+	}
+	if ready.Status != pr.StatusOpen || len(ready.AssignedReviewers) != 1 || ready.AssignedReviewers[0] != "user3" {
+		t.Fatalf("unexpected PR %+v", ready)
+	}
+	if err := testDB.QueryRow(`SELECT pr_count FROM usershistory WHERE user_id = 'user3'`).Scan(&counted); err != nil || counted != 1 {
+		t.Fatalf("expected the review to be counted, got %d (%v)", counted, err)
+	}
+	if _, err := repo.ReadyForReview(ctx, "pr-draft"); !errors.Is(err, errs.InvalidTransitionError) {
+		t.Fatalf("expected InvalidTransitionError, got %v", err)
+	}
+
+	if _, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-abandoned", PullRequestName: "Abandoned", AuthorID: "u", Draft: true}); err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	if _, err := repo.Close(ctx, "pr-abandoned"); err != nil {
+		t.Fatalf("failed to close draft: %v", err)
+	}
+	reopened, err := repo.Reopen(ctx, "pr-abandoned")
+	if err != nil {
+		t.Fatalf("failed to reopen draft: %v", err)
+	}
+	if reopened.Status != pr.StatusDraft {
+		t.Fatalf("expected a reopened draft to stay DRAFT, got %s", reopened.Status)
+	}
+}
+
+func TestPullRequestRepo_List(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i, id := range []string{"pr-a", "pr-b", "pr-c", "pr-d", "pr-e"} {
+		if _, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: id, PullRequestName: id, AuthorID: "u", Draft: id == "pr-e"}); err != nil {
+			t.Fatalf("failed to create PR: %v", err)
+		}
+		if _, err := testDB.Exec(`UPDATE pr SET created_ad = $1 WHERE id = $2`, start.Add(time.Duration(i)*time.Hour), id); err != nil {
+			t.Fatalf("failed to set created_ad: %v", err)
+		}
+	}
+	if _, err := repo.Merged(ctx, pr.MergeRequest{PullRequestID: "pr-b"}); err != nil {
+		t.Fatalf("failed to merge PR: %v", err)
+	}
+
+	var ids []string
+	filter := pr.ListFilter{Order: pr.OrderDesc, Limit: 2}
+	for pages := 0; ; pages++ {
+		page, err := repo.List(ctx, filter)
+		if err != nil {
+			t.Fatalf("failed to list PRs: %v", err)
+		}
+		for _, p := range page.PullRequests {
+			ids = append(ids, p.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if filter.After, err = pr.DecodeCursor(page.NextCursor); err != nil {
+			t.Fatalf("failed to decode cursor: %v", err)
+		}
+		if pages > 3 {
+			t.Fatalf("pagination does not end")
+		}
+	}
+	if strings.Join(ids, ",") != "pr-e,pr-d,pr-c,pr-b,pr-a" {
+		t.Fatalf("unexpected order %v", ids)
+	}
+
+	page, err := repo.List(ctx, pr.ListFilter{Statuses: []string{pr.StatusMerged}, TeamName: "Awesome Team"})
+	if err != nil {
+		t.Fatalf("failed to list PRs: %v", err)
+	}
+	if len(page.PullRequests) != 1 || page.PullRequests[0].ID != "pr-b" || page.PullRequests[0].MergedAt == nil {
+		t.Fatalf("unexpected merged PRs %+v", page.PullRequests)
+	}
+
+	page, err = repo.List(ctx, pr.ListFilter{Statuses: []string{pr.StatusDraft}})
+	if err != nil {
+		t.Fatalf("failed to list PRs: %v", err)
+	}
+	if len(page.PullRequests) != 1 || len(page.PullRequests[0].AssignedReviewers) != 0 {
+		t.Fatalf("unexpected drafts %+v", page.PullRequests)
+	}
+
+	got, err := repo.GetPr(ctx, "pr-a")
+	if err != nil {
+		t.Fatalf("failed to get PR: %v", err)
+	}
+	reviewer := got.AssignedReviewers[0]
+	createdTo := start.Add(2 * time.Hour)
+	page, err = repo.List(ctx, pr.ListFilter{ReviewerID: reviewer, CreatedTo: &createdTo, Order: pr.OrderAsc})
+	if err != nil {
+		t.Fatalf("failed to list PRs: %v", err)
+	}
+	for _, p := range page.PullRequests {
+		if !slices.Contains(p.AssignedReviewers, reviewer) || !p.CreatedAt.Before(createdTo) {
+			t.Fatalf("unexpected PR %+v", p)
+		}
+	}
+	if len(page.PullRequests) == 0 || page.PullRequests[0].ID != "pr-a" {
+		t.Fatalf("expected pr-a first, got %+v", page.PullRequests)
+	}
+}
+
+func TestPullRequestRepo_Update(t *testing.T) {
+	ctx := context.Background()
+	repo, _, _ := newTestRepo(t)
+
+	if _, err := repo.Create(ctx, pr.CreatePullRequestRequest{ID: "pr-edit", PullRequestName: "Typo", AuthorID: "u", Labels: []string{"api"}}); err != nil {
+		t.Fatalf("failed to create PR: %v", err)
+	}
+
+	name, priority, labels := "Fix typo", pr.PriorityHigh, []string{"API", "docs"}
+	updated, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-edit", EditedBy: "u", Version: 1, PullRequestName: &name, Priority: &priority, Labels: &labels})
+	if err != nil {
+		t.Fatalf("failed to update PR: %v", err)
+	}
+	if updated.PullRequestName != name || updated.Priority != priority || updated.Version != 2 || strings.Join(updated.Labels, ",") != "api,docs" {
+		t.Fatalf("unexpected PR %+v", updated)
+	}
+
+	// unchanged values do not produce a version
+	same, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-edit", EditedBy: "u", PullRequestName: &name})
+	if err != nil {
+		t.Fatalf("failed to update PR: %v", err)
+	}
+	if same.Version != 2 {
+		t.Fatalf("expected version 2, got %d", same.Version)
+	}
+
+	description := "closes #12"
+	if _, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-edit", EditedBy: "user1", Version: 1, Description: &description}); !errors.Is(err, errs.VersionConflictError) {
+		t.Fatalf("expected VersionConflictError, got %v", err)
+	}
+	if _, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-edit", EditedBy: "ghost", Description: &description}); !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected NotFountError for an unknown editor, got %v", err)
+	}
+	if _, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-edit", EditedBy: "user1", Description: &description}); err != nil {
+		t.Fatalf("failed to update PR: %v", err)
+	}
+
+	history, err := repo.History(ctx, "pr-edit")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 2 || len(history[0].Changes) != 3 || history[1].EditedBy != "user1" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if c := history[1].Changes[0]; c.Field != pr.FieldDescription || string(c.OldValue) != `""` || string(c.NewValue) != `"closes #12"` {
+		t.Fatalf("unexpected change %+v", c)
+	}
+
+	if _, err := repo.Close(ctx, "pr-edit"); err != nil {
+		t.Fatalf("failed to close PR: %v", err)
+	}
+	if _, err := repo.Update(ctx, pr.UpdateRequest{PullRequestID: "pr-edit", EditedBy: "u", PullRequestName: &description}); !errors.Is(err, errs.PRNotOpenError) {
+		t.Fatalf("expected PRNotOpenError, got %v", err)
+	}
+	if _, err := repo.History(ctx, "ghost"); !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected NotFountError, got %v", err)
+	}
+}
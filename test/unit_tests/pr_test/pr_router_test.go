@@ -3,11 +3,14 @@ package pr_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"pullreq/internal/errs"
 	"pullreq/internal/pr"
@@ -58,7 +61,7 @@ func TestCreatePullRequest(t *testing.T) {
 func TestMergePullRequest(t *testing.T) {
 	mockRepo := routermocks.NewPullRequestRepoInterface(t)
 
-	mockRepo.On("Merged", context.Background(), "pr-1001").Return(&pr.PullRequest{
+	mockRepo.On("Merged", context.Background(), pr.MergeRequest{PullRequestID: "pr-1001"}).Return(&pr.PullRequest{
 		ID:                "pr-1001",
 		PullRequestName:   "Add search",
 		AuthorID:          "u1",
@@ -92,7 +95,7 @@ func TestMergePullRequest(t *testing.T) {
 func TestReass_1(t *testing.T) {
 	mockRepo := routermocks.NewPullRequestRepoInterface(t)
 
-	mockRepo.On("AssignedReviewer", context.Background(), "pr-1001", "u2").Return(&pr.PullRequest{
+	mockRepo.On("AssignedReviewer", context.Background(), pr.ReassignRequest{PullRequestID: "pr-1001", CurrentReviewerID: "u2"}).Return(&pr.PullRequest{
 		ID:                "pr-1001",
 		PullRequestName:   "Add search",
 		AuthorID:          "u1",
@@ -169,6 +172,32 @@ func TestCreatePullRequest_ExistError(t *testing.T) {
 	}
 }
 
+// Test Create of a draft
+func TestCreatePullRequest_Draft(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	reqBody := pr.CreatePullRequestRequest{ID: "pr-1001", PullRequestName: "Add search", AuthorID: "u1", Draft: true}
+	mockRepo.On("Create", context.Background(), reqBody).Return(&pr.PullRequest{
+		ID:                "pr-1001",
+		PullRequestName:   "Add search",
+		AuthorID:          "u1",
+		Status:            pr.StatusDraft,
+		AssignedReviewers: []string{},
+	}, nil)
+
+	bodyJSON := `{"pull_request_id":"pr-1001","pull_request_name":"Add search","author_id":"u1","draft":true}`
+	req := httptest.NewRequest("POST", "/pullRequest/create", bytes.NewBufferString(bodyJSON))
+	w := httptest.NewRecorder()
+
+	router.CreatePullRequest(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusCreated || !strings.Contains(string(body), `"status":"DRAFT"`) || !strings.Contains(string(body), `"assigned_reviewers":[]`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(body))
+	}
+}
+
 // Test Merge with invalid JSON
 func TestMerge_InvalidJSON(t *testing.T) {
 	mockRepo := routermocks.NewPullRequestRepoInterface(t)
@@ -190,7 +219,7 @@ func TestMerge_PRNotFound(t *testing.T) {
 	mockRepo := routermocks.NewPullRequestRepoInterface(t)
 	router := &pr.PrRouter{PR: mockRepo}
 
-	mockRepo.On("Merged", context.Background(), "pr-1001").Return(nil, errs.NotFountError)
+	mockRepo.On("Merged", context.Background(), pr.MergeRequest{PullRequestID: "pr-1001"}).Return(nil, errs.NotFountError)
 
 	bodyJSON := `{"pull_request_id":"pr-1001"}`
 	req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewBuffer([]byte(bodyJSON)))
@@ -204,6 +233,74 @@ func TestMerge_PRNotFound(t *testing.T) {
 	}
 }
 
+// Test Merge when the quorum is not met
+func TestMerge_Blocked(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	blocked := &pr.MergeBlockedError{Unmet: []pr.UnmetCondition{
+		{Condition: pr.ConditionApprovals, Detail: "0 of 2 required approvals"},
+	}}
+	mockRepo.On("Merged", context.Background(), pr.MergeRequest{PullRequestID: "pr-1001"}).Return(nil, blocked)
+
+	req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewBufferString(`{"pull_request_id":"pr-1001"}`))
+	w := httptest.NewRecorder()
+
+	router.Merge(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", w.Code)
+	}
+	var body struct {
+		Error struct {
+			Code    string              `json:"code"`
+			Details []pr.UnmetCondition `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Error.Code != string(errs.CodeMergeBlocked) || len(body.Error.Details) != 1 || body.Error.Details[0].Condition != pr.ConditionApprovals {
+		t.Fatalf("unexpected response %+v", body)
+	}
+}
+
+// Test Merge forced without naming who forces it
+func TestMerge_ForceWithoutForcedBy(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewBufferString(`{"pull_request_id":"pr-1001","force":true}`))
+	w := httptest.NewRecorder()
+
+	router.Merge(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+// Test Merge forced by somebody who is not an admin
+func TestMerge_ForceByNonAdmin(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	mergeReq := pr.MergeRequest{PullRequestID: "pr-1001", Force: true, ForcedBy: "u2", Reason: "hotfix"}
+	mockRepo.On("Merged", context.Background(), mergeReq).Return(nil, errs.NotAdminError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/merge", bytes.NewBufferString(`{"pull_request_id":"pr-1001","force":true,"forced_by":"u2","reason":"hotfix"}`))
+	w := httptest.NewRecorder()
+
+	router.Merge(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), string(errs.CodeNotAdmin)) {
+		t.Fatalf("expected NOT_ADMIN, got %s", w.Body.String())
+	}
+}
+
 // Test AssignedReviewer with domain errors
 func TestAssignedReviewer_DomainErrors(t *testing.T) {
 	mockRepo := routermocks.NewPullRequestRepoInterface(t)
@@ -214,13 +311,15 @@ func TestAssignedReviewer_DomainErrors(t *testing.T) {
 		expectedCode int
 	}{
 		{errs.PRMergedError, http.StatusConflict},
+		{errs.InvalidTransitionError, http.StatusConflict},
 		{errs.NotAssignedError, http.StatusConflict},
 		{errs.NoCandidateError, http.StatusConflict},
+		{errs.InvalidCandidateError, http.StatusConflict},
 	}
 
 	for _, tt := range tests {
-		mockRepo.On("AssignedReviewer", context.Background(), "pr-1001", "u2").
-			Return(nil, "", tt.err)
+		mockRepo.On("AssignedReviewer", context.Background(), pr.ReassignRequest{PullRequestID: "pr-1001", CurrentReviewerID: "u2"}).
+			Return(nil, "", tt.err).Once()
 
 		bodyJSON := `{"pull_request_id":"pr-1001","old_user_id":"u2"}`
 		req := httptest.NewRequest("POST", "/pullRequest/reassign", bytes.NewBuffer([]byte(bodyJSON)))
@@ -250,3 +349,421 @@ func TestAssignedReviewer_InvalidJSON(t *testing.T) {
 		t.Fatalf("expected status 400, got %d", resp.StatusCode)
 	}
 }
+
+func TestSetLabels(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	// labels go through Update, so they are versioned like any other edit
+	labels := []string{"postgres"}
+	mockRepo.On("Update", context.Background(), pr.UpdateRequest{PullRequestID: "pr-1001", EditedBy: "u1", Labels: &labels}).Return(&pr.PullRequest{
+		ID:      "pr-1001",
+		Status:  "OPEN",
+		Labels:  []string{"postgres"},
+		Version: 2,
+	}, nil)
+	css := []string{"css"}
+	mockRepo.On("Update", context.Background(), pr.UpdateRequest{PullRequestID: "pr-merged", EditedBy: "u1", Labels: &css}).Return(nil, errs.PRNotOpenError)
+	cleared := []string{}
+	mockRepo.On("Update", context.Background(), pr.UpdateRequest{PullRequestID: "pr-1001", EditedBy: "ghost", Version: 2, Labels: &cleared}).Return(nil, errs.NotFountError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/setLabels", bytes.NewBufferString(`{"pull_request_id":"pr-1001","edited_by":"u1","labels":["postgres"]}`))
+	w := httptest.NewRecorder()
+	router.SetLabels(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(string(body), `"labels":["postgres"]`) || !strings.Contains(string(body), `"version":2`) {
+		t.Fatalf("unexpected response body: %s", string(body))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/setLabels", bytes.NewBufferString(`{"pull_request_id":"pr-merged","edited_by":"u1","labels":["css"]}`))
+	w = httptest.NewRecorder()
+	router.SetLabels(w, req)
+
+	body, _ = io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusConflict || !strings.Contains(string(body), `"PR_NOT_OPEN"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(body))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/setLabels", bytes.NewBufferString(`{"pull_request_id":"pr-1001","edited_by":"ghost","version":2,"labels":null}`))
+	w = httptest.NewRecorder()
+	router.SetLabels(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/setLabels", bytes.NewBufferString(`{"pull_request_id":"pr-1001","labels":["css"]}`))
+	w = httptest.NewRecorder()
+	router.SetLabels(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without edited_by, got %d", w.Code)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	mockRepo.On("Explain", context.Background(), "pr-1001").Return([]pr.Decision{{
+		Kind:      pr.DecisionCreate,
+		Strategy:  "least_loaded",
+		Seed:      18446744073709551615,
+		Reviewers: []string{"u2"},
+		Pool:      []pr.PoolCandidate{{UserID: "u2", Source: pr.SourceTeam, OpenReviews: 1}},
+		Excluded:  []pr.Exclusion{{UserID: "u1", Reason: pr.ExcludedAuthor}},
+	}}, nil)
+	mockRepo.On("Explain", context.Background(), "pr-404").Return(nil, errs.NotFountError)
+
+	req := httptest.NewRequest("GET", "/pullRequest/explain?pull_request_id=pr-1001", nil)
+	w := httptest.NewRecorder()
+	router.Explain(w, req)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	// the seed does not fit into a JSON number without losing precision
+	if !strings.Contains(string(body), `"seed":"18446744073709551615"`) {
+		t.Fatalf("unexpected response body: %s", string(body))
+	}
+	if !strings.Contains(string(body), `"excluded":[{"user_id":"u1","reason":"author"}]`) {
+		t.Fatalf("unexpected response body: %s", string(body))
+	}
+
+	req = httptest.NewRequest("GET", "/pullRequest/explain?pull_request_id=pr-404", nil)
+	w = httptest.NewRecorder()
+	router.Explain(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/pullRequest/explain", nil)
+	w = httptest.NewRecorder()
+	router.Explain(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestPreviewPullRequest(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	reqBody := pr.CreatePullRequestRequest{ID: "pr-1", PullRequestName: "Add search", AuthorID: "u1"}
+	mockRepo.On("Preview", context.Background(), reqBody).Return(&pr.Preview{
+		PullRequest: &pr.PullRequest{ID: "pr-1", AuthorID: "u1", Status: "OPEN", AssignedReviewers: []string{"u2"}, ReviewersRequired: 2, Understaffed: true},
+		Strategy:    "least_loaded",
+		Pool:        []pr.PoolCandidate{{UserID: "u2", Source: pr.SourceTeam, OpenReviews: 3}},
+		Excluded:    []pr.Exclusion{{UserID: "u1", Reason: pr.ExcludedAuthor}},
+	}, nil)
+	mockRepo.On("Preview", context.Background(), pr.CreatePullRequestRequest{AuthorID: "ghost"}).Return(nil, errs.NotFountError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/preview", bytes.NewBufferString(`{"pull_request_id":"pr-1","pull_request_name":"Add search","author_id":"u1"}`))
+	w := httptest.NewRecorder()
+	router.PreviewPullRequest(w, req)
+
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(string(resBody), `"candidate_pool":[{"user_id":"u2","source":"team","open_reviews":3}]`) {
+		t.Fatalf("unexpected response body: %s", string(resBody))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/preview", bytes.NewBufferString(`{"author_id":"ghost"}`))
+	w = httptest.NewRecorder()
+	router.PreviewPullRequest(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/preview", bytes.NewBufferString(`{bad`))
+	w = httptest.NewRecorder()
+	router.PreviewPullRequest(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	mockRepo.On("Rebalance", context.Background(), "backend", true).Return([]pr.Move{
+		{TeamName: "backend", PullRequestID: "pr-1", OldUserID: "u1", NewUserID: "u2"},
+	}, nil)
+	mockRepo.On("Rebalance", context.Background(), "ghost", false).Return(nil, errs.NotFountError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/rebalance", bytes.NewBufferString(`{"team_name":"backend","dry_run":true}`))
+	w := httptest.NewRecorder()
+	router.Rebalance(w, req)
+
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(string(resBody), `"dry_run":true`) || !strings.Contains(string(resBody), `"new_user_id":"u2"`) {
+		t.Fatalf("unexpected response body: %s", string(resBody))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/rebalance", bytes.NewBufferString(`{"team_name":"ghost"}`))
+	w = httptest.NewRecorder()
+	router.Rebalance(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestStartReview(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	startedAt := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	mockRepo.On("StartReview", context.Background(), "pr-1", "u2").Return(&pr.Review{PullRequestID: "pr-1", UserID: "u2", StartedAt: &startedAt}, nil)
+	mockRepo.On("StartReview", context.Background(), "pr-1", "u3").Return(nil, errs.NotAssignedError)
+	mockRepo.On("StartReview", context.Background(), "pr-draft", "u2").Return(nil, errs.InvalidTransitionError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/startReview", bytes.NewBufferString(`{"pull_request_id":"pr-1","user_id":"u2"}`))
+	w := httptest.NewRecorder()
+	router.StartReview(w, req)
+
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK || !strings.Contains(string(resBody), `"started_at":"2025-01-02T10:00:00Z"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/startReview", bytes.NewBufferString(`{"pull_request_id":"pr-1","user_id":"u3"}`))
+	w = httptest.NewRecorder()
+	router.StartReview(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", w.Code)
+	}
+	req = httptest.NewRequest("POST", "/pullRequest/startReview", bytes.NewBufferString(`{"pull_request_id":"pr-draft","user_id":"u2"}`))
+	w = httptest.NewRecorder()
+	router.StartReview(w, req)
+	resBody, _ = io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusConflict || !strings.Contains(string(resBody), `"INVALID_TRANSITION"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+}
+
+func TestSubmitReview(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	submittedAt := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	approve := pr.SubmitReviewRequest{PullRequestID: "pr-1", UserID: "u2", Verdict: pr.VerdictApproved, Message: "lgtm"}
+	mockRepo.On("SubmitReview", context.Background(), approve).Return(&pr.PullRequest{
+		ID:                "pr-1",
+		Status:            "OPEN",
+		AssignedReviewers: []string{"u2"},
+		Verdicts:          []pr.Verdict{{UserID: "u2", Verdict: pr.VerdictApproved, Message: "lgtm", SubmittedAt: submittedAt}},
+	}, nil)
+	merged := pr.SubmitReviewRequest{PullRequestID: "pr-2", UserID: "u2", Verdict: pr.VerdictCommented}
+	mockRepo.On("SubmitReview", context.Background(), merged).Return(nil, errs.PRMergedError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/review", bytes.NewBufferString(`{"pull_request_id":"pr-1","user_id":"u2","verdict":"APPROVED","message":"lgtm"}`))
+	w := httptest.NewRecorder()
+	router.SubmitReview(w, req)
+
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK || !strings.Contains(string(resBody), `"verdicts":[{"user_id":"u2","verdict":"APPROVED","message":"lgtm","submitted_at":"2025-01-02T10:00:00Z"}]`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/review", bytes.NewBufferString(`{"pull_request_id":"pr-2","user_id":"u2","verdict":"COMMENTED"}`))
+	w = httptest.NewRecorder()
+	router.SubmitReview(w, req)
+	resBody, _ = io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusConflict || !strings.Contains(string(resBody), `"PR_MERGED"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/review", bytes.NewBufferString(`{"pull_request_id":"pr-1","user_id":"u2","verdict":"LGTM"}`))
+	w = httptest.NewRecorder()
+	router.SubmitReview(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestChangeStatus(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	mockRepo.On("Close", context.Background(), "pr-1").Return(&pr.PullRequest{ID: "pr-1", Status: pr.StatusClosed}, nil)
+	mockRepo.On("Reopen", context.Background(), "pr-2").Return(nil, fmt.Errorf("%w: MERGED -> OPEN", errs.InvalidTransitionError))
+	mockRepo.On("ReadyForReview", context.Background(), "ghost").Return(nil, errs.NotFountError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/close", bytes.NewBufferString(`{"pull_request_id":"pr-1"}`))
+	w := httptest.NewRecorder()
+	router.Close(w, req)
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK || !strings.Contains(string(resBody), `"status":"CLOSED"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/reopen", bytes.NewBufferString(`{"pull_request_id":"pr-2"}`))
+	w = httptest.NewRecorder()
+	router.Reopen(w, req)
+	resBody, _ = io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusConflict || !strings.Contains(string(resBody), `"INVALID_TRANSITION"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	req = httptest.NewRequest("POST", "/pullRequest/readyForReview", bytes.NewBufferString(`{"pull_request_id":"ghost"}`))
+	w = httptest.NewRecorder()
+	router.ReadyForReview(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGet(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	createdAt := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	mockRepo.On("GetPr", context.Background(), "pr-1").Return(&pr.PullRequest{
+		ID:                "pr-1",
+		Status:            pr.StatusDraft,
+		AssignedReviewers: []string{},
+		CreatedAt:         &createdAt,
+	}, nil)
+	mockRepo.On("GetPr", context.Background(), "ghost").Return(nil, errs.NotFountError)
+
+	req := httptest.NewRequest("GET", "/pullRequest/get?pull_request_id=pr-1", nil)
+	w := httptest.NewRecorder()
+	router.Get(w, req)
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK || !strings.Contains(string(resBody), `"assigned_reviewers":[]`) || !strings.Contains(string(resBody), `"created_at":"2025-01-02T10:00:00Z"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	req = httptest.NewRequest("GET", "/pullRequest/get?pull_request_id=ghost", nil)
+	w = httptest.NewRecorder()
+	router.Get(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestList(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	createdFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursor := pr.Cursor{At: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), ID: "pr-5"}
+	filter := pr.ListFilter{
+		Statuses:    []string{pr.StatusOpen, pr.StatusDraft},
+		TeamName:    "backend",
+		ReviewerID:  "u2",
+		CreatedFrom: &createdFrom,
+		SortBy:      pr.SortCreatedAt,
+		Order:       pr.OrderAsc,
+		Limit:       2,
+		After:       &cursor,
+	}
+	mockRepo.On("List", context.Background(), filter).Return(&pr.ListPage{
+		PullRequests: []pr.PullRequest{{ID: "pr-6", Status: pr.StatusOpen, AssignedReviewers: []string{"u2"}}},
+		NextCursor:   "next",
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/pullRequest/list?status=open,DRAFT&team_name=backend&reviewer_id=u2&created_from=2025-01-01T00:00:00Z&order=asc&limit=2&cursor="+cursor.Encode(), nil)
+	w := httptest.NewRecorder()
+	router.List(w, req)
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK || !strings.Contains(string(resBody), `"next_cursor":"next"`) || !strings.Contains(string(resBody), `"pull_request_id":"pr-6"`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	for _, query := range []string{
+		"status=REJECTED",
+		"created_to=yesterday",
+		"sort=name",
+		"order=up",
+		"limit=0",
+		"limit=101",
+		"cursor=not-a-cursor",
+	} {
+		req := httptest.NewRequest("GET", "/pullRequest/list?"+query, nil)
+		w := httptest.NewRecorder()
+		router.List(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	name := "Add full-text search"
+	rename := pr.UpdateRequest{PullRequestID: "pr-1", EditedBy: "github-sync", Version: 2, PullRequestName: &name}
+	mockRepo.On("Update", context.Background(), rename).Return(&pr.PullRequest{ID: "pr-1", PullRequestName: name, Version: 3}, nil)
+	stale := pr.UpdateRequest{PullRequestID: "pr-1", EditedBy: "github-sync", Version: 1, PullRequestName: &name}
+	mockRepo.On("Update", context.Background(), stale).Return(nil, errs.VersionConflictError)
+	merged := pr.UpdateRequest{PullRequestID: "pr-2", EditedBy: "u1", PullRequestName: &name}
+	mockRepo.On("Update", context.Background(), merged).Return(nil, errs.PRNotOpenError)
+
+	req := httptest.NewRequest("POST", "/pullRequest/update", bytes.NewBufferString(`{"pull_request_id":"pr-1","edited_by":"github-sync","version":2,"pull_request_name":"Add full-text search"}`))
+	w := httptest.NewRecorder()
+	router.Update(w, req)
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK || !strings.Contains(string(resBody), `"version":3`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+
+	for body, code := range map[string]string{
+		`{"pull_request_id":"pr-1","edited_by":"github-sync","version":1,"pull_request_name":"Add full-text search"}`: `"VERSION_CONFLICT"`,
+		`{"pull_request_id":"pr-2","edited_by":"u1","pull_request_name":"Add full-text search"}`:                      `"PR_NOT_OPEN"`,
+	} {
+		req := httptest.NewRequest("POST", "/pullRequest/update", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.Update(w, req)
+		resBody, _ := io.ReadAll(w.Result().Body)
+		if w.Code != http.StatusConflict || !strings.Contains(string(resBody), code) {
+			t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+		}
+	}
+
+	for _, body := range []string{
+		`{"pull_request_id":"pr-1","pull_request_name":"x"}`,
+		`{"pull_request_id":"pr-1","edited_by":"u1","pull_request_name":" "}`,
+		`{"pull_request_id":"pr-1","edited_by":"u1","priority":"urgent"}`,
+	} {
+		req := httptest.NewRequest("POST", "/pullRequest/update", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.Update(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	mockRepo := routermocks.NewPullRequestRepoInterface(t)
+	router := &pr.PrRouter{PR: mockRepo}
+
+	editedAt := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	mockRepo.On("History", context.Background(), "pr-1").Return([]pr.Edit{{
+		Version:  2,
+		EditedBy: "u1",
+		EditedAt: editedAt,
+		Changes:  []pr.FieldChange{{Field: pr.FieldPriority, OldValue: []byte(`"normal"`), NewValue: []byte(`"high"`)}},
+	}}, nil)
+
+	req := httptest.NewRequest("GET", "/pullRequest/history?pull_request_id=pr-1", nil)
+	w := httptest.NewRecorder()
+	router.History(w, req)
+	resBody, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusOK || !strings.Contains(string(resBody), `{"field":"priority","old_value":"normal","new_value":"high"}`) {
+		t.Fatalf("unexpected response %d: %s", w.Code, string(resBody))
+	}
+}
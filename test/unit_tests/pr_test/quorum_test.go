@@ -0,0 +1,38 @@
+package pr_test
+
+import (
+	"context"
+	"testing"
+
+	"pullreq/internal/errs"
+	"pullreq/internal/pr"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerged_ForceRequiresAdmin(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{name: "not_admin", rows: sqlmock.NewRows([]string{"is_admin"}).AddRow(false)},
+		{name: "unknown_user", rows: sqlmock.NewRows([]string{"is_admin"})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			// the PR is neither read nor locked
+			mock.ExpectQuery(`SELECT is_admin FROM users WHERE id = \$1`).
+				WithArgs("u2").
+				WillReturnRows(tc.rows)
+
+			repo := &pr.PullRequestRepo{DB: db}
+			_, err = repo.Merged(context.Background(), pr.MergeRequest{PullRequestID: "pr-1", Force: true, ForcedBy: "u2", Reason: "hotfix"})
+			require.ErrorIs(t, err, errs.NotAdminError)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package pr_test
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+
+	"pullreq/internal/pr"
+	"pullreq/internal/team"
+	"pullreq/internal/user"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func candidates(load map[string]int) []pr.Candidate {
+	res := make([]pr.Candidate, 0, len(load))
+	for id, open := range load {
+		res = append(res, pr.Candidate{User: &user.User{Id: id, IsActive: true}, OpenReviews: open})
+	}
+	return res
+}
+
+func TestLeastLoadedSelector(t *testing.T) {
+	s := &pr.LeastLoadedSelector{}
+
+	for _, tc := range []struct {
+		name  string
+		load  map[string]int
+		count int
+		want  []string
+	}{
+		{name: "fewest_first", load: map[string]int{"a": 3, "b": 0, "c": 1, "d": 5}, count: 2, want: []string{"b", "c"}},
+		{name: "all_when_short", load: map[string]int{"a": 2, "b": 1}, count: 5, want: []string{"b", "a"}},
+		{name: "none_requested", load: map[string]int{"a": 0}, count: 0, want: []string{}},
+		{name: "empty_pool", load: map[string]int{}, count: 2, want: []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Select(context.Background(), pr.SelectionInput{Candidates: candidates(tc.load), Count: tc.count})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestLeastLoadedSelector_TiesAreShuffled(t *testing.T) {
+	s := &pr.LeastLoadedSelector{}
+	pool := candidates(map[string]int{"a": 1, "b": 1, "c": 1, "d": 0})
+
+	seen := make(map[string]bool)
+	for seed := uint64(0); seed < 50; seed++ {
+		got, err := s.Select(context.Background(), pr.SelectionInput{
+			Candidates: pool,
+			Count:      2,
+			Rand:       rand.New(rand.NewPCG(seed, seed)),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "d", got[0])
+		seen[got[1]] = true
+	}
+	require.Len(t, seen, 3)
+}
+
+func TestRoundRobinSelector_ContinuesAfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO team_review_cursor`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT COALESCE\(last_user_id, ''\) FROM team_review_cursor WHERE team_id = \$1 FOR UPDATE`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"last_user_id"}).AddRow("b"))
+	mock.ExpectExec(`UPDATE team_review_cursor SET last_user_id = \$1 WHERE team_id = \$2`).
+		WithArgs("a", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	got, err := (&pr.RoundRobinSelector{}).Select(context.Background(), pr.SelectionInput{
+		TeamID:     7,
+		Candidates: candidates(map[string]int{"a": 0, "c": 0, "d": 0}),
+		Count:      3,
+		Tx:         tx,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "d", "a"}, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRoundRobinSelector_Rotation(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		ids    []string
+		cursor string
+		count  int
+		want   []string
+	}{
+		{name: "no_cursor", ids: []string{"c", "a", "b"}, cursor: "", count: 2, want: []string{"a", "b"}},
+		{name: "after_cursor", ids: []string{"a", "b", "c"}, cursor: "a", count: 1, want: []string{"b"}},
+		{name: "wraps_around", ids: []string{"a", "b", "c"}, cursor: "c", count: 2, want: []string{"a", "b"}},
+		{name: "cursor_left_the_pool", ids: []string{"a", "c", "d"}, cursor: "b", count: 2, want: []string{"c", "d"}},
+		{name: "never_repeats", ids: []string{"a", "b"}, cursor: "a", count: 5, want: []string{"b", "a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`INSERT INTO team_review_cursor`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT COALESCE\(last_user_id, ''\) FROM team_review_cursor`).
+				WillReturnRows(sqlmock.NewRows([]string{"last_user_id"}).AddRow(tc.cursor))
+			mock.ExpectExec(`UPDATE team_review_cursor SET last_user_id = \$1`).
+				WithArgs(tc.want[len(tc.want)-1], 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			tx, err := db.Begin()
+			require.NoError(t, err)
+
+			pool := make([]pr.Candidate, 0, len(tc.ids))
+			for _, id := range tc.ids {
+				pool = append(pool, pr.Candidate{User: &user.User{Id: id, IsActive: true}})
+			}
+			got, err := (&pr.RoundRobinSelector{}).Select(context.Background(), pr.SelectionInput{
+				TeamID:     1,
+				Candidates: pool,
+				Count:      tc.count,
+				Tx:         tx,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRoundRobinSelector_DryRun(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cursor *sqlmock.Rows
+		want   []string
+	}{
+		{name: "reads_cursor", cursor: sqlmock.NewRows([]string{"last_user_id"}).AddRow("a"), want: []string{"c"}},
+		{name: "no_cursor_yet", cursor: sqlmock.NewRows([]string{"last_user_id"}), want: []string{"a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			// neither the cursor row is created nor the cursor locked or moved
+			mock.ExpectBegin()
+			mock.ExpectQuery(`^SELECT COALESCE\(last_user_id, ''\) FROM team_review_cursor WHERE team_id = \$1$`).
+				WithArgs(7).
+				WillReturnRows(tc.cursor)
+
+			tx, err := db.Begin()
+			require.NoError(t, err)
+
+			got, err := (&pr.RoundRobinSelector{}).Select(context.Background(), pr.SelectionInput{
+				TeamID:     7,
+				Candidates: candidates(map[string]int{"a": 0, "c": 0}),
+				Count:      1,
+				Tx:         tx,
+				DryRun:     true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRoundRobinSelector_RequiresTx(t *testing.T) {
+	_, err := (&pr.RoundRobinSelector{}).Select(context.Background(), pr.SelectionInput{
+		Candidates: candidates(map[string]int{"a": 0}),
+		Count:      1,
+	})
+	require.Error(t, err)
+}
+
+func TestWeightedSelector_Distinct(t *testing.T) {
+	s := &pr.WeightedSelector{}
+
+	got, err := s.Select(context.Background(), pr.SelectionInput{
+		Candidates: candidates(map[string]int{"a": 0, "b": 10, "c": 2}),
+		Count:      5,
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "b", "c"}, got)
+}
+
+func TestWeightedSelector(t *testing.T) {
+	s := &pr.WeightedSelector{}
+
+	for _, tc := range []struct {
+		name  string
+		load  map[string]int
+		count int
+		want  int
+	}{
+		{name: "count_respected", load: map[string]int{"a": 0, "b": 1, "c": 2}, count: 2, want: 2},
+		{name: "whole_pool", load: map[string]int{"a": 0, "b": 1}, count: 2, want: 2},
+		{name: "pool_too_small", load: map[string]int{"a": 3}, count: 2, want: 1},
+		{name: "empty_pool", load: map[string]int{}, count: 2, want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Select(context.Background(), pr.SelectionInput{Candidates: candidates(tc.load), Count: tc.count})
+			require.NoError(t, err)
+			require.Len(t, got, tc.want)
+			for _, id := range got {
+				require.Contains(t, tc.load, id)
+			}
+		})
+	}
+}
+
+func TestWeightedSelector_FavorsLessLoaded(t *testing.T) {
+	s := &pr.WeightedSelector{}
+	pool := candidates(map[string]int{"idle": 0, "busy": 9})
+
+	picks := make(map[string]int)
+	for seed := uint64(0); seed < 200; seed++ {
+		got, err := s.Select(context.Background(), pr.SelectionInput{
+			Candidates: pool,
+			Count:      1,
+			Rand:       rand.New(rand.NewPCG(seed, seed)),
+		})
+		require.NoError(t, err)
+		picks[got[0]]++
+	}
+	// weights are 1 and 1/10, so idle is expected about 10 times as often
+	require.Greater(t, picks["idle"], 5*picks["busy"])
+	require.Positive(t, picks["busy"])
+}
+
+func TestSelectPreferred(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		tiers [][]pr.Candidate
+		count int
+		want  []string
+	}{
+		{
+			name:  "first_tier_suffices",
+			tiers: [][]pr.Candidate{candidates(map[string]int{"a": 1, "b": 0}), candidates(map[string]int{"c": 0})},
+			count: 2,
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "falls_through_to_later_tiers",
+			tiers: [][]pr.Candidate{candidates(map[string]int{"a": 5}), nil, candidates(map[string]int{"c": 2, "d": 1})},
+			count: 2,
+			want:  []string{"a", "d"},
+		},
+		{
+			name:  "later_tier_unused_once_full",
+			tiers: [][]pr.Candidate{candidates(map[string]int{"a": 0}), candidates(map[string]int{"b": 0})},
+			count: 1,
+			want:  []string{"a"},
+		},
+		{
+			name:  "short_of_candidates",
+			tiers: [][]pr.Candidate{candidates(map[string]int{"a": 0}), candidates(map[string]int{})},
+			count: 3,
+			want:  []string{"a"},
+		},
+		{
+			name:  "no_tiers",
+			count: 2,
+			want:  []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pr.SelectPreferred(context.Background(), &pr.LeastLoadedSelector{}, pr.SelectionInput{Count: tc.count}, tc.tiers...)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestStrategySelector_UnknownFallsBackToDefault(t *testing.T) {
+	s := pr.NewStrategySelector()
+
+	got, err := s.Select(context.Background(), pr.SelectionInput{
+		Strategy:   "coin_flip",
+		Candidates: candidates(map[string]int{"a": 4, "b": 1}),
+		Count:      1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, got)
+
+	require.True(t, team.ValidStrategy(team.StrategyWeighted))
+	require.False(t, team.ValidStrategy("coin_flip"))
+}
+
+func TestSelectors_SeedReproducesChoice(t *testing.T) {
+	pool := []pr.Candidate{
+		{User: &user.User{Id: "a"}, OpenReviews: 1},
+		{User: &user.User{Id: "b"}, OpenReviews: 1},
+		{User: &user.User{Id: "c"}, OpenReviews: 1},
+		{User: &user.User{Id: "d"}, OpenReviews: 2},
+		{User: &user.User{Id: "e"}, OpenReviews: 0},
+	}
+
+	for name, s := range map[string]pr.ReviewerSelector{
+		"random":       &pr.RandomSelector{},
+		"least_loaded": &pr.LeastLoadedSelector{},
+		"weighted":     &pr.WeightedSelector{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pick := func() []string {
+				got, err := s.Select(context.Background(), pr.SelectionInput{
+					Candidates: pool,
+					Count:      3,
+					Rand:       rand.New(rand.NewPCG(42, 42)),
+				})
+				require.NoError(t, err)
+				return got
+			}
+
+			first := pick()
+			for i := 0; i < 10; i++ {
+				require.Equal(t, first, pick())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package pr_test
+
+import (
+	"context"
+	"testing"
+
+	"pullreq/internal/errs"
+	"pullreq/internal/pr"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanTransition(t *testing.T) {
+	allowed := [][2]string{
+		{pr.StatusDraft, pr.StatusOpen},
+		{pr.StatusDraft, pr.StatusClosed},
+		{pr.StatusOpen, pr.StatusClosed},
+		{pr.StatusOpen, pr.StatusMerged},
+		{pr.StatusClosed, pr.StatusOpen},
+		{pr.StatusClosed, pr.StatusDraft},
+	}
+	for _, tr := range allowed {
+		require.True(t, pr.CanTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+
+	rejected := [][2]string{
+		{pr.StatusMerged, pr.StatusOpen},
+		{pr.StatusMerged, pr.StatusClosed},
+		{pr.StatusClosed, pr.StatusMerged},
+		{pr.StatusDraft, pr.StatusMerged},
+		{pr.StatusOpen, pr.StatusDraft},
+		{pr.StatusOpen, pr.StatusOpen},
+	}
+	for _, tr := range rejected {
+		require.False(t, pr.CanTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+}
+
+func TestStartReview_RequiresOpen(t *testing.T) {
+	for _, tc := range []struct {
+		status string
+		err    error
+	}{
+		{status: pr.StatusDraft, err: errs.InvalidTransitionError},
+		{status: pr.StatusClosed, err: errs.InvalidTransitionError},
+		{status: pr.StatusMerged, err: errs.PRMergedError},
+	} {
+		t.Run(tc.status, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			// the review is not touched
+			mock.ExpectQuery(`SELECT pr_status FROM pr WHERE id = \$1`).
+				WithArgs("pr-1").
+				WillReturnRows(sqlmock.NewRows([]string{"pr_status"}).AddRow(tc.status))
+
+			repo := &pr.PullRequestRepo{DB: db}
+			_, err = repo.StartReview(context.Background(), "pr-1", "u2")
+			require.ErrorIs(t, err, tc.err)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package rotation_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pullreq/internal/errs"
+	"pullreq/internal/rotation"
+	routermocks "pullreq/mocks"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+func TestRotation_Order(t *testing.T) {
+	rot := &rotation.Rotation{TeamName: "backend", Members: []string{"a", "b", "c"}, PeriodDays: 7, StartsAt: start}
+
+	require.Nil(t, rot.Order(start.Add(-time.Minute)))
+	require.Equal(t, []string{"a", "b", "c"}, rot.Order(start))
+	require.Equal(t, []string{"b", "c", "a"}, rot.Order(start.AddDate(0, 0, 8)))
+	require.Equal(t, []string{"a", "b", "c"}, rot.Order(start.AddDate(0, 0, 21)))
+}
+
+func TestRotationRepo_Schedule(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rr := &rotation.RotationRepo{DB: db}
+	at := start.AddDate(0, 0, 1)
+
+	sqlMock.ExpectQuery(`SELECT t.team_name, r.period_days, r.starts_at, COALESCE\(m.user_id, ''\) FROM duty_rotations r`).
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_name", "period_days", "starts_at", "user_id"}).
+			AddRow("backend", 7, start, "a").
+			AddRow("backend", 7, start, "b").
+			AddRow("backend", 7, start, "c"))
+	sqlMock.ExpectQuery(`SELECT id, is_active FROM users WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_active"}).
+			AddRow("a", false).
+			AddRow("b", true).
+			AddRow("c", true))
+	// b is out of office now but back for the next shift
+	sqlMock.ExpectQuery(`SELECT user_id, COALESCE\(delegate_id, ''\) FROM out_of_office`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "delegate_id"}).AddRow("b", ""))
+	sqlMock.ExpectQuery(`SELECT user_id, COALESCE\(delegate_id, ''\) FROM out_of_office`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "delegate_id"}))
+	sqlMock.ExpectQuery(`SELECT user_id, COALESCE\(delegate_id, ''\) FROM out_of_office`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "delegate_id"}))
+
+	current, upcoming, err := rr.Schedule(context.Background(), "backend", at, 2)
+	require.NoError(t, err)
+	require.Equal(t, "a", current.ScheduledID)
+	require.Equal(t, "c", current.UserID)
+	require.Equal(t, []string{"a", "b"}, current.Skipped)
+	require.Equal(t, start.AddDate(0, 0, 7), current.EndsAt)
+
+	require.Len(t, upcoming, 2)
+	require.Equal(t, "b", upcoming[0].UserID)
+	require.Equal(t, "c", upcoming[1].UserID)
+	require.Empty(t, upcoming[1].Skipped)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestRotationRepo_ScheduleDefaultsToClock(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := start.AddDate(0, 0, 1)
+	rr := &rotation.RotationRepo{DB: db, Clock: func() time.Time { return now }}
+
+	sqlMock.ExpectQuery(`SELECT t.team_name, r.period_days, r.starts_at, COALESCE\(m.user_id, ''\) FROM duty_rotations r`).
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_name", "period_days", "starts_at", "user_id"}).
+			AddRow("backend", 7, start, "a"))
+	sqlMock.ExpectQuery(`SELECT id, is_active FROM users WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_active"}).AddRow("a", true))
+	sqlMock.ExpectQuery(`SELECT user_id, COALESCE\(delegate_id, ''\) FROM out_of_office`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "delegate_id"}))
+
+	// the first shift is in progress by the clock, it would not have started at the zero time
+	current, upcoming, err := rr.Schedule(context.Background(), "backend", time.Time{}, 0)
+	require.NoError(t, err)
+	require.NotNil(t, current)
+	require.Equal(t, start, current.StartsAt)
+	require.Empty(t, upcoming)
+	require.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSetRotationHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRR := routermocks.NewRotationRepoInterface(t)
+		router := &rotation.RotationRouter{RR: mockRR}
+
+		rot := rotation.Rotation{TeamName: "backend", Members: []string{"a", "b"}, PeriodDays: 7, StartsAt: start}
+		mockRR.On("SetRotation", mock.Anything, rot).Return(&rot, nil)
+
+		body, _ := json.Marshal(rot)
+		req := httptest.NewRequest(http.MethodPost, "/rotation/set", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.SetRotationHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("invalid_rotation", func(t *testing.T) {
+		router := &rotation.RotationRouter{RR: routermocks.NewRotationRepoInterface(t)}
+
+		for _, body := range []string{
+			`{"team_name":"backend","members":["a"]}`,
+			`{"team_name":"backend","members":[],"starts_at":"2025-01-06T09:00:00Z"}`,
+			`{"team_name":"backend","members":["a","a"],"starts_at":"2025-01-06T09:00:00Z"}`,
+			`{"team_name":"backend","members":["a"],"period_days":-1,"starts_at":"2025-01-06T09:00:00Z"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/rotation/set", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+
+			router.SetRotationHandler(w, req)
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("foreign_member", func(t *testing.T) {
+		mockRR := routermocks.NewRotationRepoInterface(t)
+		router := &rotation.RotationRouter{RR: mockRR}
+		mockRR.On("SetRotation", mock.Anything, mock.Anything).Return(nil, errs.RotationMemberError)
+
+		req := httptest.NewRequest(http.MethodPost, "/rotation/set", bytes.NewBufferString(`{"team_name":"backend","members":["stranger"],"starts_at":"2025-01-06T09:00:00Z"}`))
+		w := httptest.NewRecorder()
+
+		router.SetRotationHandler(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestGetScheduleHandler(t *testing.T) {
+	mockRR := routermocks.NewRotationRepoInterface(t)
+	router := &rotation.RotationRouter{RR: mockRR}
+
+	at := start.AddDate(0, 0, 1)
+	rot := &rotation.Rotation{TeamName: "backend", Members: []string{"a", "b"}, PeriodDays: 7, StartsAt: start}
+	mockRR.On("GetRotation", mock.Anything, "backend").Return(rot, nil)
+	mockRR.On("Schedule", mock.Anything, "backend", at, 1).Return(
+		&rotation.Shift{StartsAt: start, EndsAt: start.AddDate(0, 0, 7), ScheduledID: "a", UserID: "a"},
+		[]rotation.Shift{{StartsAt: start.AddDate(0, 0, 7), EndsAt: start.AddDate(0, 0, 14), ScheduledID: "b", UserID: "b"}},
+		nil,
+	)
+	mockRR.On("Schedule", mock.Anything, "backend", time.Time{}, 1).Return(nil, []rotation.Shift{}, nil)
+	mockRR.On("GetRotation", mock.Anything, "ghost").Return(nil, errs.NotFountError)
+
+	req := httptest.NewRequest(http.MethodGet, "/rotation/schedule?team_name=backend&periods=1&at=2025-01-07T09:00:00Z", nil)
+	w := httptest.NewRecorder()
+	router.GetScheduleHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Current  rotation.Shift   `json:"current"`
+		Upcoming []rotation.Shift `json:"upcoming"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, "a", resp.Current.UserID)
+	require.Len(t, resp.Upcoming, 1)
+
+	// without at the repository decides what now is
+	req = httptest.NewRequest(http.MethodGet, "/rotation/schedule?team_name=backend&periods=1", nil)
+	w = httptest.NewRecorder()
+	router.GetScheduleHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/rotation/schedule?team_name=ghost", nil)
+	w = httptest.NewRecorder()
+	router.GetScheduleHandler(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/rotation/schedule?team_name=backend&periods=100", nil)
+	w = httptest.NewRecorder()
+	router.GetScheduleHandler(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
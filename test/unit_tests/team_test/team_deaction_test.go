@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"pullreq/internal/team"
+	"pullreq/internal/user"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -32,12 +33,24 @@ func setupTestDB(t *testing.T) *sql.DB {
 
 	return db
 }
+
+// reassignerStub records the users whose reviews it was asked to move.
+type reassignerStub struct {
+	userIDs []string
+}
+
+func (r *reassignerStub) ReassignReviews(ctx context.Context, tx *sql.Tx, userIDs []string) ([]user.Reassignment, error) {
+	r.userIDs = userIDs
+	return []user.Reassignment{{PullRequestID: "pr-1", OldUserID: "u1", Understaffed: true}}, nil
+}
+
 func TestTeamRepo_Deactivation_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
 
-	tr := &team.TeamRepo{DB: db}
+	reassigner := &reassignerStub{}
+	tr := &team.TeamRepo{DB: db, Reassigner: reassigner}
 
 	ctx := context.Background()
 	teamName := "backend"
@@ -46,16 +59,40 @@ func TestTeamRepo_Deactivation_Success(t *testing.T) {
 	mock.ExpectBegin()
 
 	// SQL ожидаемый для обновления юзеров
-	mock.ExpectExec(`UPDATE users u SET is_active = \$1 FROM teams t WHERE u.team_id = t.id AND t.team_name = \$2`).
+	mock.ExpectQuery(`UPDATE users u SET is_active = \$1 FROM teams t WHERE u.team_id = t.id AND t.team_name = \$2 RETURNING u.id`).
 		WithArgs(false, teamName).
-		WillReturnResult(sqlmock.NewResult(0, 2))
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("u1").AddRow("u2"))
 
 	// Expect commit
 	mock.ExpectCommit()
 
-	err = tr.Deactivation(ctx, teamName)
+	reassignments, err := tr.Deactivation(ctx, teamName, true)
 	require.NoError(t, err)
+	require.Equal(t, []string{"u1", "u2"}, reassigner.userIDs)
+	require.Equal(t, []string{"pr-1"}, user.UnderstaffedPRs(reassignments))
 
 	// Проверка, что все ожидания выполнены
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestTeamRepo_Deactivation_WithoutReassign(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	reassigner := &reassignerStub{}
+	tr := &team.TeamRepo{DB: db, Reassigner: reassigner}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE users u SET is_active = \$1 FROM teams t`).
+		WithArgs(false, "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("u1"))
+	mock.ExpectCommit()
+
+	reassignments, err := tr.Deactivation(context.Background(), "backend", false)
+	require.NoError(t, err)
+	require.Empty(t, reassignments)
+	require.Nil(t, reassigner.userIDs)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -34,7 +34,7 @@ func TestTeamRepo_AddTeam_Success(t *testing.T) {
 
 	// --- Insert team ---
 	mock.ExpectQuery(`INSERT INTO teams`).
-		WithArgs(teamName).
+		WithArgs(teamName, team.StrategyLeastLoaded, team.DefaultReviewersRequired).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
 
 	// --- Insert users (single query with multiple VALUES) ---
@@ -49,7 +49,7 @@ func TestTeamRepo_AddTeam_Success(t *testing.T) {
 	mock.ExpectCommit()
 
 	// --- Call AddTeam ---
-	res, err := tr.AddTeam(ctx, teamName, members)
+	res, err := tr.AddTeam(ctx, teamName, members, team.TeamSettings{})
 	require.NoError(t, err)
 	require.Equal(t, teamName, res.TeamName)
 	require.Len(t, res.Members, 2)
@@ -73,7 +73,7 @@ func TestTeamRepo_AddTeam_AlreadyExists(t *testing.T) {
 
 	mock.ExpectRollback()
 
-	_, err := tr.AddTeam(ctx, "backend", []team.TeamMember{})
+	_, err := tr.AddTeam(ctx, "backend", []team.TeamMember{}, team.TeamSettings{})
 	if !errors.Is(err, errs.ExistError) && err == nil {
 		t.Fatalf("expected exist error, got: %v", err)
 	}
@@ -86,13 +86,16 @@ func TestTeamRepo_GetTeamWithMembers_Success(t *testing.T) {
 	ur := &user.UserRepo{DB: db}
 	tr := &team.TeamRepo{DB: db, UR: ur}
 
-	rows := sqlmock.NewRows([]string{"id", "team_name", "user_id", "username", "is_active"}).
-		AddRow(10, "backend", "u1", "Alice", true).
-		AddRow(10, "backend", "u2", "Bob", false)
+	rows := sqlmock.NewRows([]string{"id", "team_name", "reviewer_strategy", "reviewers_required", "require_senior", "working_hours_ahead", "rebalance_threshold", "duty_reviewer", "approvals_required", "block_on_changes_requested", "require_resolved_threads", "user_id", "username", "is_active"}).
+		AddRow(10, "backend", "round_robin", 1, true, 8, 3, true, 2, true, true, "u1", "Alice", true).
+		AddRow(10, "backend", "round_robin", 1, true, 8, 3, true, 2, true, true, "u2", "Bob", false)
 
 	mock.ExpectQuery(`SELECT (.+) FROM teams AS t LEFT JOIN users`).
 		WithArgs("backend").
 		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT t.id, t.team_name FROM team_fallbacks f JOIN teams t`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "team_name"}).AddRow(11, "platform"))
 
 	res, err := tr.GetTeamWithMembers(context.Background(), "backend")
 	if err != nil {
@@ -106,6 +109,59 @@ func TestTeamRepo_GetTeamWithMembers_Success(t *testing.T) {
 	if len(res.Members) != 2 {
 		t.Fatalf("expected 2 members, got %d", len(res.Members))
 	}
+
+	if res.Settings.ReviewerStrategy != team.StrategyRoundRobin || !res.Settings.RequireSenior || res.Settings.WorkingHoursAhead != 8 || res.Settings.RebalanceThreshold != 3 || !res.Settings.DutyReviewer ||
+		res.Settings.ApprovalsRequired != 2 || !res.Settings.BlockOnChangesRequested || !res.Settings.RequireResolvedThreads {
+		t.Fatalf("expected round_robin strategy, got %s", res.Settings.ReviewerStrategy)
+	}
+
+	if len(res.Settings.FallbackTeams) != 1 || res.Settings.FallbackTeams[0] != "platform" {
+		t.Fatalf("expected platform fallback team, got %v", res.Settings.FallbackTeams)
+	}
+
+	// one active member is the author, nobody is left to review
+	if !res.Understaffed() {
+		t.Fatalf("expected team to be understaffed")
+	}
+}
+
+func TestTeam_Understaffed(t *testing.T) {
+	tm := &team.Team{
+		Members: []*user.User{
+			{Id: "u1", IsActive: true},
+			{Id: "u2", IsActive: true},
+			{Id: "u3", IsActive: false},
+		},
+		Settings: team.TeamSettings{ReviewersRequired: 2},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		authorID string
+		eligible int
+	}{
+		{name: "active_member", authorID: "u1", eligible: 1},
+		{name: "inactive_member", authorID: "u3", eligible: 2},
+		{name: "other_team", authorID: "x", eligible: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tm.EligibleReviewers(tc.authorID); got != tc.eligible {
+				t.Fatalf("expected %d eligible reviewers, got %d", tc.eligible, got)
+			}
+			if got := tm.UnderstaffedFor(tc.authorID); got != (tc.eligible < 2) {
+				t.Fatalf("unexpected understaffed %v", got)
+			}
+		})
+	}
+
+	// a PR of an active member gets a single reviewer
+	if !tm.Understaffed() {
+		t.Fatalf("expected team to be understaffed")
+	}
+	tm.Settings.ReviewersRequired = 1
+	if tm.Understaffed() {
+		t.Fatalf("expected team not to be understaffed")
+	}
 }
 
 func TestTeamRepo_GetTeamByUserID_NotFound(t *testing.T) {
@@ -133,11 +189,11 @@ func TestTeamRepo_GetTeamMember_OK(t *testing.T) {
 	ur := &user.UserRepo{DB: db}
 	tr := &team.TeamRepo{DB: db, UR: ur}
 
-	rows := sqlmock.NewRows([]string{"id", "username", "is_active"}).
-		AddRow("u1", "Alice", true).
-		AddRow("u2", "Bob", false)
+	rows := sqlmock.NewRows([]string{"id", "username", "is_active", "max_open_reviews", "seniority", "timezone", "work_start", "work_end"}).
+		AddRow("u1", "Alice", true, nil, "junior", "Europe/Moscow", "09:00", "18:00").
+		AddRow("u2", "Bob", false, 3, "staff", "UTC", "", "")
 
-	mock.ExpectQuery(`SELECT id, username, is_active FROM users WHERE team_id`).
+	mock.ExpectQuery(`SELECT id, username, is_active, max_open_reviews, seniority, timezone, (.+) FROM users WHERE team_id`).
 		WithArgs(10).
 		WillReturnRows(rows)
 
@@ -149,6 +205,67 @@ func TestTeamRepo_GetTeamMember_OK(t *testing.T) {
 	if len(list) != 2 {
 		t.Fatalf("expected 2 members, got %d", len(list))
 	}
+
+	if list[0].MaxOpenReviews != nil || list[1].MaxOpenReviews == nil || *list[1].MaxOpenReviews != 3 {
+		t.Fatalf("unexpected max_open_reviews: %v, %v", list[0].MaxOpenReviews, list[1].MaxOpenReviews)
+	}
+
+	if list[0].IsSenior() || !list[1].IsSenior() {
+		t.Fatalf("unexpected seniority: %s, %s", list[0].Seniority, list[1].Seniority)
+	}
+
+	if list[0].Timezone != "Europe/Moscow" || list[0].WorkStart != "09:00" || list[1].WorkEnd != "" {
+		t.Fatalf("unexpected working hours: %+v, %+v", list[0], list[1])
+	}
+}
+
+func TestTeamRepo_UpdateSettings_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	tr := &team.TeamRepo{DB: db}
+
+	strategy := team.StrategyRandom
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE teams SET team_name = \$1, reviewer_strategy = \$2 WHERE team_name = \$3 RETURNING id, reviewer_strategy, reviewers_required, require_senior, working_hours_ahead, rebalance_threshold, duty_reviewer, approvals_required, block_on_changes_requested, require_resolved_threads`).
+		WithArgs("ghost", strategy, "ghost").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reviewer_strategy", "reviewers_required", "require_senior", "working_hours_ahead", "rebalance_threshold", "duty_reviewer", "approvals_required", "block_on_changes_requested", "require_resolved_threads"}))
+	mock.ExpectRollback()
+
+	_, err := tr.UpdateSettings(context.Background(), team.TeamSettingsInput{TeamName: "ghost", ReviewerStrategy: &strategy})
+	if !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
+
+func TestTeamRepo_UpdateSettings_FallbackTeams(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	tr := &team.TeamRepo{DB: db}
+
+	fallbacks := []string{"platform", "backend"}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE teams SET team_name = \$1 WHERE team_name = \$2 RETURNING id, reviewer_strategy, reviewers_required, require_senior, working_hours_ahead, rebalance_threshold, duty_reviewer, approvals_required, block_on_changes_requested, require_resolved_threads`).
+		WithArgs("backend", "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reviewer_strategy", "reviewers_required", "require_senior", "working_hours_ahead", "rebalance_threshold", "duty_reviewer", "approvals_required", "block_on_changes_requested", "require_resolved_threads"}).AddRow(10, "least_loaded", 2, false, 0, 0, false, 0, false, false))
+	mock.ExpectExec(`DELETE FROM team_fallbacks WHERE team_id = \$1`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id, team_name FROM teams WHERE team_name IN \(\$1,\$2\)`).
+		WithArgs("platform", "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "team_name"}).AddRow(11, "platform").AddRow(10, "backend"))
+	mock.ExpectRollback()
+
+	// a team cannot fall back on itself
+	_, err := tr.UpdateSettings(context.Background(), team.TeamSettingsInput{TeamName: "backend", FallbackTeams: &fallbacks})
+	if !errors.Is(err, errs.FallbackTeamError) {
+		t.Fatalf("expected fallback team error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
 }
 
 type mockTeamRepo struct {
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"pullreq/internal/errs"
 	"pullreq/internal/team"
 	"pullreq/internal/user"
 	routermocks "pullreq/mocks"
@@ -28,7 +29,7 @@ func TestHandleAddTeam(t *testing.T) {
 				{UserID: "u1", Username: "Alice", IsActive: true},
 			},
 		}
-		mockTR.On("AddTeam", mock.Anything, input.TeamName, input.Members).Return(&team.Team{
+		mockTR.On("AddTeam", mock.Anything, input.TeamName, input.Members, team.TeamSettings{}).Return(&team.Team{
 			TeamName: input.TeamName,
 		}, nil)
 
@@ -50,7 +51,7 @@ func TestHandleAddTeam(t *testing.T) {
 			TeamName: "TeamB",
 			Members:  []team.TeamMember{},
 		}
-		mockTR.On("AddTeam", mock.Anything, input.TeamName, input.Members).Return(nil, errors.New("exists"))
+		mockTR.On("AddTeam", mock.Anything, input.TeamName, input.Members, team.TeamSettings{}).Return(nil, errors.New("exists"))
 
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
@@ -87,7 +88,9 @@ func TestDeactivateTeamHandler(t *testing.T) {
 			TeamName: "TeamX",
 		}
 
-		mockTR.On("Deactivation", mock.Anything, input.TeamName).Return(nil)
+		mockTR.On("Deactivation", mock.Anything, input.TeamName, true).Return([]user.Reassignment{
+			{PullRequestID: "pr-1", OldUserID: "u1", NewUserID: "u7"},
+		}, nil)
 		mockTR.On("GetTeamWithMembers", mock.Anything, input.TeamName).Return(&team.Team{
 			TeamName: input.TeamName,
 			Members: []*user.User{
@@ -107,6 +110,20 @@ func TestDeactivateTeamHandler(t *testing.T) {
 		err := json.Unmarshal(w.Body.Bytes(), &resp)
 		require.NoError(t, err)
 		require.Equal(t, "TeamX", resp["team"].(map[string]interface{})["team_name"])
+		require.Len(t, resp["reassignments"], 1)
+	})
+
+	t.Run("without_reassign", func(t *testing.T) {
+		input := team.DeactivateTeamRequest{TeamName: "TeamZ"}
+		mockTR.On("Deactivation", mock.Anything, input.TeamName, false).Return([]user.Reassignment{}, nil)
+		mockTR.On("GetTeamWithMembers", mock.Anything, input.TeamName).Return(&team.Team{TeamName: input.TeamName}, nil)
+
+		body, _ := json.Marshal(input)
+		req := httptest.NewRequest(http.MethodPost, "/?reassign=false", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.DeactivateTeam(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
 	})
 
 	t.Run("invalid_json", func(t *testing.T) {
@@ -135,7 +152,7 @@ func TestDeactivateTeamHandler(t *testing.T) {
 
 	t.Run("repo_error", func(t *testing.T) {
 		input := team.DeactivateTeamRequest{TeamName: "TeamY"}
-		mockTR.On("Deactivation", mock.Anything, input.TeamName).Return(errors.New("db error"))
+		mockTR.On("Deactivation", mock.Anything, input.TeamName, true).Return(nil, errors.New("db error"))
 
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
@@ -201,3 +218,59 @@ func TestGetTeamWithMembersHandler(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, w.Code)
 	})
 }
+
+func TestUpdateTeamSettingsHandler(t *testing.T) {
+	mockTR := routermocks.NewTeamRepoInterface(t)
+	router := &team.TeamRouter{TR: mockTR}
+
+	t.Run("success", func(t *testing.T) {
+		strategy := team.StrategyRoundRobin
+		input := team.TeamSettingsInput{TeamName: "TeamA", ReviewerStrategy: &strategy}
+		mockTR.On("UpdateSettings", mock.Anything, input).Return(&team.TeamSettings{ReviewerStrategy: strategy}, nil)
+
+		body, _ := json.Marshal(input)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.UpdateTeamSettings(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Equal(t, strategy, resp["settings"].(map[string]interface{})["reviewer_strategy"])
+	})
+
+	t.Run("unknown_strategy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"team_name":"TeamA","reviewer_strategy":"coin_flip"}`))
+		w := httptest.NewRecorder()
+
+		router.UpdateTeamSettings(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("team_not_found", func(t *testing.T) {
+		input := team.TeamSettingsInput{TeamName: "Unknown"}
+		mockTR.On("UpdateSettings", mock.Anything, input).Return(nil, errs.NotFountError)
+
+		body, _ := json.Marshal(input)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.UpdateTeamSettings(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid_fallback_team", func(t *testing.T) {
+		fallbacks := []string{"TeamB"}
+		input := team.TeamSettingsInput{TeamName: "TeamC", FallbackTeams: &fallbacks}
+		mockTR.On("UpdateSettings", mock.Anything, input).Return(nil, errs.FallbackTeamError)
+
+		body, _ := json.Marshal(input)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.UpdateTeamSettings(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -2,8 +2,11 @@ package user_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"pullreq/internal/errs"
 	"pullreq/internal/user"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -28,11 +31,13 @@ func TestUserRepo_UpdateUserActivity(t *testing.T) {
 	userID := "u1"
 	isActive := true
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE users SET is_active = \$1 WHERE id = \$2 RETURNING id, username, team_id, is_active`).
 		WithArgs(true, "u1").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active"}).AddRow("u1", "Alice", 1, true))
+	mock.ExpectCommit()
 
-	updated, err := repo.UpdateUserActivity(context.Background(), userID, isActive)
+	updated, _, err := repo.UpdateUserActivity(context.Background(), userID, isActive, true)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -72,3 +77,188 @@ func TestUserRepo_GetUsersPrShort(t *testing.T) {
 		t.Errorf("unmet expectations: %v", err)
 	}
 }
+
+// --- Test SetMaxOpenReviews ---
+func TestUserRepo_SetMaxOpenReviews(t *testing.T) {
+	repo, mock, teardown := setupUserRepo(t)
+	defer teardown()
+
+	max := 2
+	mock.ExpectQuery(`UPDATE users SET max_open_reviews = \$1 WHERE id = \$2 RETURNING id, username, team_id, is_active, max_open_reviews`).
+		WithArgs(&max, "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active", "max_open_reviews"}).AddRow("u1", "Alice", 1, true, 2))
+
+	updated, err := repo.SetMaxOpenReviews(context.Background(), "u1", &max)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.MaxOpenReviews == nil || *updated.MaxOpenReviews != 2 {
+		t.Errorf("expected max_open_reviews 2, got %+v", updated.MaxOpenReviews)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// --- Test SetAdmin ---
+func TestUserRepo_SetAdmin(t *testing.T) {
+	repo, mock, teardown := setupUserRepo(t)
+	defer teardown()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT is_admin FROM users WHERE id = \$1`).
+		WithArgs("u3").
+		WillReturnRows(sqlmock.NewRows([]string{"is_admin"}).AddRow(true))
+	mock.ExpectQuery(`UPDATE users SET is_admin = \$1 WHERE id = \$2 RETURNING id, username, team_id, is_active, is_admin`).
+		WithArgs(true, "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active", "is_admin"}).AddRow("u1", "Alice", 1, true, true))
+	mock.ExpectCommit()
+
+	updated, err := repo.SetAdmin(context.Background(), "u3", "u1", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !updated.IsAdmin {
+		t.Errorf("expected an admin, got %+v", updated)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT is_admin FROM users`).
+		WithArgs("u3").
+		WillReturnRows(sqlmock.NewRows([]string{"is_admin"}).AddRow(true))
+	mock.ExpectQuery(`UPDATE users SET is_admin`).
+		WithArgs(true, "ghost").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active", "is_admin"}))
+	mock.ExpectRollback()
+
+	if _, err := repo.SetAdmin(context.Background(), "u3", "ghost", true); err != errs.NotFountError {
+		t.Errorf("expected NotFountError, got %v", err)
+	}
+
+	// neither a regular user nor an unknown one may grant the role, the user is not touched
+	for _, rows := range []*sqlmock.Rows{
+		sqlmock.NewRows([]string{"is_admin"}).AddRow(false),
+		sqlmock.NewRows([]string{"is_admin"}),
+	} {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT is_admin FROM users`).
+			WithArgs("u2").
+			WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		if _, err := repo.SetAdmin(context.Background(), "u2", "u2", true); err != errs.NotAdminError {
+			t.Errorf("expected NotAdminError, got %v", err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// --- Test SetSeniority ---
+func TestUserRepo_SetSeniority(t *testing.T) {
+	repo, mock, teardown := setupUserRepo(t)
+	defer teardown()
+
+	mock.ExpectQuery(`UPDATE users SET seniority = \$1 WHERE id = \$2 RETURNING id, username, team_id, is_active, seniority`).
+		WithArgs("staff", "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active", "seniority"}).AddRow("u1", "Alice", 1, true, "staff"))
+
+	updated, err := repo.SetSeniority(context.Background(), "u1", "staff")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Seniority != "staff" || !updated.IsSenior() {
+		t.Errorf("expected a staff user, got %+v", updated)
+	}
+
+	mock.ExpectQuery(`UPDATE users SET seniority`).
+		WithArgs("junior", "ghost").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active", "seniority"}))
+
+	if _, err := repo.SetSeniority(context.Background(), "ghost", "junior"); err != errs.NotFountError {
+		t.Errorf("expected NotFountError, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// --- Test GetTags for unknown user ---
+func TestUserRepo_GetTags_NotFound(t *testing.T) {
+	repo, mock, teardown := setupUserRepo(t)
+	defer teardown()
+
+	mock.ExpectQuery(`SELECT t.tag FROM users u LEFT JOIN user_tags t`).
+		WithArgs("ghost").
+		WillReturnRows(sqlmock.NewRows([]string{"tag"}))
+
+	_, err := repo.GetTags(context.Background(), "ghost")
+	if !errors.Is(err, errs.NotFountError) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+// --- Test Availability during overlapping out of office periods ---
+func TestUserRepo_Availability(t *testing.T) {
+	repo, mock, teardown := setupUserRepo(t)
+	defer teardown()
+
+	at := time.Date(2025, 7, 3, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, username, (.+) FROM users WHERE id IN \(\$1\)`).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active", "max_open_reviews", "seniority", "timezone", "work_start", "work_end"}).
+			AddRow("u1", "Alice", 1, true, nil, "mid", "Europe/Moscow", "09:00", "18:00"))
+	mock.ExpectQuery(`SELECT id, user_id, starts_at, ends_at, COALESCE\(delegate_id, ''\) FROM out_of_office WHERE user_id = \$1 AND ends_at > \$2`).
+		WithArgs("u1", at).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "starts_at", "ends_at", "delegate_id"}).
+			AddRow(1, "u1", at.Add(-48*time.Hour), at.Add(24*time.Hour), "u2").
+			AddRow(2, "u1", at.Add(-time.Hour), at.Add(72*time.Hour), "u3").
+			AddRow(3, "u1", at.Add(96*time.Hour), at.Add(120*time.Hour), ""))
+
+	res, err := repo.Availability(context.Background(), "u1", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.OutOfOffice || res.Available || !res.Working {
+		t.Errorf("expected a working user out of office, got %+v", res)
+	}
+	if res.BackAt == nil || !res.BackAt.Equal(at.Add(72*time.Hour)) || res.DelegateID != "u3" {
+		t.Errorf("expected u3 delegating until the later period ends, got %+v", res)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// --- Test Availability defaulting to the clock ---
+func TestUserRepo_AvailabilityDefaultsToClock(t *testing.T) {
+	repo, mock, teardown := setupUserRepo(t)
+	defer teardown()
+
+	now := time.Date(2025, 7, 3, 12, 0, 0, 0, time.UTC)
+	repo.Clock = func() time.Time { return now }
+	mock.ExpectQuery(`SELECT id, username, (.+) FROM users WHERE id IN \(\$1\)`).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "team_id", "is_active", "max_open_reviews", "seniority", "timezone", "work_start", "work_end"}).
+			AddRow("u1", "Alice", 1, true, nil, "mid", "", "", ""))
+	mock.ExpectQuery(`SELECT id, user_id, starts_at, ends_at, COALESCE\(delegate_id, ''\) FROM out_of_office WHERE user_id = \$1 AND ends_at > \$2`).
+		WithArgs("u1", now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "starts_at", "ends_at", "delegate_id"}))
+
+	res, err := repo.Availability(context.Background(), "u1", time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.At.Equal(now) || !res.Available {
+		t.Errorf("expected u1 available now, got %+v", res)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"pullreq/internal/errs"
 	"pullreq/internal/user"
@@ -67,7 +68,7 @@ func TestRouterSetActiviry(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		input := user.UserShortInput{UserID: "u1", IsActive: true}
 		updatedUser := &user.User{Id: "u1", Username: "Alice", IsActive: true}
-		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive).Return(updatedUser, nil)
+		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive, true).Return(updatedUser, []user.Reassignment{}, nil)
 
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
@@ -78,12 +79,12 @@ func TestRouterSetActiviry(t *testing.T) {
 		var resp map[string]interface{}
 		err := json.Unmarshal(w.Body.Bytes(), &resp)
 		require.NoError(t, err)
-		require.Equal(t, "u1", resp["user"].(map[string]interface{})["Id"])
+		require.Equal(t, "u1", resp["user"].(map[string]interface{})["user_id"])
 	})
 
 	t.Run("user_not_found", func(t *testing.T) {
 		input := user.UserShortInput{UserID: "missing", IsActive: true}
-		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive).Return(nil, sql.ErrNoRows)
+		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive, true).Return(nil, nil, sql.ErrNoRows)
 
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
@@ -93,6 +94,46 @@ func TestRouterSetActiviry(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, w.Code)
 	})
 
+	t.Run("deactivation_moves_reviews", func(t *testing.T) {
+		input := user.UserShortInput{UserID: "u3", IsActive: false}
+		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive, true).Return(&user.User{Id: "u3"}, []user.Reassignment{
+			{PullRequestID: "pr-1", OldUserID: "u3", NewUserID: "u4"},
+			{PullRequestID: "pr-2", OldUserID: "u3", Understaffed: true},
+		}, nil)
+
+		body, _ := json.Marshal(input)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.RouterSetActiviry(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp["reassignments"], 2)
+		require.Equal(t, []interface{}{"pr-2"}, resp["understaffed"])
+	})
+
+	t.Run("opt_out", func(t *testing.T) {
+		input := user.UserShortInput{UserID: "u5", IsActive: false}
+		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive, false).Return(&user.User{Id: "u5"}, []user.Reassignment{}, nil)
+
+		body, _ := json.Marshal(input)
+		req := httptest.NewRequest(http.MethodPost, "/?reassign=false", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.RouterSetActiviry(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("bad_flag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/?reassign=maybe", bytes.NewBufferString(`{"user_id":"u5","is_active":false}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetActiviry(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
 	t.Run("invalid_json", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("{invalid")))
 		w := httptest.NewRecorder()
@@ -103,7 +144,7 @@ func TestRouterSetActiviry(t *testing.T) {
 
 	t.Run("internal_error", func(t *testing.T) {
 		input := user.UserShortInput{UserID: "u2", IsActive: false}
-		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive).Return(nil, errors.New("db error"))
+		mockUR.On("UpdateUserActivity", mock.Anything, input.UserID, input.IsActive, true).Return(nil, nil, errors.New("db error"))
 
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
@@ -173,3 +214,316 @@ func TestGetStat(t *testing.T) {
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
+
+func TestRouterSetMaxOpenReviews(t *testing.T) {
+	mockUR := routermocks.NewUserRepoInterface(t)
+	router := &user.UserRouter{UR: mockUR}
+
+	t.Run("success", func(t *testing.T) {
+		max := 3
+		mockUR.On("SetMaxOpenReviews", mock.Anything, "u1", &max).Return(&user.User{Id: "u1", MaxOpenReviews: &max}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","max_open_reviews":3}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetMaxOpenReviews(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Equal(t, float64(3), resp["user"].(map[string]interface{})["max_open_reviews"])
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","max_open_reviews":-1}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetMaxOpenReviews(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		mockUR.On("SetMaxOpenReviews", mock.Anything, "missing", (*int)(nil)).Return(nil, errs.NotFountError)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"missing","max_open_reviews":null}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetMaxOpenReviews(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestRouterSetSeniority(t *testing.T) {
+	mockUR := routermocks.NewUserRepoInterface(t)
+	router := &user.UserRouter{UR: mockUR}
+
+	t.Run("success", func(t *testing.T) {
+		mockUR.On("SetSeniority", mock.Anything, "u1", user.SenioritySenior).Return(&user.User{Id: "u1", Seniority: user.SenioritySenior}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","seniority":"senior"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetSeniority(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Equal(t, "senior", resp["user"].(map[string]interface{})["seniority"])
+	})
+
+	t.Run("unknown_level", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","seniority":"principal"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetSeniority(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		mockUR.On("SetSeniority", mock.Anything, "missing", user.SeniorityJunior).Return(nil, errs.NotFountError)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"missing","seniority":"junior"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetSeniority(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestRouterSetAdmin(t *testing.T) {
+	mockUR := routermocks.NewUserRepoInterface(t)
+	router := &user.UserRouter{UR: mockUR}
+
+	t.Run("success", func(t *testing.T) {
+		mockUR.On("SetAdmin", mock.Anything, "u3", "u1", true).Return(&user.User{Id: "u1", IsAdmin: true}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","is_admin":true,"granted_by":"u3"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetAdmin(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Equal(t, true, resp["user"].(map[string]interface{})["is_admin"])
+	})
+
+	t.Run("missing_fields", func(t *testing.T) {
+		for _, body := range []string{`{"is_admin":true,"granted_by":"u3"}`, `{"user_id":"u1","is_admin":true}`} {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+
+			router.RouterSetAdmin(w, req)
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("granted_by_non_admin", func(t *testing.T) {
+		mockUR.On("SetAdmin", mock.Anything, "u2", "u2", true).Return(nil, errs.NotAdminError)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u2","is_admin":true,"granted_by":"u2"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetAdmin(w, req)
+		require.Equal(t, http.StatusForbidden, w.Code)
+		require.Contains(t, w.Body.String(), `"NOT_ADMIN"`)
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		mockUR.On("SetAdmin", mock.Anything, "u3", "missing", false).Return(nil, errs.NotFountError)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"missing","is_admin":false,"granted_by":"u3"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetAdmin(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestRouterSetTags(t *testing.T) {
+	mockUR := routermocks.NewUserRepoInterface(t)
+	router := &user.UserRouter{UR: mockUR}
+
+	t.Run("success", func(t *testing.T) {
+		mockUR.On("SetTags", mock.Anything, "u1", []string{"Postgres", "security"}).Return([]string{"postgres", "security"}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","tags":["Postgres","security"]}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetTags(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"postgres", "security"}, resp["tags"])
+	})
+
+	t.Run("missing_user_id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"tags":["css"]}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetTags(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestNormalizeTags(t *testing.T) {
+	require.Equal(t, []string{"frontend", "postgres"}, user.NormalizeTags([]string{" Postgres", "frontend", "", "postgres"}))
+}
+
+func TestRouterSetWorkingHours(t *testing.T) {
+	mockUR := routermocks.NewUserRepoInterface(t)
+	router := &user.UserRouter{UR: mockUR}
+
+	t.Run("success", func(t *testing.T) {
+		mockUR.On("SetWorkingHours", mock.Anything, "u1", "Asia/Vladivostok", "09:00", "18:00").
+			Return(&user.User{Id: "u1", Timezone: "Asia/Vladivostok", WorkStart: "09:00", WorkEnd: "18:00"}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","timezone":"Asia/Vladivostok","work_start":"09:00","work_end":"18:00"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetWorkingHours(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("unknown_timezone", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","timezone":"Mars/Olympus","work_start":"09:00","work_end":"18:00"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetWorkingHours(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("half_window", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","timezone":"UTC","work_start":"09:00"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetWorkingHours(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		mockUR.On("SetWorkingHours", mock.Anything, "missing", "UTC", "", "").Return(nil, errs.NotFountError)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"missing"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterSetWorkingHours(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestUntilWorking(t *testing.T) {
+	// 06:00 UTC is 09:00 in Moscow and 16:00 in Vladivostok
+	now := time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC)
+
+	moscow := &user.User{Timezone: "Europe/Moscow", WorkStart: "10:00", WorkEnd: "19:00"}
+	require.Equal(t, time.Hour, moscow.UntilWorking(now))
+
+	vladivostok := &user.User{Timezone: "Asia/Vladivostok", WorkStart: "09:00", WorkEnd: "18:00"}
+	require.Equal(t, time.Duration(0), vladivostok.UntilWorking(now))
+	require.Equal(t, 14*time.Hour, vladivostok.UntilWorking(now.Add(3*time.Hour)))
+
+	night := &user.User{Timezone: "UTC", WorkStart: "22:00", WorkEnd: "04:00"}
+	require.Equal(t, time.Duration(0), night.UntilWorking(now.Add(-3*time.Hour)))
+	require.Equal(t, 16*time.Hour, night.UntilWorking(now))
+
+	require.Equal(t, time.Duration(0), (&user.User{}).UntilWorking(now))
+}
+
+func TestRouterAddOutOfOffice(t *testing.T) {
+	mockUR := routermocks.NewUserRepoInterface(t)
+	router := &user.UserRouter{UR: mockUR}
+
+	starts := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	ends := starts.Add(14 * 24 * time.Hour)
+
+	t.Run("success", func(t *testing.T) {
+		period := user.OutOfOffice{UserID: "u1", StartsAt: starts, EndsAt: ends, DelegateID: "u2"}
+		stored := period
+		stored.ID = 7
+		mockUR.On("AddOutOfOffice", mock.Anything, period).Return(&stored, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","starts_at":"2025-07-01T00:00:00Z","ends_at":"2025-07-15T00:00:00Z","delegate_id":"u2"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterAddOutOfOffice(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var resp map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, float64(7), resp["out_of_office"]["id"])
+	})
+
+	t.Run("empty_range", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","starts_at":"2025-07-15T00:00:00Z","ends_at":"2025-07-01T00:00:00Z"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterAddOutOfOffice(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("self_delegate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","starts_at":"2025-07-01T00:00:00Z","ends_at":"2025-07-15T00:00:00Z","delegate_id":"u1"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterAddOutOfOffice(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unknown_delegate", func(t *testing.T) {
+		mockUR.On("AddOutOfOffice", mock.Anything, mock.Anything).Return(nil, errs.NotFountError).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"user_id":"u1","starts_at":"2025-07-01T00:00:00Z","ends_at":"2025-07-15T00:00:00Z","delegate_id":"ghost"}`))
+		w := httptest.NewRecorder()
+
+		router.RouterAddOutOfOffice(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAvailabilityHandler(t *testing.T) {
+	mockUR := routermocks.NewUserRepoInterface(t)
+	router := &user.UserRouter{UR: mockUR}
+
+	t.Run("success", func(t *testing.T) {
+		at := time.Date(2025, 7, 3, 12, 0, 0, 0, time.UTC)
+		back := time.Date(2025, 7, 15, 0, 0, 0, 0, time.UTC)
+		mockUR.On("Availability", mock.Anything, "u1", at).
+			Return(&user.Availability{UserID: "u1", At: at, IsActive: true, OutOfOffice: true, BackAt: &back, DelegateID: "u2"}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/?user_id=u1&at=2025-07-03T12:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		router.AvailabilityHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, false, resp["availability"]["available"])
+		require.Equal(t, "u2", resp["availability"]["delegate_id"])
+	})
+
+	t.Run("bad_timestamp", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?user_id=u1&at=yesterday", nil)
+		w := httptest.NewRecorder()
+
+		router.AvailabilityHandler(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		// without at the repository decides what now is
+		mockUR.On("Availability", mock.Anything, "ghost", time.Time{}).Return(nil, errs.NotFountError)
+
+		req := httptest.NewRequest(http.MethodGet, "/?user_id=ghost", nil)
+		w := httptest.NewRecorder()
+
+		router.AvailabilityHandler(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}